@@ -4,8 +4,10 @@ import (
 	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 const defaultTokens = 2500
@@ -74,8 +76,8 @@ func (c *ChatInstance) ConvertMessages(props *adaptercommon.ChatProps) []globals
 			continue
 		}
 
-		// anthropic api does not allow multi-same role messages
-		if len(result) > 0 && result[len(result)-1].Role == message.Role {
+		// anthropic api does not allow multi-same role messages, the tool messages are merged in GetMessages
+		if len(result) > 0 && result[len(result)-1].Role == message.Role && !isToolMessage(result[len(result)-1]) && !isToolMessage(message) {
 			result[len(result)-1].Content += "\n" + message.Content
 			continue
 		}
@@ -86,9 +88,86 @@ func (c *ChatInstance) ConvertMessages(props *adaptercommon.ChatProps) []globals
 	return result
 }
 
+func isToolMessage(message globals.Message) bool {
+	return message.Role == globals.Tool || message.ToolCalls != nil
+}
+
+// getToolUseContents converts the openai tool calls of the assistant message to the anthropic tool_use blocks
+func getToolUseContents(message globals.Message) []MessageContent {
+	contents := make([]MessageContent, 0)
+	if text := strings.TrimSpace(message.Content); len(text) > 0 {
+		contents = append(contents, MessageContent{Type: "text", Text: &text})
+	}
+
+	for _, call := range *message.ToolCalls {
+		var input interface{}
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &input); err != nil || input == nil {
+			input = map[string]interface{}{}
+		}
+
+		contents = append(contents, MessageContent{
+			Type:  "tool_use",
+			Id:    call.Id,
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+	return contents
+}
+
+// getToolResultContent converts the openai tool message to the anthropic tool_result block
+func getToolResultContent(message globals.Message) MessageContent {
+	result := MessageContent{Type: "tool_result", Content: &message.Content}
+	if message.ToolCallId != nil {
+		result.ToolUseId = *message.ToolCallId
+	}
+	return result
+}
+
+func toMessageContents(content interface{}) []MessageContent {
+	switch value := content.(type) {
+	case []MessageContent:
+		return value
+	case string:
+		if len(strings.TrimSpace(value)) == 0 {
+			return nil
+		}
+		return []MessageContent{{Type: "text", Text: &value}}
+	}
+	return nil
+}
+
+// mergeMessages merges the same role messages which contain the tool blocks, the tool results are sent as the user messages
+func mergeMessages(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		if len(result) > 0 && result[len(result)-1].Role == message.Role {
+			last := &result[len(result)-1]
+			last.Content = append(toMessageContents(last.Content), toMessageContents(message.Content)...)
+			continue
+		}
+		result = append(result, message)
+	}
+	return result
+}
+
 func (c *ChatInstance) GetMessages(props *adaptercommon.ChatProps) []Message {
 	converted := c.ConvertMessages(props)
-	return utils.Each(converted, func(message globals.Message) Message {
+	return mergeMessages(utils.Each(converted, func(message globals.Message) Message {
+		if message.Role == globals.Tool {
+			return Message{
+				Role:    globals.User,
+				Content: []MessageContent{getToolResultContent(message)},
+			}
+		}
+
+		if message.ToolCalls != nil {
+			return Message{
+				Role:    globals.Assistant,
+				Content: getToolUseContents(message),
+			}
+		}
+
 		if !globals.IsVisionModel(props.Model) || message.Role != globals.User {
 			return Message{
 				Role:    message.Role,
@@ -122,7 +201,7 @@ func (c *ChatInstance) GetMessages(props *adaptercommon.ChatProps) []Message {
 				Text: &content,
 			}),
 		}
-	})
+	}))
 }
 
 func (c *ChatInstance) GetSystemPrompt(props *adaptercommon.ChatProps) (prompt string) {
@@ -134,6 +213,44 @@ func (c *ChatInstance) GetSystemPrompt(props *adaptercommon.ChatProps) (prompt s
 	return
 }
 
+// GetTools converts the openai function tools to the anthropic tools
+func (c *ChatInstance) GetTools(props *adaptercommon.ChatProps) []Tool {
+	if props.Tools == nil {
+		return nil
+	}
+
+	return utils.Each(*props.Tools, func(tool globals.ToolObject) Tool {
+		return Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: tool.Function.Parameters,
+		}
+	})
+}
+
+// GetToolChoice converts the openai tool choice ("auto", "required" or a named function) to the anthropic tool choice
+func (c *ChatInstance) GetToolChoice(props *adaptercommon.ChatProps) *ToolChoice {
+	if props.Tools == nil || props.ToolChoice == nil {
+		return nil
+	}
+
+	switch choice := (*props.ToolChoice).(type) {
+	case string:
+		if choice == "required" {
+			return &ToolChoice{Type: "any"}
+		}
+		return &ToolChoice{Type: "auto"}
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && len(name) > 0 {
+				return &ToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *ChatInstance) GetChatBody(props *adaptercommon.ChatProps, stream bool) *ChatBody {
 	messages := c.GetMessages(props)
 	return &ChatBody{
@@ -145,11 +262,38 @@ func (c *ChatInstance) GetChatBody(props *adaptercommon.ChatProps, stream bool)
 		Temperature: props.Temperature,
 		TopP:        props.TopP,
 		TopK:        props.TopK,
+		Tools:       c.GetTools(props),
+		ToolChoice:  c.GetToolChoice(props),
 	}
 }
 
 func (c *ChatInstance) ProcessLine(data string) (*globals.Chunk, error) {
 	if form := processChatResponse(data); form != nil {
+		// the tool_use blocks are streamed as the openai tool calls, the input json is appended to the arguments
+		if form.Type == "content_block_start" && form.ContentBlock.Type == "tool_use" {
+			return &globals.Chunk{
+				ToolCall: &globals.ToolCalls{{
+					Index: utils.ToPtr(form.Index),
+					Type:  "function",
+					Id:    form.ContentBlock.Id,
+					Function: globals.ToolCallFunction{
+						Name: form.ContentBlock.Name,
+					},
+				}},
+			}, nil
+		}
+
+		if form.Delta.Type == "input_json_delta" {
+			return &globals.Chunk{
+				ToolCall: &globals.ToolCalls{{
+					Index: utils.ToPtr(form.Index),
+					Function: globals.ToolCallFunction{
+						Arguments: form.Delta.PartialJson,
+					},
+				}},
+			}, nil
+		}
+
 		return &globals.Chunk{
 			Content: form.Delta.Text,
 		}, nil
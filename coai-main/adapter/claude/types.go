@@ -1,5 +1,7 @@
 package claude
 
+import "chat/globals"
+
 // ChatBody is the request body for anthropic claude
 
 type Message struct {
@@ -17,26 +19,54 @@ type MessageContent struct {
 	Type   string        `json:"type"`
 	Text   *string       `json:"text,omitempty"`
 	Source *MessageImage `json:"source,omitempty"`
+
+	// tool_use blocks of the assistant messages
+	Id    string      `json:"id,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Input interface{} `json:"input,omitempty"`
+
+	// tool_result blocks of the user messages
+	ToolUseId string  `json:"tool_use_id,omitempty"`
+	Content   *string `json:"content,omitempty"`
+}
+
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema globals.ToolParameters `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"` // auto, any or tool
+	Name string `json:"name,omitempty"`
 }
 
 type ChatBody struct {
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens"`
-	Model       string    `json:"model"`
-	System      string    `json:"system"`
-	Stream      bool      `json:"stream"`
-	Temperature *float32  `json:"temperature,omitempty"`
-	TopP        *float32  `json:"top_p,omitempty"`
-	TopK        *int      `json:"top_k,omitempty"`
+	Messages    []Message   `json:"messages"`
+	MaxTokens   int         `json:"max_tokens"`
+	Model       string      `json:"model"`
+	System      string      `json:"system"`
+	Stream      bool        `json:"stream"`
+	Temperature *float32    `json:"temperature,omitempty"`
+	TopP        *float32    `json:"top_p,omitempty"`
+	TopK        *int        `json:"top_k,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
 }
 
 type ChatStreamResponse struct {
 	Type  string `json:"type"`
 	Index int    `json:"index"`
 	Delta struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJson string `json:"partial_json"` // input_json_delta of the tool_use blocks
 	} `json:"delta"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
 }
 
 type ChatErrorResponse struct {
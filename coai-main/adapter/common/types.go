@@ -28,6 +28,7 @@ type ChatProps struct {
 	TopK              *int                   `json:"top_k,omitempty"`
 	Tools             *globals.FunctionTools `json:"tools,omitempty"`
 	ToolChoice        *interface{}           `json:"tool_choice,omitempty"`
+	OptionalTools     bool                   `json:"-"` // drop the tools on the channels not supporting tool calling
 	Buffer            *utils.Buffer          `json:"-"`
 }
 
@@ -68,6 +68,8 @@ func (c *ChatInstance) GetChatBody(props *adaptercommon.ChatProps, stream bool)
 		TopP:             props.TopP,
 		PresencePenalty:  props.PresencePenalty,
 		FrequencyPenalty: props.FrequencyPenalty,
+		Tools:            props.Tools,
+		ToolChoice:       props.ToolChoice,
 	}
 }
 
@@ -92,41 +94,52 @@ func processChatErrorResponse(data string) *ChatStreamErrorResponse {
 	return nil
 }
 
-func (c *ChatInstance) ProcessLine(data string) (string, error) {
+func (c *ChatInstance) ProcessLine(data string) (*globals.Chunk, error) {
 	if form := processChatStreamResponse(data); form != nil {
 		if len(form.Choices) == 0 {
-			return "", nil
+			return &globals.Chunk{Content: ""}, nil
 		}
 
 		delta := form.Choices[0].Delta
-
-		if c.isFirstReasoning == false && !c.isReasonOver && delta.ReasoningContent == nil {
-			c.isReasonOver = true
-			if delta.Content != "" {
-				return fmt.Sprintf("\n</think>\n\n%s", delta.Content), nil
-			}
-			return "\n</think>\n\n", nil
+		if delta.ToolCalls != nil {
+			return &globals.Chunk{
+				Content:  delta.Content,
+				ToolCall: delta.ToolCalls,
+			}, nil
 		}
 
-		if delta.ReasoningContent != nil {
-			content := *delta.ReasoningContent
-			if c.isFirstReasoning {
-				c.isFirstReasoning = false
-				return fmt.Sprintf("<think>\n%s", content), nil
-			}
-			return content, nil
-		}
-
-		return delta.Content, nil
+		return &globals.Chunk{Content: c.ProcessContent(delta)}, nil
 	}
 
 	if form := processChatErrorResponse(data); form != nil {
 		if form.Error.Message != "" {
-			return "", errors.New(fmt.Sprintf("deepseek error: %s", form.Error.Message))
+			return &globals.Chunk{Content: ""}, errors.New(fmt.Sprintf("deepseek error: %s", form.Error.Message))
+		}
+	}
+
+	return &globals.Chunk{Content: ""}, nil
+}
+
+// ProcessContent wraps the reasoning content of the delta in the think tags
+func (c *ChatInstance) ProcessContent(delta globals.Message) string {
+	if c.isFirstReasoning == false && !c.isReasonOver && delta.ReasoningContent == nil {
+		c.isReasonOver = true
+		if delta.Content != "" {
+			return fmt.Sprintf("\n</think>\n\n%s", delta.Content)
+		}
+		return "\n</think>\n\n"
+	}
+
+	if delta.ReasoningContent != nil {
+		content := *delta.ReasoningContent
+		if c.isFirstReasoning {
+			c.isFirstReasoning = false
+			return fmt.Sprintf("<think>\n%s", content)
 		}
+		return content
 	}
 
-	return "", nil
+	return delta.Content
 }
 
 func (c *ChatInstance) CreateChatRequest(props *adaptercommon.ChatProps) (string, error) {
@@ -172,7 +185,7 @@ func (c *ChatInstance) CreateStreamChatRequest(props *adaptercommon.ChatProps, c
 			if err != nil {
 				return err
 			}
-			return callback(&globals.Chunk{Content: partial})
+			return callback(partial)
 		},
 	}, props.Proxy)
 
@@ -7,14 +7,16 @@ import (
 // DeepSeek API is similar to OpenAI API with additional reasoning content
 
 type ChatRequest struct {
-	Model            string            `json:"model"`
-	Messages         []globals.Message `json:"messages"`
-	MaxTokens        *int              `json:"max_tokens,omitempty"`
-	Stream           bool              `json:"stream"`
-	Temperature      *float32          `json:"temperature,omitempty"`
-	TopP             *float32          `json:"top_p,omitempty"`
-	PresencePenalty  *float32          `json:"presence_penalty,omitempty"`
-	FrequencyPenalty *float32          `json:"frequency_penalty,omitempty"`
+	Model            string                 `json:"model"`
+	Messages         []globals.Message      `json:"messages"`
+	MaxTokens        *int                   `json:"max_tokens,omitempty"`
+	Stream           bool                   `json:"stream"`
+	Temperature      *float32               `json:"temperature,omitempty"`
+	TopP             *float32               `json:"top_p,omitempty"`
+	PresencePenalty  *float32               `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32               `json:"frequency_penalty,omitempty"`
+	Tools            *globals.FunctionTools `json:"tools,omitempty"`
+	ToolChoice       *interface{}           `json:"tool_choice,omitempty"`
 }
 
 // ChatResponse is the native http request body for deepseek
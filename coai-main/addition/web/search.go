@@ -38,6 +38,13 @@ type SearXNGResponse struct {
 	UnresponsiveEngines [][]string    `json:"unresponsive_engines"`
 }
 
+// SearchResult is a single search result with the fields the callers need
+type SearchResult struct {
+	Title   string `json:"title"`
+	Url     string `json:"url"`
+	Content string `json:"content"`
+}
+
 func formatResponse(data *SearXNGResponse) string {
 	res := make([]string, 0)
 	for _, item := range data.Results {
@@ -96,6 +103,39 @@ func GenerateSearchResult(q string) (string, error) {
 	return content, nil
 }
 
+// GenerateSearchResults returns at most limit structured results of the query,
+// the results without a title or an url are skipped
+func GenerateSearchResults(q string, limit int) ([]SearchResult, error) {
+	if len(globals.SearchEndpoint) == 0 {
+		return nil, errors.New("search endpoint is not configured")
+	}
+
+	res, err := createSearXNGRequest(q)
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[web] failed to get search result: %s (query: %s)", err.Error(), utils.Extract(q, 20, "...")))
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, limit)
+	for _, item := range res.Results {
+		if len(results) >= limit {
+			break
+		}
+		if item.Url == "" || item.Title == "" {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Title:   item.Title,
+			Url:     item.Url,
+			Content: utils.Extract(item.Content, 200, "..."),
+		})
+	}
+
+	globals.Debug(fmt.Sprintf("[web] %d structured search results (query: %s)", len(results), q))
+	return results, nil
+}
+
 func TestSearch(c *gin.Context) {
 	// get `query` param from query
 	query := c.Query("query")
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"chat/globals"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeSearXNG returns a local searxng server which answers the json search api
+func newFakeSearXNG(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "json" || r.URL.Query().Get("q") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"query": r.URL.Query().Get("q"),
+			"results": []map[string]interface{}{
+				{"title": "Photosynthesis", "url": "https://en.wikipedia.org/wiki/Photosynthesis", "content": "Photosynthesis is a process used by plants."},
+				{"title": "", "url": "https://example.com/untitled", "content": "skipped without a title"},
+				{"title": "Light-dependent reactions", "url": "https://en.wikipedia.org/wiki/Light-dependent_reactions", "content": "The light-dependent reactions."},
+				{"title": "Calvin cycle", "url": "https://en.wikipedia.org/wiki/Calvin_cycle", "content": "The Calvin cycle."},
+			},
+		})
+	}))
+}
+
+func TestGenerateSearchResults(t *testing.T) {
+	server := newFakeSearXNG(t)
+	defer server.Close()
+
+	endpoint := globals.SearchEndpoint
+	globals.SearchEndpoint = server.URL + "/search"
+	defer func() { globals.SearchEndpoint = endpoint }()
+
+	results, err := GenerateSearchResults("photosynthesis", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Title != "Photosynthesis" || results[1].Title != "Light-dependent reactions" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestGenerateSearchResultsWithoutEndpoint(t *testing.T) {
+	endpoint := globals.SearchEndpoint
+	globals.SearchEndpoint = ""
+	defer func() { globals.SearchEndpoint = endpoint }()
+
+	if _, err := GenerateSearchResults("photosynthesis", 2); err == nil {
+		t.Errorf("expected an error without the search endpoint")
+	}
+}
@@ -123,6 +123,21 @@ func ErrorAnalysisAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, GetErrorData(cache))
 }
 
+func QuizAnalysisAPI(c *gin.Context) {
+	cache := utils.GetCacheFromContext(c)
+	c.JSON(http.StatusOK, GetQuizData(cache))
+}
+
+func QuizModelAnalysisAPI(c *gin.Context) {
+	cache := utils.GetCacheFromContext(c)
+	c.JSON(http.StatusOK, GetQuizModelData(cache))
+}
+
+func QuizJudgeAnalysisAPI(c *gin.Context) {
+	cache := utils.GetCacheFromContext(c)
+	c.JSON(http.StatusOK, GetQuizJudgeData(cache))
+}
+
 func UserTypeAnalysisAPI(c *gin.Context) {
 	db := utils.GetDBFromContext(c)
 	if form, err := GetUserTypeData(db); err != nil {
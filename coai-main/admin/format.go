@@ -44,3 +44,43 @@ func getRequestFormat(t string) string {
 func getModelFormat(t string, model string) string {
 	return fmt.Sprintf("nio:model-analysis-%s-%s", model, t)
 }
+
+func getQuizJudgeFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-judge-analysis-%s-%s", model, t)
+}
+
+func getQuizDisagreeFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-disagree-analysis-%s-%s", model, t)
+}
+
+func getQuizGenerationFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-generation-analysis-%s-%s", model, t)
+}
+
+func getQuizLatencyFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-latency-analysis-%s-%s", model, t)
+}
+
+func getQuizQuestionFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-question-analysis-%s-%s", model, t)
+}
+
+func getQuizResponseFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-response-analysis-%s-%s", model, t)
+}
+
+func getQuizParseErrorFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-parse-err-analysis-%s-%s", model, t)
+}
+
+func getQuizInvalidFormat(t string, model string) string {
+	return fmt.Sprintf("nio:quiz-invalid-analysis-%s-%s", model, t)
+}
+
+func getQuizAttemptFormat(t string) string {
+	return fmt.Sprintf("nio:quiz-attempt-analysis-%s", t)
+}
+
+func getQuizScoreFormat(t string) string {
+	return fmt.Sprintf("nio:quiz-score-analysis-%s", t)
+}
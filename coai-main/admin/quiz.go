@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"chat/connection"
+	"chat/globals"
+	"chat/utils"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IncrQuizJudge records the questions checked by the judge model and the disagreements
+// with the answer keys written by the generator model
+func IncrQuizJudge(cache *redis.Client, model string, checked int64, disagreed int64) {
+	utils.IncrWithExpire(cache, getQuizJudgeFormat(getDay(), model), checked, time.Hour*24*7*2)
+	utils.IncrWithExpire(cache, getQuizDisagreeFormat(getDay(), model), disagreed, time.Hour*24*7*2)
+}
+
+// IncrQuizGeneration records a completed quiz generation with its question count and latency
+func IncrQuizGeneration(cache *redis.Client, model string, questions int64, latency time.Duration) {
+	utils.IncrOnce(cache, getQuizGenerationFormat(getDay(), model), time.Hour*24*7*2)
+	utils.IncrWithExpire(cache, getQuizQuestionFormat(getDay(), model), questions, time.Hour*24*7*2)
+	utils.IncrWithExpire(cache, getQuizLatencyFormat(getDay(), model), latency.Milliseconds(), time.Hour*24*7*2)
+}
+
+// IncrQuizResponse records a model output of the quiz generation, parseFailed is true if the output
+// is not a complete JSON array and invalid is true if the parsed questions break the schema
+func IncrQuizResponse(cache *redis.Client, model string, parseFailed bool, invalid bool) {
+	utils.IncrOnce(cache, getQuizResponseFormat(getDay(), model), time.Hour*24*7*2)
+	if parseFailed {
+		utils.IncrOnce(cache, getQuizParseErrorFormat(getDay(), model), time.Hour*24*7*2)
+	}
+	if invalid {
+		utils.IncrOnce(cache, getQuizInvalidFormat(getDay(), model), time.Hour*24*7*2)
+	}
+}
+
+// IncrQuizAttempt records a finished attempt, the score is stored in basis points of the total
+func IncrQuizAttempt(cache *redis.Client, score int, total int) {
+	if total <= 0 {
+		return
+	}
+
+	utils.IncrOnce(cache, getQuizAttemptFormat(getDay()), time.Hour*24*7*2)
+	utils.IncrWithExpire(cache, getQuizScoreFormat(getDay()), int64(score*10000/total), time.Hour*24*7*2)
+}
+
+func AnalyseQuizAttempt(score int, total int) {
+	IncrQuizAttempt(connection.Cache, score, total)
+}
+
+func sumQuizModels(cache *redis.Client, date time.Time, format func(t string, model string) string) int64 {
+	return utils.Sum(utils.Each[string, int64](globals.SupportModels, func(model string) int64 {
+		return utils.MustInt(cache, format(getFormat(date), model))
+	}))
+}
+
+func getAverage(sum int64, count int64) float32 {
+	if count == 0 {
+		return 0
+	}
+	return float32(sum) / float32(count)
+}
+
+func GetQuizData(cache *redis.Client) QuizChartForm {
+	dates := getDays(7)
+
+	form := QuizChartForm{
+		Date:        getDates(dates),
+		Generations: make([]int64, 0, len(dates)),
+		Latency:     make([]float32, 0, len(dates)),
+		Questions:   make([]float32, 0, len(dates)),
+		Attempts:    make([]int64, 0, len(dates)),
+		Score:       make([]float32, 0, len(dates)),
+	}
+
+	for _, date := range dates {
+		generations := sumQuizModels(cache, date, getQuizGenerationFormat)
+		latency := sumQuizModels(cache, date, getQuizLatencyFormat)
+		questions := sumQuizModels(cache, date, getQuizQuestionFormat)
+		attempts := utils.MustInt(cache, getQuizAttemptFormat(getFormat(date)))
+		score := utils.MustInt(cache, getQuizScoreFormat(getFormat(date)))
+
+		form.Generations = append(form.Generations, generations)
+		form.Latency = append(form.Latency, getAverage(latency, generations)/1000)
+		form.Questions = append(form.Questions, getAverage(questions, generations))
+		form.Attempts = append(form.Attempts, attempts)
+		form.Score = append(form.Score, getAverage(score, attempts)/100)
+	}
+
+	return form
+}
+
+func GetQuizModelData(cache *redis.Client) QuizModelChartForm {
+	dates := getDays(7)
+
+	return QuizModelChartForm{
+		Date: getDates(dates),
+		Value: utils.EachNotNil[string, QuizModelData](globals.SupportModels, func(model string) *QuizModelData {
+			data := QuizModelData{
+				Model: model,
+				Responses: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+					return utils.MustInt(cache, getQuizResponseFormat(getFormat(date), model))
+				}),
+				ParseFailed: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+					return utils.MustInt(cache, getQuizParseErrorFormat(getFormat(date), model))
+				}),
+				Invalid: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+					return utils.MustInt(cache, getQuizInvalidFormat(getFormat(date), model))
+				}),
+			}
+
+			responses := utils.Sum(data.Responses)
+			if responses == 0 {
+				return nil
+			}
+
+			generations := utils.Sum(utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+				return utils.MustInt(cache, getQuizGenerationFormat(getFormat(date), model))
+			}))
+			latency := utils.Sum(utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+				return utils.MustInt(cache, getQuizLatencyFormat(getFormat(date), model))
+			}))
+
+			data.ParseRate = getAverage(utils.Sum(data.ParseFailed), responses)
+			data.InvalidRate = getAverage(utils.Sum(data.Invalid), responses)
+			data.Latency = getAverage(latency, generations) / 1000
+			return &data
+		}),
+	}
+}
+
+func GetQuizJudgeData(cache *redis.Client) QuizJudgeChartForm {
+	dates := getDays(7)
+
+	return QuizJudgeChartForm{
+		Date: getDates(dates),
+		Value: utils.EachNotNil[string, QuizJudgeData](globals.SupportModels, func(model string) *QuizJudgeData {
+			data := QuizJudgeData{
+				Model: model,
+				Checked: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+					return utils.MustInt(cache, getQuizJudgeFormat(getFormat(date), model))
+				}),
+				Disagreed: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+					return utils.MustInt(cache, getQuizDisagreeFormat(getFormat(date), model))
+				}),
+			}
+
+			checked := utils.Sum(data.Checked)
+			if checked == 0 {
+				return nil
+			}
+
+			data.Rate = float32(utils.Sum(data.Disagreed)) / float32(checked)
+			return &data
+		}),
+	}
+}
@@ -18,6 +18,9 @@ func Register(app *gin.RouterGroup) {
 	app.GET("/admin/analytics/billing", BillingAnalysisAPI)
 	app.GET("/admin/analytics/error", ErrorAnalysisAPI)
 	app.GET("/admin/analytics/user", UserTypeAnalysisAPI)
+	app.GET("/admin/analytics/quiz", QuizAnalysisAPI)
+	app.GET("/admin/analytics/quiz/model", QuizModelAnalysisAPI)
+	app.GET("/admin/analytics/quiz/judge", QuizJudgeAnalysisAPI)
 
 	app.GET("/admin/invitation/list", InvitationPaginationAPI)
 	app.POST("/admin/invitation/generate", GenerateInvitationAPI)
@@ -18,6 +18,42 @@ type ModelChartForm struct {
 	Value []ModelData `json:"value"`
 }
 
+type QuizJudgeData struct {
+	Model     string  `json:"model"`
+	Checked   []int64 `json:"checked"`
+	Disagreed []int64 `json:"disagreed"`
+	Rate      float32 `json:"rate"` // disagreement rate of the period
+}
+
+type QuizJudgeChartForm struct {
+	Date  []string        `json:"date"`
+	Value []QuizJudgeData `json:"value"`
+}
+
+type QuizChartForm struct {
+	Date        []string  `json:"date"`
+	Generations []int64   `json:"generations"`
+	Latency     []float32 `json:"latency"`   // average generation latency in seconds
+	Questions   []float32 `json:"questions"` // average questions per generation
+	Attempts    []int64   `json:"attempts"`
+	Score       []float32 `json:"score"` // average score of the attempts in percent
+}
+
+type QuizModelData struct {
+	Model       string  `json:"model"`
+	Responses   []int64 `json:"responses"`
+	ParseFailed []int64 `json:"parse_failed"`
+	Invalid     []int64 `json:"invalid"`
+	ParseRate   float32 `json:"parse_rate"`   // parse failure rate of the period
+	InvalidRate float32 `json:"invalid_rate"` // validation failure rate of the period
+	Latency     float32 `json:"latency"`      // average generation latency of the period in seconds
+}
+
+type QuizModelChartForm struct {
+	Date  []string        `json:"date"`
+	Value []QuizModelData `json:"value"`
+}
+
 type RequestChartForm struct {
 	Date  []string `json:"date"`
 	Value []int64  `json:"value"`
@@ -6,6 +6,9 @@ import {
   InvitationGenerateResponse,
   InvitationResponse,
   ModelChartResponse,
+  QuizChartResponse,
+  QuizJudgeChartResponse,
+  QuizModelChartResponse,
   RedeemResponse,
   RequestChartResponse,
   UserResponse,
@@ -69,6 +72,43 @@ export async function getErrorChart(): Promise<ErrorChartResponse> {
   }
 }
 
+export async function getQuizChart(): Promise<QuizChartResponse> {
+  try {
+    const response = await axios.get("/admin/analytics/quiz");
+    return response.data as QuizChartResponse;
+  } catch (e) {
+    console.warn(e);
+    return {
+      date: [],
+      generations: [],
+      latency: [],
+      questions: [],
+      attempts: [],
+      score: [],
+    };
+  }
+}
+
+export async function getQuizModelChart(): Promise<QuizModelChartResponse> {
+  try {
+    const response = await axios.get("/admin/analytics/quiz/model");
+    return response.data as QuizModelChartResponse;
+  } catch (e) {
+    console.warn(e);
+    return { date: [], value: [] };
+  }
+}
+
+export async function getQuizJudgeChart(): Promise<QuizJudgeChartResponse> {
+  try {
+    const response = await axios.get("/admin/analytics/quiz/judge");
+    return response.data as QuizJudgeChartResponse;
+  } catch (e) {
+    console.warn(e);
+    return { date: [], value: [] };
+  }
+}
+
 export async function getUserTypeChart(): Promise<UserTypeChartResponse> {
   try {
     const response = await axios.get("/admin/analytics/user");
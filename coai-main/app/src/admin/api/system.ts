@@ -60,8 +60,16 @@ export type CommonState = {
 
   article: string[];
   generation: string[];
+  classroom: string[];
 
   image_store: boolean;
+
+  quiz_cache: string[];
+  quiz_expire: number;
+  quiz_pool: number;
+  quiz_shuffle: boolean;
+  quiz_judge: string;
+  quiz_judge_policy: string;
 };
 
 export type SystemProps = {
@@ -188,9 +196,16 @@ export const initialSystemState: SystemProps = {
   common: {
     article: [],
     generation: [],
+    classroom: [],
     cache: [],
     expire: 3600,
     size: 1,
     image_store: false,
+    quiz_cache: [],
+    quiz_expire: 86400,
+    quiz_pool: 1,
+    quiz_shuffle: false,
+    quiz_judge: "",
+    quiz_judge_policy: "off",
   },
 };
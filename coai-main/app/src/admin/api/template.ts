@@ -0,0 +1,26 @@
+import { QuizPromptTemplate } from "@/types/quiz.ts";
+import axios from "axios";
+import { CommonResponse } from "@/api/common.ts";
+import { getErrorMessage } from "@/utils/base.ts";
+
+export async function listQuizTemplates(): Promise<QuizPromptTemplate[]> {
+  try {
+    const response = await axios.get("/admin/quiz/template/list");
+    return (response.data.data || []) as QuizPromptTemplate[];
+  } catch (e) {
+    console.warn(e);
+    return [];
+  }
+}
+
+export async function updateQuizTemplates(
+  data: QuizPromptTemplate[],
+): Promise<CommonResponse> {
+  try {
+    const response = await axios.post("/admin/quiz/template/update", data);
+    return response.data as CommonResponse;
+  } catch (e) {
+    console.warn(e);
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
@@ -1,9 +1,17 @@
 export const tokenBilling = "token-billing";
 export const timesBilling = "times-billing";
 export const nonBilling = "non-billing";
+export const questionBilling = "question-billing";
+export const quizBilling = "quiz-billing";
 
 export const defaultChargeType = tokenBilling;
-export const chargeTypes = [nonBilling, timesBilling, tokenBilling];
+export const chargeTypes = [
+  nonBilling,
+  timesBilling,
+  tokenBilling,
+  questionBilling,
+  quizBilling,
+];
 export type ChargeType = (typeof chargeTypes)[number];
 
 export type ChargeBaseProps = {
@@ -16,4 +24,13 @@ export type ChargeBaseProps = {
 export type ChargeProps = ChargeBaseProps & {
   id: number;
   models: string[];
+  features?: string[];
 };
+
+// feature rules are applied by the feature key instead of the model name
+export const chargeFeatures = ["quiz"];
+export const featureChargeTypes = [questionBilling, quizBilling];
+
+export function isFeatureCharge(type: string): boolean {
+  return featureChargeTypes.includes(type);
+}
@@ -33,6 +33,42 @@ export type ErrorChartResponse = {
   value: number[];
 };
 
+export type QuizChartResponse = {
+  date: string[];
+  generations: number[];
+  latency: number[];
+  questions: number[];
+  attempts: number[];
+  score: number[];
+};
+
+export type QuizModelData = {
+  model: string;
+  responses: number[];
+  parse_failed: number[];
+  invalid: number[];
+  parse_rate: number;
+  invalid_rate: number;
+  latency: number;
+};
+
+export type QuizModelChartResponse = {
+  date: string[];
+  value: QuizModelData[];
+};
+
+export type QuizJudgeData = {
+  model: string;
+  checked: number[];
+  disagreed: number[];
+  rate: number;
+};
+
+export type QuizJudgeChartResponse = {
+  date: string[];
+  value: QuizJudgeData[];
+};
+
 export type UserTypeChartResponse = {
   total: number;
   normal: number;
@@ -63,19 +63,9 @@ export class QuizManager {
         this.connection = null;
       }
 
-      // Parse the final quiz data
+      // The final frame carries the validated quiz data
       if (response.data && this.onComplete) {
-        try {
-          // Remove markdown code blocks if present
-          let data = response.data.trim();
-          data = data.replace(/^```json\s*/i, "").replace(/```\s*$/, "");
-          data = data.trim();
-
-          const quizzes = JSON.parse(data) as Quiz[];
-          this.onComplete(quizzes);
-        } catch (error) {
-          console.error("Failed to parse quiz data:", error);
-        }
+        this.onComplete(response.data);
       }
     }
   }
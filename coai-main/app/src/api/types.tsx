@@ -62,6 +62,7 @@ export type PlanItem = {
   value: number;
   icon: string;
   models: string[];
+  features?: string[]; // metered features, counted apart from the models
 };
 
 export type Plan = {
@@ -2,9 +2,13 @@ import { RadioGroup, RadioGroupItem } from "@/components/ui/radio-group.tsx";
 import { Label } from "@/components/ui/label.tsx";
 import {
   ChargeProps,
+  chargeFeatures,
   chargeTypes,
   defaultChargeType,
+  isFeatureCharge,
   nonBilling,
+  questionBilling,
+  quizBilling,
   timesBilling,
   tokenBilling,
 } from "@/admin/charge.ts";
@@ -83,6 +87,7 @@ const initialState: ChargeProps = {
   id: -1,
   type: defaultChargeType,
   models: [],
+  features: [],
   anonymous: false,
   input: 0,
   output: 0,
@@ -111,6 +116,14 @@ function reducer(state: ChargeProps, action: any): ChargeProps {
         ...state,
         models: state.models.filter((model) => model !== action.payload),
       };
+    case "toggle-feature":
+      const features = state.features || [];
+      return features.includes(action.payload)
+        ? {
+            ...state,
+            features: features.filter((feature) => feature !== action.payload),
+          }
+        : { ...state, features: [...features, action.payload] };
     case "set-type":
       return { ...state, type: action.payload };
     case "set-anonymous":
@@ -144,8 +157,16 @@ function preflight(state: ChargeProps): ChargeProps {
     case tokenBilling:
       state.anonymous = false;
       break;
+    case questionBilling:
+    case quizBilling:
+      state.input = 0;
+      state.anonymous = false;
+      break;
   }
 
+  if (isFeatureCharge(state.type)) state.models = [];
+  else state.features = [];
+
   if (state.input < 0) state.input = 0;
   if (state.output < 0) state.output = 0;
 
@@ -399,17 +420,20 @@ function ChargeEditor({
     );
   }, [form.models, usedModels]);
 
+  const feature = useMemo(() => isFeatureCharge(form.type), [form.type]);
+
   const disabled = useMemo(() => {
+    if (feature) return (form.features || []).length === 0;
     if (model.trim() !== "") return false;
     return form.models.length === 0;
-  }, [model, form.models]);
+  }, [model, form.models, form.features, feature]);
 
   const [loading, setLoading] = useState(false);
 
   async function post() {
     const raw = model.trim();
     const data = preflight({ ...form });
-    if (raw !== "" && !data.models.includes(raw)) {
+    if (!feature && raw !== "" && !data.models.includes(raw)) {
       data.models = [raw, ...data.models];
       setModel("");
     }
@@ -453,7 +477,30 @@ function ChargeEditor({
           ))}
         </RadioGroup>
       </div>
-      <div className={`flex flex-row w-full h-max mb-4`}>
+      {feature && (
+        <div className={`flex flex-col w-full h-max mb-4 gap-2`}>
+          {chargeFeatures.map((item, index) => (
+            <div
+              className={`flex flex-row w-full h-max items-center`}
+              key={index}
+            >
+              <Label className={`grow`}>
+                {t(`admin.charge.feature-${item}`)}
+              </Label>
+              <Switch
+                checked={(form.features || []).includes(item)}
+                onCheckedChange={() =>
+                  dispatch({ type: "toggle-feature", payload: item })
+                }
+              />
+            </div>
+          ))}
+        </div>
+      )}
+
+      <div
+        className={cn(`flex flex-row w-full h-max mb-4`, feature && `hidden`)}
+      >
         <Button
           onClick={() => {
             dispatch({ type: "add-model", payload: model });
@@ -502,7 +549,9 @@ function ChargeEditor({
           </DropdownMenuContent>
         </DropdownMenu>
       </div>
-      <div className={`flex flex-col w-full h-max mb-2`}>
+      <div
+        className={cn(`flex flex-col w-full h-max mb-2`, feature && `hidden`)}
+      >
         {form.models.map((model, index) => (
           <div
             className={`flex flex-row w-full h-max shrink-0 mb-2 select-none`}
@@ -551,6 +600,25 @@ function ChargeEditor({
         </div>
       )}
 
+      {feature && (
+        <div className={`flex flex-row w-full h-max items-center`}>
+          <Cloud className={`w-4 h-4 mr-2`} />
+          <Label className={`grow`}>
+            {t(`admin.charge.${form.type}-count`)}
+          </Label>
+          <NumberInput
+            value={form.output}
+            onValueChange={(value) =>
+              dispatch({ type: "set-output", payload: value })
+            }
+            acceptNegative={false}
+            className={`w-20`}
+            min={0}
+            max={99999}
+          />
+        </div>
+      )}
+
       {form.type === tokenBilling && (
         <div className={`flex flex-col w-full h-max gap-2`}>
           <div className={`flex flex-row w-full h-max items-center`}>
@@ -668,7 +736,13 @@ function ChargeTable({ data, dispatch, onRefresh }: ChargeTableProps) {
                 </Badge>
               </TableCell>
               <TableCell>
-                <pre>{charge.models.join("\n")}</pre>
+                <pre>
+                  {isFeatureCharge(charge.type)
+                    ? (charge.features || [])
+                        .map((feature) => t(`admin.charge.feature-${feature}`))
+                        .join("\n")
+                    : charge.models.join("\n")}
+                </pre>
               </TableCell>
               <TableCell>
                 {charge.input === 0 ? 0 : charge.input.toFixed(3)}
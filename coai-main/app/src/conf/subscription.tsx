@@ -27,6 +27,9 @@ export const subscriptionIcons: Record<string, React.ReactElement> = {
 
 export const subscriptionIconsList: string[] = Object.keys(subscriptionIcons);
 
+// metered features of the plan items, counted apart from the model calls
+export const planFeatures: string[] = ["quiz", "quiz-attempt"];
+
 export const subscriptionType: Record<number, string> = {
   1: "basic",
   2: "standard",
@@ -28,6 +28,7 @@ import {
 } from "lucide-react";
 import {
   getPlanName,
+  planFeatures,
   SubscriptionIcon,
   subscriptionIconsList,
 } from "@/conf/subscription.tsx";
@@ -183,6 +184,7 @@ function reducer(state: PlanConfig, action: Record<string, any>): PlanConfig {
                   value: 0,
                   icon: subscriptionIconsList[0],
                   models: [],
+                  features: [],
                 },
               ],
             };
@@ -211,6 +213,27 @@ function reducer(state: PlanConfig, action: Record<string, any>): PlanConfig {
           return plan;
         }),
       };
+    case "set-item-features":
+      return {
+        ...state,
+        plans: state.plans.map((plan: Plan) => {
+          if (plan.level === action.payload.level) {
+            return {
+              ...plan,
+              items: plan.items.map((item: PlanItem, index: number) => {
+                if (index === action.payload.index) {
+                  return {
+                    ...item,
+                    features: action.payload.features,
+                  };
+                }
+                return item;
+              }),
+            };
+          }
+          return plan;
+        }),
+      };
     case "remove-item":
       return {
         ...state,
@@ -608,6 +631,34 @@ function PlanConfig() {
                           className={`w-full max-w-full`}
                         />
                       </div>
+                      <div className={`plan-editor-row`}>
+                        <p className={`plan-editor-label mr-2`}>
+                          {t(`admin.plan.item-features`)}
+                          <Tips content={t("admin.plan.item-features-tip")} />
+                        </p>
+                        <MultiCombobox
+                          align={`start`}
+                          value={item.features || []}
+                          onChange={(value: string[]) => {
+                            formDispatch({
+                              type: "set-item-features",
+                              payload: {
+                                level: plan.level,
+                                features: value,
+                                index,
+                              },
+                            });
+                          }}
+                          placeholder={t(
+                            `admin.plan.item-features-placeholder`,
+                            { length: (item.features || []).length },
+                          )}
+                          list={planFeatures}
+                          listTranslate={`admin.plan.feature`}
+                          disabledSearch
+                          className={`w-full max-w-full`}
+                        />
+                      </div>
                       <div className={`plan-editor-row`}>
                         <p className={`plan-editor-label mr-2`}>
                           {t(`admin.plan.item-icon`)}
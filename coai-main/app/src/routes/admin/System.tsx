@@ -784,6 +784,97 @@ function Common({ form, data, dispatch, onChange }: CompProps<CommonState>) {
         </div>
       </ParagraphItem>
       <ParagraphSpace />
+      <ParagraphItem>
+        <Label className={`flex flex-row items-center`}>
+          {t("admin.system.quizCache")}
+          <Tips content={t("admin.system.quizCacheTip")} />
+        </Label>
+        <MultiCombobox
+          value={data.quiz_cache}
+          onChange={(value) => {
+            dispatch({ type: "update:common.quiz_cache", value });
+          }}
+          list={channelModels}
+          placeholder={t("admin.system.cachePlaceholder", {
+            length: (data.quiz_cache ?? []).length,
+          })}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>
+          {t("admin.system.quizCacheExpired")}
+          <Tips
+            className={`inline-block`}
+            content={t("admin.system.quizCacheExpiredTip")}
+          />
+        </Label>
+        <NumberInput
+          value={data.quiz_expire}
+          onValueChange={(value) =>
+            dispatch({ type: "update:common.quiz_expire", value })
+          }
+          min={0}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>
+          {t("admin.system.quizCachePool")}
+          <Tips
+            className={`inline-block`}
+            content={t("admin.system.quizCachePoolTip")}
+          />
+        </Label>
+        <NumberInput
+          value={data.quiz_pool}
+          onValueChange={(value) =>
+            dispatch({ type: "update:common.quiz_pool", value })
+          }
+          min={1}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label className={`flex flex-row items-center`}>
+          {t("admin.system.quizCacheShuffle")}
+          <Tips content={t("admin.system.quizCacheShuffleTip")} />
+        </Label>
+        <Switch
+          checked={data.quiz_shuffle}
+          onCheckedChange={(value) => {
+            dispatch({ type: "update:common.quiz_shuffle", value });
+          }}
+        />
+      </ParagraphItem>
+      <ParagraphSpace />
+      <ParagraphItem>
+        <Label className={`flex flex-row items-center`}>
+          {t("admin.system.quizJudge")}
+          <Tips content={t("admin.system.quizJudgeTip")} />
+        </Label>
+        <Combobox
+          value={data.quiz_judge}
+          onChange={(value) => {
+            dispatch({ type: "update:common.quiz_judge", value });
+          }}
+          list={channelModels}
+          placeholder={t("admin.system.quizJudgePlaceholder")}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label className={`flex flex-row items-center`}>
+          {t("admin.system.quizJudgePolicy")}
+          <Tips content={t("admin.system.quizJudgePolicyTip")} />
+        </Label>
+        <Combobox
+          value={data.quiz_judge_policy || "off"}
+          onChange={(value) => {
+            dispatch({ type: "update:common.quiz_judge_policy", value });
+          }}
+          list={["off", "flag", "regenerate", "drop"]}
+          listTranslated={`admin.system.quizJudgePolicies`}
+          hideSearchBar
+        />
+      </ParagraphItem>
+      <ParagraphSpace />
       <ParagraphItem>
         <Label className={`flex flex-row items-center`}>
           {t("admin.system.article")}
@@ -818,6 +909,23 @@ function Common({ form, data, dispatch, onChange }: CompProps<CommonState>) {
           })}
         />
       </ParagraphItem>
+      <ParagraphItem>
+        <Label className={`flex flex-row items-center`}>
+          {t("admin.system.classroom")}
+          <Tips content={t("admin.system.classroomTip")} />
+        </Label>
+        <MultiCombobox
+          value={data.classroom}
+          onChange={(value) => {
+            dispatch({ type: "update:common.classroom", value });
+          }}
+          list={allGroups}
+          listTranslate={`admin.channels.groups`}
+          placeholder={t("admin.system.groupPlaceholder", {
+            length: (data.classroom ?? []).length,
+          })}
+        />
+      </ParagraphItem>
       <ParagraphFooter>
         <div className={`grow`} />
         <Button
@@ -4,12 +4,11 @@ import { RootState } from "@/store";
 import {
   quizSelector,
   setStatus,
-  setStreamContent,
   setQuizzes,
   reset,
 } from "@/store/quiz.ts";
-import { useEffect, useState } from "react";
-import { Quiz, QuizGenerationForm } from "@/types/quiz.ts";
+import { useState } from "react";
+import { QuizGenerationForm } from "@/types/quiz.ts";
 import { quizManager } from "@/api/quiz.ts";
 import { getMemory } from "@/utils/memory.ts";
 import { tokenField } from "@/conf/bootstrap.ts";
@@ -22,29 +21,9 @@ import QuizSummary from "@/components/quiz/QuizSummary.tsx";
 function QuizPage() {
   const { t } = useTranslation();
   const dispatch = useDispatch();
-  const { status, streamContent } = useSelector(quizSelector);
+  const { status } = useSelector(quizSelector);
   const [error, setError] = useState<string>("");
 
-  useEffect(() => {
-    if (status === "done") {
-      try {
-        // Clean up the response
-        let data = streamContent.trim();
-        data = data.replace(/^```json\s*/i, "").replace(/```\s*$/, "");
-        data = data.trim();
-
-        const quizzes = JSON.parse(data) as Quiz[];
-        dispatch(setQuizzes(quizzes));
-      } catch (err) {
-        console.error("Error parsing quiz data:", err);
-        setError(t("quiz.parse-error"));
-        dispatch(reset());
-        dispatch(setStreamContent(""));
-        dispatch(setStatus("idle"));
-      }
-    }
-  }, [status, streamContent, dispatch, t]);
-
   const handleGenerateQuiz = (formData: {
     notes: string;
     files: File[];
@@ -54,7 +33,6 @@ function QuizPage() {
     model: string;
   }) => {
     dispatch(setStatus("streaming"));
-    dispatch(setStreamContent(""));
     setError("");
 
     const token = getMemory(tokenField) || "";
@@ -91,9 +69,6 @@ function QuizPage() {
             setError(response.error);
             dispatch(setStatus("idle"));
           }
-          if (response.data) {
-            dispatch(setStreamContent(response.data));
-          }
         },
         (quizzes) => {
           dispatch(setQuizzes(quizzes));
@@ -9,7 +9,6 @@ interface QuizState {
   points: number;
   totalPoints: number;
   status: QuizStatus;
-  streamContent: string;
 }
 
 const initialState: QuizState = {
@@ -19,7 +18,6 @@ const initialState: QuizState = {
   points: 1,
   totalPoints: 0,
   status: "idle",
-  streamContent: "",
 };
 
 export const quizSlice = createSlice({
@@ -52,9 +50,6 @@ export const quizSlice = createSlice({
     setStatus: (state, action) => {
       state.status = action.payload as QuizStatus;
     },
-    setStreamContent: (state, action) => {
-      state.streamContent = action.payload as string;
-    },
   },
 });
 
@@ -65,7 +60,6 @@ export const {
   addPoints,
   reset,
   setStatus,
-  setStreamContent,
 } = quizSlice.actions;
 
 export default quizSlice.reducer;
@@ -11,13 +11,30 @@ export interface QuizResource {
   link: string;
 }
 
+export type QuizQuestionType =
+  | "multiple_choice"
+  | "true_false"
+  | "multi_select"
+  | "fill_blank"
+  | "ordering"
+  | "short_answer";
+
 export interface Quiz {
   id: string;
+  type?: QuizQuestionType;
   question: string;
   description: string;
   options: QuizOption;
-  answer: string;
+  answer?: string;
+  answers?: string[];
+  rubric?: string[];
+  model_answer?: string; // short_answer reference answer
   resources?: QuizResource[];
+  explanations?: Record<string, string>;
+  source?: string;
+  confidence?: number;
+  flagged?: boolean;
+  judge_answer?: string;
 }
 
 export interface QuizGenerationForm {
@@ -28,17 +45,165 @@ export interface QuizGenerationForm {
   quiz_count: number;
   difficulty: string;
   topic?: string;
+  language?: string;
   model: string;
+  question_types?: QuizQuestionType[];
+  grounding?: boolean;
+  template_id?: string;
+}
+
+export interface QuizPromptTemplate {
+  id: string;
+  name: string;
+  description: string;
+  system: string;
+  prompt: string; // supports {{topic}}, {{difficulty}}, {{count}}, {{language}} and {{notes}}
+  groups: string[]; // visible to all groups if empty
+}
+
+export interface QuizPromptMessage {
+  role: "system" | "user";
+  content: string;
 }
 
 export interface QuizGenerationResponse {
+  id?: number;
   message: string;
   quota: number;
   end: boolean;
   error?: string;
-  data?: string;
+  type?: string; // error type of the failed generation
+  cached?: boolean;
+  progress?: QuizProgress;
+  cost?: QuizCost;
+  data?: Quiz[];
+}
+
+export interface QuizCost {
+  type: "question-billing" | "quiz-billing";
+  price: number;
+  count: number; // requested questions in the estimate, validated questions in the final frame
+  quota: number;
+}
+
+export interface QuizProgress {
+  current: number;
+  total: number;
+  skipped?: number; // chunks of a long source left out of the generation
 }
 
 export type QuizStatus = "idle" | "streaming" | "done" | "start" | "summary";
 
 export type QuizDifficulty = "Easy" | "Medium" | "Hard";
+
+export interface QuizAbility {
+  topic: string;
+  rating: number;
+  answered: number;
+  correct: number;
+  difficulty: QuizDifficulty;
+}
+
+export interface QuizAdaptiveForm {
+  token: string;
+  topic: string;
+  notes?: string;
+  model: string;
+  count?: number;
+  question_types?: QuizQuestionType[];
+}
+
+export interface QuizAdaptiveAnswerForm {
+  question_id: string;
+  answer: string;
+}
+
+export interface QuizAttemptTiming {
+  deadline: number; // unix milliseconds, 0 for untimed attempts
+  remaining: number; // seconds, -1 for untimed attempts
+  question_limit: number;
+}
+
+export interface Classroom {
+  id: number;
+  name: string;
+  code?: string;
+  teacher: string;
+  members: number;
+  time: string;
+}
+
+export interface QuizAssignment {
+  id: number;
+  classroom_id: number;
+  quiz_id: number;
+  quiz_name: string;
+  open_at?: string;
+  close_at?: string;
+  max_attempts: number;
+  time_limit: number;
+  question_limit: number;
+  attempts: number;
+  time?: string;
+}
+
+export interface GradebookRecord {
+  user_id: number;
+  username: string;
+  assignment_id: number;
+  quiz_name: string;
+  attempt_id: number;
+  score: number;
+  total: number;
+  finished: boolean;
+  duration: number;
+  expired: boolean;
+  elapsed?: Record<string, number>;
+  started_at?: string;
+  finished_at?: string;
+}
+
+export type LiveStage = "lobby" | "question" | "reveal" | "ended";
+
+export interface LiveScore {
+  rank: number;
+  name: string;
+  score: number;
+}
+
+export interface LiveEvent {
+  type:
+    | "room"
+    | "welcome"
+    | "join"
+    | "leave"
+    | "question"
+    | "answered"
+    | "result"
+    | "reveal"
+    | "end"
+    | "error";
+  pin?: string;
+  name?: string;
+  stage?: LiveStage;
+  players: number;
+  answered: number;
+  index: number;
+  total: number;
+  duration?: number;
+  deadline?: number;
+  question?: Quiz; // the answer fields are hidden
+  answer?: string;
+  correct?: boolean;
+  points: number;
+  score: number;
+  counts?: Record<string, number>;
+  leaderboard?: LiveScore[];
+  message?: string;
+}
+
+export interface LiveAction {
+  type: "next" | "end" | "answer";
+  index?: number;
+  answer?: string;
+}
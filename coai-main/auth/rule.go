@@ -62,3 +62,26 @@ func CanEnableModelWithSubscription(db *sql.DB, cache *redis.Client, user *User,
 	}
 	return CanEnableModel(db, user, model, messages), false
 }
+
+// CanEnableFeatureWithSubscription uses the feature item of the plan instead of the model item if the plan
+// meters the feature, the model quota is used when the feature allowance is exhausted
+func CanEnableFeatureWithSubscription(db *sql.DB, cache *redis.Client, user *User, feature string, model string, messages []globals.Message) (canEnable error, usePlan bool) {
+	if hit, ok := HandleFeatureUsage(db, cache, user, feature); hit {
+		if ok {
+			return nil, true
+		}
+		return CanEnableModel(db, user, model, messages), false
+	}
+	return CanEnableModelWithSubscription(db, cache, user, model, messages)
+}
+
+// RevertFeatureWithSubscription reverts the usage counted by CanEnableFeatureWithSubscription
+func RevertFeatureWithSubscription(db *sql.DB, cache *redis.Client, user *User, feature string, model string) bool {
+	if user == nil || disableSubscription() {
+		return false
+	}
+	if plan := user.GetPlan(db); plan.HasFeature(feature) {
+		return plan.DecreaseFeatureUsage(user, cache, feature)
+	}
+	return RevertSubscriptionUsage(db, cache, user, model)
+}
@@ -200,3 +200,23 @@ func RevertSubscriptionUsage(db *sql.DB, cache *redis.Client, user *User, model
 	plan := user.GetPlan(db)
 	return plan.DecreaseUsage(user, cache, model)
 }
+
+// HandleFeatureUsage counts the feature usage, hit is false if the plan of the user does not meter the feature
+func HandleFeatureUsage(db *sql.DB, cache *redis.Client, user *User, feature string) (hit bool, ok bool) {
+	if user == nil || disableSubscription() {
+		return false, false
+	}
+	plan := user.GetPlan(db)
+	if !plan.HasFeature(feature) {
+		return false, false
+	}
+	return true, plan.IncreaseFeatureUsage(user, cache, feature)
+}
+
+func RevertFeatureUsage(db *sql.DB, cache *redis.Client, user *User, feature string) bool {
+	if user == nil || disableSubscription() {
+		return false
+	}
+	plan := user.GetPlan(db)
+	return plan.DecreaseFeatureUsage(user, cache, feature)
+}
@@ -15,6 +15,7 @@ func NewChargeManager() *ChargeManager {
 	m := &ChargeManager{
 		Sequence:         seq,
 		Models:           map[string]*Charge{},
+		Features:         map[string]*Charge{},
 		NonBillingModels: []string{},
 	}
 	m.Load()
@@ -38,6 +39,9 @@ func (m *ChargeManager) Load() {
 	// init support models
 	m.Models = map[string]*Charge{}
 	for _, charge := range m.Sequence {
+		if charge.IsFeatureBilling() {
+			continue
+		}
 		for _, model := range charge.Models {
 			if _, ok := m.Models[model]; !ok {
 				m.Models[model] = charge
@@ -45,6 +49,19 @@ func (m *ChargeManager) Load() {
 		}
 	}
 
+	// init feature rules, which are applied by the feature key instead of the model name
+	m.Features = map[string]*Charge{}
+	for _, charge := range m.Sequence {
+		if !charge.IsFeatureBilling() {
+			continue
+		}
+		for _, feature := range charge.Features {
+			if _, ok := m.Features[feature]; !ok {
+				m.Features[feature] = charge
+			}
+		}
+	}
+
 	m.NonBillingModels = []string{}
 	for _, charge := range m.Sequence {
 		if !charge.IsBilling() {
@@ -78,6 +95,14 @@ func (m *ChargeManager) GetCharge(model string) *Charge {
 	}
 }
 
+// GetFeatureCharge returns the feature rule of the feature, nil if the feature is billed by its model
+func (m *ChargeManager) GetFeatureCharge(feature string) *Charge {
+	if charge, ok := m.Features[feature]; ok {
+		return charge
+	}
+	return nil
+}
+
 func (m *ChargeManager) SaveConfig() error {
 	viper.Set("charge", m.Sequence)
 	m.Load()
@@ -259,6 +284,10 @@ func (c *Charge) GetOutput() float32 {
 	return c.Output
 }
 
+func (c *Charge) GetFeatures() []string {
+	return c.Features
+}
+
 func (c *Charge) SupportAnonymous() bool {
 	return c.Anonymous
 }
@@ -271,11 +300,31 @@ func (c *Charge) IsBillingType(t string) bool {
 	return c.GetType() == t
 }
 
+func (c *Charge) IsFeatureBilling() bool {
+	return c.IsBillingType(globals.QuestionBilling) || c.IsBillingType(globals.QuizBilling)
+}
+
+// GetFeatureQuota returns the quota of the feature rule for count generated items
+func (c *Charge) GetFeatureQuota(count int) float32 {
+	if count <= 0 {
+		return 0
+	}
+
+	switch c.GetType() {
+	case globals.QuestionBilling:
+		return c.GetOutput() * float32(count)
+	case globals.QuizBilling:
+		return c.GetOutput()
+	default:
+		return 0
+	}
+}
+
 func (c *Charge) GetLimit() float32 {
 	switch c.GetType() {
 	case globals.NonBilling:
 		return 0
-	case globals.TimesBilling:
+	case globals.TimesBilling, globals.QuestionBilling, globals.QuizBilling:
 		return c.GetOutput()
 	case globals.TokenBilling:
 		// 1k input tokens + 1k output tokens
@@ -286,7 +335,7 @@ func (c *Charge) GetLimit() float32 {
 }
 
 func (c *Charge) Contains(model string) bool {
-	return utils.Contains(model, c.Models)
+	return !c.IsFeatureBilling() && utils.Contains(model, c.Models)
 }
 
 func (c *Charge) New(model string) *Charge {
@@ -24,11 +24,12 @@ type Plan struct {
 }
 
 type PlanItem struct {
-	Id     string   `json:"id" mapstructure:"id"`
-	Name   string   `json:"name" mapstructure:"name"`
-	Icon   string   `json:"icon" mapstructure:"icon"`
-	Value  int64    `json:"value" mapstructure:"value"`
-	Models []string `json:"models" mapstructure:"models"`
+	Id       string   `json:"id" mapstructure:"id"`
+	Name     string   `json:"name" mapstructure:"name"`
+	Icon     string   `json:"icon" mapstructure:"icon"`
+	Value    int64    `json:"value" mapstructure:"value"`
+	Models   []string `json:"models" mapstructure:"models"`
+	Features []string `json:"features" mapstructure:"features"` // metered features, counted apart from the models
 }
 
 type Usage struct {
@@ -270,6 +271,37 @@ func (p *Plan) ReleaseUsage(user globals.AuthLike, cache *redis.Client, model st
 	return false
 }
 
+// GetFeatureItem returns the item metering the feature, nil if the plan does not meter it
+func (p *Plan) GetFeatureItem(feature string) *PlanItem {
+	for i := range p.Items {
+		if utils.Contains(feature, p.Items[i].Features) {
+			return &p.Items[i]
+		}
+	}
+
+	return nil
+}
+
+func (p *Plan) HasFeature(feature string) bool {
+	return p.GetFeatureItem(feature) != nil
+}
+
+func (p *Plan) IncreaseFeatureUsage(user globals.AuthLike, cache *redis.Client, feature string) bool {
+	if item := p.GetFeatureItem(feature); item != nil {
+		return item.Increase(user, cache)
+	}
+
+	return false
+}
+
+func (p *Plan) DecreaseFeatureUsage(user globals.AuthLike, cache *redis.Client, feature string) bool {
+	if item := p.GetFeatureItem(feature); item != nil {
+		return item.Decrease(user, cache)
+	}
+
+	return false
+}
+
 func (p *Plan) ReleaseAll(user globals.AuthLike, cache *redis.Client) bool {
 	for _, usage := range p.Items {
 		if !usage.Release(user, cache) {
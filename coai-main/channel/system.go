@@ -75,11 +75,18 @@ type SearchState struct {
 type commonState struct {
 	Article     []string `json:"article" mapstructure:"article"`
 	Generation  []string `json:"generation" mapstructure:"generation"`
+	Classroom   []string `json:"classroom" mapstructure:"classroom"`
 	Cache       []string `json:"cache" mapstructure:"cache"`
 	Expire      int64    `json:"expire" mapstructure:"expire"`
 	Size        int64    `json:"size" mapstructure:"size"`
 	ImageStore  bool     `json:"image_store" mapstructure:"imagestore"`
 	PromptStore bool     `json:"prompt_store" mapstructure:"promptstore"`
+	QuizCache   []string `json:"quiz_cache" mapstructure:"quizcache"`
+	QuizExpire  int64    `json:"quiz_expire" mapstructure:"quizexpire"`
+	QuizPool    int64    `json:"quiz_pool" mapstructure:"quizpool"`
+	QuizShuffle bool     `json:"quiz_shuffle" mapstructure:"quizshuffle"`
+	QuizJudge   string   `json:"quiz_judge" mapstructure:"quizjudge"`
+	JudgePolicy string   `json:"quiz_judge_policy" mapstructure:"quizjudgepolicy"`
 }
 
 type SystemConfig struct {
@@ -109,6 +116,7 @@ func (c *SystemConfig) Load() {
 
 	globals.ArticlePermissionGroup = c.Common.Article
 	globals.GenerationPermissionGroup = c.Common.Generation
+	globals.ClassroomPermissionGroup = c.Common.Classroom
 	globals.CacheAcceptedModels = c.Common.Cache
 
 	globals.CacheAcceptedExpire = c.GetCacheAcceptedExpire()
@@ -117,6 +125,13 @@ func (c *SystemConfig) Load() {
 
 	globals.AcceptPromptStore = c.Common.PromptStore
 
+	globals.QuizCacheModels = c.Common.QuizCache
+	globals.QuizCacheExpire = c.GetQuizCacheExpire()
+	globals.QuizCachePool = c.GetQuizCachePool()
+	globals.QuizCacheShuffle = c.Common.QuizShuffle
+	globals.QuizJudgeModel = c.Common.QuizJudge
+	globals.QuizJudgePolicy = c.GetQuizJudgePolicy()
+
 	if c.General.PWAManifest == "" {
 		c.General.PWAManifest = utils.ReadPWAManifest()
 	}
@@ -294,6 +309,32 @@ func (c *SystemConfig) GetCacheAcceptedSize() int64 {
 	return c.Common.Size
 }
 
+func (c *SystemConfig) GetQuizCacheExpire() int64 {
+	if c.Common.QuizExpire <= 0 {
+		// default 1 day
+		return 86400
+	}
+
+	return c.Common.QuizExpire
+}
+
+func (c *SystemConfig) GetQuizCachePool() int64 {
+	if c.Common.QuizPool < 1 {
+		return 1
+	}
+
+	return c.Common.QuizPool
+}
+
+func (c *SystemConfig) GetQuizJudgePolicy() string {
+	switch c.Common.JudgePolicy {
+	case globals.QuizJudgeFlag, globals.QuizJudgeRegenerate, globals.QuizJudgeDrop:
+		return c.Common.JudgePolicy
+	}
+
+	return globals.QuizJudgeOff
+}
+
 func (c *SystemConfig) AcceptImageStore() bool {
 	// if notify url is empty, then image store is not allowed
 	if len(strings.TrimSpace(globals.NotifyUrl)) == 0 {
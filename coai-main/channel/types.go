@@ -41,6 +41,7 @@ type Charge struct {
 	Id        int      `json:"id" mapstructure:"id"`
 	Type      string   `json:"type" mapstructure:"type"`
 	Models    []string `json:"models" mapstructure:"models"`
+	Features  []string `json:"features" mapstructure:"features"` // feature keys of the feature billing types
 	Input     float32  `json:"input" mapstructure:"input"`
 	Output    float32  `json:"output" mapstructure:"output"`
 	Anonymous bool     `json:"anonymous" mapstructure:"anonymous"`
@@ -52,5 +53,6 @@ type ChargeSequence []*Charge
 type ChargeManager struct {
 	Sequence         ChargeSequence     `json:"sequence"`
 	Models           map[string]*Charge `json:"models"`
+	Features         map[string]*Charge `json:"features"`
 	NonBillingModels []string           `json:"non_billing_models"`
 }
@@ -20,7 +20,7 @@ func NewChatRequest(group string, props *adaptercommon.ChatProps, hook globals.H
 	for !ticker.IsDone() {
 		if channel := ticker.Next(); channel != nil {
 			props.MaxRetries = utils.ToPtr(channel.GetRetry())
-			if err = adapter.NewChatRequest(channel, props, hook); adapter.IsSkipError(err) {
+			if err = adapter.NewChatRequest(channel, getChannelProps(channel, props), hook); adapter.IsSkipError(err) {
 				return err
 			}
 
@@ -37,6 +37,18 @@ func NewChatRequest(group string, props *adaptercommon.ChatProps, hook globals.H
 	return err
 }
 
+// getChannelProps drops the optional tools if the channel type does not support tool calling
+func getChannelProps(channel *Channel, props *adaptercommon.ChatProps) *adaptercommon.ChatProps {
+	if !props.OptionalTools || props.Tools == nil || utils.Contains(channel.GetType(), globals.ToolCallingChannelTypes) {
+		return props
+	}
+
+	fallback := *props
+	fallback.Tools = nil
+	fallback.ToolChoice = nil
+	return &fallback
+}
+
 func PreflightCache(cache *redis.Client, model string, hash string, buffer *utils.Buffer, hook globals.Hook) (int64, bool, error) {
 	if !utils.Contains(model, globals.CacheAcceptedModels) {
 		return 0, false, nil
@@ -16,6 +16,8 @@ func Run() bool {
 		CreateTokenCommand(param)
 	case "root":
 		UpdateRootCommand(param)
+	case "quiz-import":
+		ImportQuizCommand(param)
 	default:
 		return false
 	}
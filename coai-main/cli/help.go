@@ -8,6 +8,7 @@ Commands:
 	- invite <type> <num> <quota>
 	- token <user-id>
 	- root <password>
+	- quiz-import <user-id> <file> [gift|moodle|csv|json]
 `
 
 func Help() {
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"chat/connection"
+	"chat/quiz"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func ImportQuizCommand(args []string) {
+	db := connection.ConnectDatabase()
+
+	var (
+		id   = GetArgInt64(args, 0)
+		path = GetArgString(args, 1)
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		outputError(err)
+		return
+	}
+
+	format := quiz.DetectImportFormat(path, data)
+	if len(args) > 2 {
+		format = strings.ToLower(GetArgString(args, 2))
+	}
+
+	result, err := quiz.ImportQuiz(db, id, name, format, data)
+	if err != nil {
+		outputError(err)
+		return
+	}
+
+	for _, row := range result.Errors {
+		fmt.Println(fmt.Sprintf("row %d: %s", row.Row, row.Message))
+	}
+	for _, row := range result.Duplicates {
+		if row.QuizId > 0 {
+			fmt.Println(fmt.Sprintf("row %d: duplicate of quiz %d (%s)", row.Row, row.QuizId, row.QuizName))
+		} else {
+			fmt.Println(fmt.Sprintf("row %d: duplicate in the file", row.Row))
+		}
+	}
+
+	if result.Imported == 0 {
+		outputError(fmt.Errorf("no new valid questions to import"))
+		return
+	}
+
+	outputInfo("quiz", fmt.Sprintf(
+		"%d of %d questions imported as quiz %d (%d duplicates, %d errors)",
+		result.Imported, result.Total, result.Id, len(result.Duplicates), len(result.Errors),
+	))
+}
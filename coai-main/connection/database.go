@@ -90,6 +90,16 @@ func ConnectDatabase() *sql.DB {
 	CreateInvitationTable(db)
 	CreateRedeemTable(db)
 	CreateBroadcastTable(db)
+	CreateQuizTable(db)
+	CreateQuizAttemptTable(db)
+	CreateQuizReviewTable(db)
+	CreateQuizSharingTable(db)
+	CreateQuizShareAttemptTable(db)
+	CreateQuizAbilityTable(db)
+	CreateClassroomTable(db)
+	CreateClassroomMemberTable(db)
+	CreateQuizAssignmentTable(db)
+	CreateQuizAssignmentAttemptTable(db)
 
 	if err := doMigration(db); err != nil {
 		fmt.Println(fmt.Sprintf("migration error: %s", err))
@@ -315,3 +325,208 @@ func CreateBroadcastTable(db *sql.DB) {
 		fmt.Println(err)
 	}
 }
+
+func CreateQuizTable(db *sql.DB) {
+	// source_hash is the sha256 hash of the source notes and files
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  user_id INT,
+		  quiz_name VARCHAR(255),
+		  model VARCHAR(255),
+		  topic VARCHAR(255),
+		  difficulty VARCHAR(32),
+		  source_hash CHAR(64),
+		  quiz_count INT DEFAULT 0,
+		  language VARCHAR(32) DEFAULT '',
+		  parent_id INT DEFAULT 0,
+		  data MEDIUMTEXT,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizAttemptTable(db *sql.DB) {
+	// answers is a json object of question id -> selected option, timer is the json of the time limits
+	// and the elapsed time of the questions, deadline is in unix milliseconds
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_attempt (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  user_id INT,
+		  quiz_id INT,
+		  answers MEDIUMTEXT,
+		  score INT DEFAULT 0,
+		  total INT DEFAULT 0,
+		  finished BOOLEAN DEFAULT FALSE,
+		  timer MEDIUMTEXT,
+		  deadline BIGINT DEFAULT 0,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  finished_at DATETIME,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizReviewTable(db *sql.DB) {
+	// spaced-repetition schedule of the missed quiz questions (SM-2)
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_review (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  user_id INT,
+		  quiz_id INT,
+		  question_id VARCHAR(64),
+		  ease DECIMAL(6, 2) DEFAULT 2.5,
+		  review_interval INT DEFAULT 0,
+		  repetitions INT DEFAULT 0,
+		  due_at DATETIME,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  UNIQUE KEY (user_id, quiz_id, question_id),
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizSharingTable(db *sql.DB) {
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_sharing (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  hash CHAR(32) UNIQUE,
+		  user_id INT,
+		  quiz_id INT,
+		  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizShareAttemptTable(db *sql.DB) {
+	// user_id is null for anonymous attempts, token authorizes the submissions of the attempt
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_share_attempt (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  hash CHAR(32),
+		  user_id INT,
+		  token CHAR(32),
+		  name VARCHAR(255),
+		  answers MEDIUMTEXT,
+		  score INT DEFAULT 0,
+		  total INT DEFAULT 0,
+		  finished BOOLEAN DEFAULT FALSE,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  finished_at DATETIME,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizAbilityTable(db *sql.DB) {
+	// topic is the normalized quiz topic, rating is the elo-style ability estimate of the user on the topic
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_ability (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  user_id INT,
+		  topic VARCHAR(255),
+		  rating DECIMAL(8, 2) DEFAULT 1000,
+		  answered INT DEFAULT 0,
+		  correct INT DEFAULT 0,
+		  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  UNIQUE KEY (user_id, topic),
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateClassroomTable(db *sql.DB) {
+	// user_id is the teacher of the classroom, students join the classroom with the code
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS classroom (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  user_id INT,
+		  name VARCHAR(255),
+		  code VARCHAR(64) UNIQUE,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateClassroomMemberTable(db *sql.DB) {
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS classroom_member (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  classroom_id INT,
+		  user_id INT,
+		  joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  UNIQUE KEY (classroom_id, user_id),
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizAssignmentTable(db *sql.DB) {
+	// open_at and close_at are stored in utc and null for an unlimited window, max_attempts is 0 for unlimited attempts
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_assignment (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  classroom_id INT,
+		  quiz_id INT,
+		  open_at DATETIME,
+		  close_at DATETIME,
+		  max_attempts INT DEFAULT 0,
+		  time_limit INT DEFAULT 0,
+		  question_limit INT DEFAULT 0,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func CreateQuizAssignmentAttemptTable(db *sql.DB) {
+	_, err := globals.ExecDb(db, `
+		CREATE TABLE IF NOT EXISTS quiz_assignment_attempt (
+		  id INT PRIMARY KEY AUTO_INCREMENT,
+		  assignment_id INT,
+		  user_id INT,
+		  answers MEDIUMTEXT,
+		  score INT DEFAULT 0,
+		  total INT DEFAULT 0,
+		  finished BOOLEAN DEFAULT FALSE,
+		  timer MEDIUMTEXT,
+		  deadline BIGINT DEFAULT 0,
+		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		  finished_at DATETIME,
+		  FOREIGN KEY (user_id) REFERENCES auth(id)
+		);
+	`)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
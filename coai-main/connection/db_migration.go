@@ -3,6 +3,7 @@ package connection
 import (
 	"chat/globals"
 	"database/sql"
+	"fmt"
 	"strings"
 )
 
@@ -15,8 +16,10 @@ func validSqlError(err error) bool {
 
 	// Error 1060: Duplicate column name
 	// Error 1050: Table already exists
+	// sqlite: duplicate column name
 
-	return !(strings.Contains(content, "Error 1060") || strings.Contains(content, "Error 1050"))
+	return !(strings.Contains(content, "Error 1060") || strings.Contains(content, "Error 1050") ||
+		strings.Contains(content, "duplicate column name"))
 }
 
 func checkSqlError(_ sql.Result, err error) error {
@@ -57,11 +60,62 @@ func doMigration(db *sql.DB) error {
 		return err
 	}
 
-	return nil
+	return doQuizMigration(db)
 }
 
 func doSqliteMigration(db *sql.DB) error {
 	// v3.10 added sqlite support, no migration needed before this version
 
+	return doQuizMigration(db)
+}
+
+func doQuizMigration(db *sql.DB) error {
+	// add new field `language` and `parent_id` (the quiz translated from) in `quiz` table,
+	// sqlite only supports adding a single column in an alter statement
+	if err := execSql(db, `
+		ALTER TABLE quiz
+		ADD COLUMN language VARCHAR(32) DEFAULT '';
+	`); err != nil {
+		return err
+	}
+
+	if err := execSql(db, `
+		ALTER TABLE quiz
+		ADD COLUMN parent_id INT DEFAULT 0;
+	`); err != nil {
+		return err
+	}
+
+	// add the timer fields of the timed attempts
+	for _, table := range []string{"quiz_attempt", "quiz_assignment_attempt"} {
+		if err := execSql(db, fmt.Sprintf(`
+			ALTER TABLE %s
+			ADD COLUMN timer MEDIUMTEXT;
+		`, table)); err != nil {
+			return err
+		}
+
+		if err := execSql(db, fmt.Sprintf(`
+			ALTER TABLE %s
+			ADD COLUMN deadline BIGINT DEFAULT 0;
+		`, table)); err != nil {
+			return err
+		}
+	}
+
+	if err := execSql(db, `
+		ALTER TABLE quiz_assignment
+		ADD COLUMN time_limit INT DEFAULT 0;
+	`); err != nil {
+		return err
+	}
+
+	if err := execSql(db, `
+		ALTER TABLE quiz_assignment
+		ADD COLUMN question_limit INT DEFAULT 0;
+	`); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -30,12 +30,31 @@ const (
 	CozeChannelType        = "coze"
 )
 
+// ToolCallingChannelTypes are the channel types whose adapters support the function tools
+var ToolCallingChannelTypes = []string{
+	OpenAIChannelType,
+	AzureOpenAIChannelType,
+	ClaudeChannelType,
+	DeepseekChannelType,
+	ChatGLMChannelType,
+}
+
+const (
+	NonBilling      = "non-billing"
+	TimesBilling    = "times-billing"
+	TokenBilling    = "token-billing"
+	QuestionBilling = "question-billing" // feature billing, flat price per generated question
+	QuizBilling     = "quiz-billing"     // feature billing, flat price per generated quiz
+)
+
 const (
-	NonBilling   = "non-billing"
-	TimesBilling = "times-billing"
-	TokenBilling = "token-billing"
+	QuizFeature        = "quiz"         // quiz generations
+	QuizAttemptFeature = "quiz-attempt" // quiz attempts
 )
 
+var ChargeFeatures = []string{QuizFeature}
+var PlanFeatures = []string{QuizFeature, QuizAttemptFeature}
+
 const (
 	AnonymousType = "anonymous"
 	NormalType    = "normal"
@@ -52,6 +71,13 @@ const (
 	Socks5ProxyType
 )
 
+const (
+	QuizJudgeOff        = "off"        // answer keys are not verified
+	QuizJudgeFlag       = "flag"       // disputed questions are flagged
+	QuizJudgeRegenerate = "regenerate" // disputed questions are rewritten by the generator and verified again
+	QuizJudgeDrop       = "drop"       // disputed questions are dropped
+)
+
 const (
 	WebTokenType = "web"
 	ApiTokenType = "api"
@@ -12,6 +12,7 @@ const ChatMaxThread = 5
 const AnonymousMaxThread = 1
 
 var HttpMaxTimeout = 30 * time.Minute
+var LinkCheckTimeout = 10 * time.Second
 
 var AllowedOrigins []string
 
@@ -19,9 +20,16 @@ var DebugMode bool
 var NotifyUrl = ""
 var ArticlePermissionGroup []string
 var GenerationPermissionGroup []string
+var ClassroomPermissionGroup []string
 var CacheAcceptedModels []string
 var CacheAcceptedExpire int64
 var CacheAcceptedSize int64
+var QuizCacheModels []string
+var QuizCacheExpire int64
+var QuizCachePool int64 // size of the cached question pool as a multiple of the question count
+var QuizCacheShuffle bool
+var QuizJudgeModel string
+var QuizJudgePolicy string
 var AcceptImageStore bool
 var AcceptPromptStore bool
 var CloseRegistration bool
@@ -59,6 +59,7 @@ func main() {
 	utils.ReadConf()
 	admin.InitInstance()
 	channel.InitManager()
+	quiz.InitTemplates()
 
 	if cli.Run() {
 		return
@@ -71,6 +72,7 @@ func main() {
 	utils.RegisterStaticRoute(app)
 	registerApiRouter(app)
 	readCorsOrigins()
+	quiz.SweepWorker()
 
 	if err := app.Run(fmt.Sprintf(":%s", viper.GetString("server.port"))); err != nil {
 		panic(err)
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"chat/auth"
+	"chat/channel"
 	"chat/utils"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -13,13 +14,14 @@ type BillingResponse struct {
 }
 
 type SubscriptionResponse struct {
-	Object             string  `json:"object"`
-	SoftLimit          int64   `json:"soft_limit"`
-	HardLimit          int64   `json:"hard_limit"`
-	SystemHardLimit    int64   `json:"system_hard_limit"`
-	SoftLimitUSD       float32 `json:"soft_limit_usd"`
-	HardLimitUSD       float32 `json:"hard_limit_usd"`
-	SystemHardLimitUSD float32 `json:"system_hard_limit_usd"`
+	Object             string           `json:"object"`
+	SoftLimit          int64            `json:"soft_limit"`
+	HardLimit          int64            `json:"hard_limit"`
+	SystemHardLimit    int64            `json:"system_hard_limit"`
+	SoftLimitUSD       float32          `json:"soft_limit_usd"`
+	HardLimitUSD       float32          `json:"hard_limit_usd"`
+	SystemHardLimitUSD float32          `json:"system_hard_limit_usd"`
+	Usage              channel.UsageMap `json:"usage,omitempty"` // monthly usage of the subscription plan items
 }
 
 func GetBillingUsage(c *gin.Context) {
@@ -44,10 +46,16 @@ func GetSubscription(c *gin.Context) {
 	}
 
 	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
 	quota := user.GetQuota(db)
 	used := user.GetUsedQuota(db)
 	total := quota + used
 
+	var usage channel.UsageMap
+	if user.IsSubscribe(db) {
+		usage = user.GetSubscriptionUsage(db, cache)
+	}
+
 	c.JSON(http.StatusOK, SubscriptionResponse{
 		Object:             "billing_subscription",
 		SoftLimit:          int64(quota * 100),
@@ -56,5 +64,6 @@ func GetSubscription(c *gin.Context) {
 		SoftLimitUSD:       quota / 7.3 / 10,
 		HardLimitUSD:       total / 7.3 / 10,
 		SystemHardLimitUSD: 1000000,
+		Usage:              usage,
 	})
 }
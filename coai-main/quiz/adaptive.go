@@ -0,0 +1,501 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the adaptive mode keeps an elo-style ability rating of the user per topic. each question is rated by
+// its difficulty, the next question is drawn at the difficulty closest to the rating of the user, from the
+// saved questions of the topic first and generated on demand if the bank has none left. after each answer
+// the rating moves by K times the difference of the score and the expected score of the question.
+
+const (
+	defaultRating        = 1000.
+	adaptiveCount        = 10 // default question count of an adaptive session
+	maxAdaptiveCount     = 50
+	adaptiveBatch        = 3  // questions generated at once if the bank is empty
+	provisionalAnswers   = 10 // the rating moves faster for the first answers of the topic
+	provisionalK         = 48.
+	establishedK         = 24.
+	defaultAdaptiveTopic = "general"
+)
+
+var difficultyRatings = map[string]float64{
+	"Easy":   800,
+	"Medium": 1000,
+	"Hard":   1200,
+}
+
+var difficultyLevels = []string{"Easy", "Medium", "Hard"}
+
+// Ability represents the ability estimate of the user on a topic
+type Ability struct {
+	Topic      string  `json:"topic"`
+	Rating     float64 `json:"rating"`
+	Answered   int     `json:"answered"`
+	Correct    int     `json:"correct"`
+	Difficulty string  `json:"difficulty"` // difficulty of the next question
+}
+
+// AdaptiveForm is the first message of the adaptive websocket
+type AdaptiveForm struct {
+	Token         string   `json:"token"`
+	Topic         string   `json:"topic"`
+	Notes         string   `json:"notes,omitempty"` // source of the generated questions
+	Model         string   `json:"model"`           // model of the generated questions
+	Language      string   `json:"language,omitempty"`
+	Count         int      `json:"count"`
+	QuestionTypes []string `json:"question_types,omitempty"`
+}
+
+// AdaptiveAnswerForm is the answer message of the adaptive websocket
+type AdaptiveAnswerForm struct {
+	QuestionId string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// AdaptiveResponse represents the frames of the adaptive websocket
+type AdaptiveResponse struct {
+	Message    string          `json:"message"`
+	Index      int             `json:"index,omitempty"` // index of the question in the session, from 1
+	Total      int             `json:"total,omitempty"`
+	Question   *QuizQuestion   `json:"question,omitempty"`
+	Difficulty string          `json:"difficulty,omitempty"` // difficulty of the question
+	Result     *QuestionResult `json:"result,omitempty"`
+	Ability    *Ability        `json:"ability,omitempty"`
+	Quota      float32         `json:"quota,omitempty"`
+	End        bool            `json:"end"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// adaptiveQuestion is a question of the bank with the saved quiz and the difficulty of it
+type adaptiveQuestion struct {
+	QuizId     int64
+	Difficulty string
+	Quiz       Quiz
+}
+
+func normalizeTopic(topic string) string {
+	if topic = normalizeText(topic); len(topic) == 0 {
+		return defaultAdaptiveTopic
+	}
+	return utils.Extract(topic, 255, "")
+}
+
+func getDifficultyLevel(difficulty string) string {
+	for _, level := range difficultyLevels {
+		if strings.EqualFold(strings.TrimSpace(difficulty), level) {
+			return level
+		}
+	}
+	return "Medium"
+}
+
+// GetDifficulty returns the difficulty level whose rating is closest to the ability rating
+func (a *Ability) GetDifficulty() string {
+	result, distance := difficultyLevels[0], math.Inf(1)
+	for _, level := range difficultyLevels {
+		if d := math.Abs(difficultyRatings[level] - a.Rating); d < distance {
+			result, distance = level, d
+		}
+	}
+	return result
+}
+
+// ExpectedScore returns the probability of answering a question of the difficulty correctly
+func (a *Ability) ExpectedScore(difficulty string) float64 {
+	return 1 / (1 + math.Pow(10, (difficultyRatings[getDifficultyLevel(difficulty)]-a.Rating)/400))
+}
+
+// Update moves the rating by the answer to a question of the difficulty
+func (a *Ability) Update(difficulty string, correct bool) {
+	k := establishedK
+	if a.Answered < provisionalAnswers {
+		k = provisionalK
+	}
+
+	score := 0.
+	if correct {
+		score = 1
+		a.Correct++
+	}
+
+	a.Rating = math.Round((a.Rating+k*(score-a.ExpectedScore(difficulty)))*100) / 100
+	a.Answered++
+	a.Difficulty = a.GetDifficulty()
+}
+
+// LoadAbility loads the ability of the user on the topic, the default ability is returned if not rated yet
+func LoadAbility(db *sql.DB, userId int64, topic string) (*Ability, bool) {
+	ability := Ability{
+		Topic:  normalizeTopic(topic),
+		Rating: defaultRating,
+	}
+
+	err := globals.QueryRowDb(db, `
+		SELECT rating, answered, correct FROM quiz_ability WHERE user_id = ? AND topic = ?
+	`, userId, ability.Topic).Scan(&ability.Rating, &ability.Answered, &ability.Correct)
+	ability.Difficulty = ability.GetDifficulty()
+
+	return &ability, err == nil
+}
+
+func (a *Ability) Save(db *sql.DB, userId int64, exists bool) error {
+	var err error
+	if exists {
+		_, err = globals.ExecDb(db, `
+			UPDATE quiz_ability SET rating = ?, answered = ?, correct = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND topic = ?
+		`, a.Rating, a.Answered, a.Correct, userId, a.Topic)
+	} else {
+		_, err = globals.ExecDb(db, `
+			INSERT INTO quiz_ability (user_id, topic, rating, answered, correct) VALUES (?, ?, ?, ?, ?)
+		`, userId, a.Topic, a.Rating, a.Answered, a.Correct)
+	}
+
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during save ability: %s", err.Error()))
+	}
+	return err
+}
+
+// LoadAbilityList loads the abilities of the user ordered by the last update
+func LoadAbilityList(db *sql.DB, userId int64) []Ability {
+	list := make([]Ability, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT topic, rating, answered, correct FROM quiz_ability
+		WHERE user_id = ?
+		ORDER BY updated_at DESC LIMIT 100
+	`, userId)
+	if err != nil {
+		return list
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ability Ability
+		if err := rows.Scan(&ability.Topic, &ability.Rating, &ability.Answered, &ability.Correct); err != nil {
+			continue
+		}
+		ability.Difficulty = ability.GetDifficulty()
+		list = append(list, ability)
+	}
+
+	return list
+}
+
+// loadAdaptiveBank loads the saved questions of the normalized topic (in the language if set),
+// the saved topics are matched with normalizeTopic as the ability ratings and the questions flagged by the judge are skipped
+func loadAdaptiveBank(db *sql.DB, userId int64, topic string, language string) []adaptiveQuestion {
+	bank := make([]adaptiveQuestion, 0)
+
+	// the like pattern only narrows the rows down, the whitespace of the saved topics may differ
+	condition := "LOWER(topic) LIKE ?"
+	if topic == defaultAdaptiveTopic {
+		condition = "(LOWER(topic) LIKE ? OR TRIM(COALESCE(topic, '')) = '')"
+	}
+	rows, err := globals.QueryDb(db, fmt.Sprintf(`
+		SELECT id, COALESCE(topic, ''), difficulty, language, data FROM quiz WHERE user_id = ? AND %s
+		ORDER BY id DESC LIMIT 100
+	`, condition), userId, "%"+strings.ReplaceAll(topic, " ", "%")+"%")
+	if err != nil {
+		return bank
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id         int64
+			saved      string
+			difficulty string
+			lang       string
+			data       string
+		)
+		if err := rows.Scan(&id, &saved, &difficulty, &lang, &data); err != nil {
+			continue
+		}
+
+		if normalizeTopic(saved) != topic {
+			continue
+		}
+
+		if len(language) > 0 && !strings.EqualFold(lang, language) {
+			continue
+		}
+
+		quizzes, err := utils.UnmarshalString[[]Quiz](data)
+		if err != nil {
+			continue
+		}
+
+		for _, q := range quizzes {
+			if !q.Flagged {
+				bank = append(bank, adaptiveQuestion{QuizId: id, Difficulty: getDifficultyLevel(difficulty), Quiz: q})
+			}
+		}
+	}
+
+	return bank
+}
+
+// AdaptiveSession serves the questions of an adaptive quiz one at a time
+type AdaptiveSession struct {
+	Form    AdaptiveForm
+	UserId  int64
+	Ability *Ability
+	Bank    []adaptiveQuestion
+	Served  map[string]bool // served questions by quiz id and question id
+	Rated   bool            // the ability is saved in the database
+	Current *adaptiveQuestion
+	Index   int
+	Quota   float32
+}
+
+func NewAdaptiveSession(db *sql.DB, userId int64, form AdaptiveForm) *AdaptiveSession {
+	if form.Count <= 0 {
+		form.Count = adaptiveCount
+	} else if form.Count > maxAdaptiveCount {
+		form.Count = maxAdaptiveCount
+	}
+
+	ability, rated := LoadAbility(db, userId, form.Topic)
+	return &AdaptiveSession{
+		Form:    form,
+		UserId:  userId,
+		Ability: ability,
+		Bank:    loadAdaptiveBank(db, userId, normalizeTopic(form.Topic), getLanguageName(form.Language)),
+		Served:  map[string]bool{},
+		Rated:   rated,
+	}
+}
+
+func getServedKey(item adaptiveQuestion) string {
+	return fmt.Sprintf("%d:%s", item.QuizId, item.Quiz.ID)
+}
+
+// pickQuestion picks a random unserved question of the difficulty from the bank
+func (s *AdaptiveSession) pickQuestion(difficulty string) *adaptiveQuestion {
+	candidates := make([]adaptiveQuestion, 0)
+	for _, item := range s.Bank {
+		if item.Difficulty == difficulty && !s.Served[getServedKey(item)] {
+			candidates = append(candidates, item)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return &candidates[rand.Intn(len(candidates))]
+}
+
+// generateQuestions generates a batch of questions of the difficulty into the library and the bank,
+// the generation is checked and billed as a normal quiz generation
+func (s *AdaptiveSession) generateQuestions(c *gin.Context, user *auth.User, difficulty string, send func(response AdaptiveResponse)) error {
+	if len(s.Form.Model) == 0 {
+		return fmt.Errorf("no more questions of difficulty %s in the bank", difficulty)
+	}
+
+	form := QuizGenerationRequest{
+		Notes:         s.Form.Notes,
+		Topic:         s.Form.Topic,
+		Language:      s.Form.Language,
+		Model:         s.Form.Model,
+		Difficulty:    difficulty,
+		QuizCount:     adaptiveBatch,
+		QuestionTypes: s.Form.QuestionTypes,
+	}
+
+	var result QuizGenerationResponse
+	processQuizGeneration(c, user, &form, func(response QuizGenerationResponse) {
+		if response.End {
+			result = response
+			return
+		}
+		send(AdaptiveResponse{Message: response.Message, Quota: s.Quota + response.Quota})
+	})
+
+	s.Quota += result.Quota
+	if len(result.Error) > 0 {
+		return errors.New(result.Message)
+	}
+
+	for _, q := range result.Data {
+		if !q.Flagged {
+			s.Bank = append(s.Bank, adaptiveQuestion{QuizId: result.Id, Difficulty: difficulty, Quiz: q})
+		}
+	}
+	return nil
+}
+
+// Next serves the next question at the difficulty of the ability, nil if the session is finished
+func (s *AdaptiveSession) Next(c *gin.Context, user *auth.User, send func(response AdaptiveResponse)) (*adaptiveQuestion, error) {
+	if s.Index >= s.Form.Count {
+		return nil, nil
+	}
+
+	difficulty := s.Ability.GetDifficulty()
+	item := s.pickQuestion(difficulty)
+	if item == nil {
+		send(AdaptiveResponse{
+			Message: fmt.Sprintf("generating %s questions...", strings.ToLower(difficulty)),
+			Quota:   s.Quota,
+		})
+
+		if err := s.generateQuestions(c, user, difficulty, send); err != nil {
+			return nil, err
+		}
+		if item = s.pickQuestion(difficulty); item == nil {
+			return nil, fmt.Errorf("no valid questions generated")
+		}
+	}
+
+	s.Index++
+	s.Current = item
+	s.Served[getServedKey(*item)] = true
+	return item, nil
+}
+
+// Answer grades the answer of the current question and updates the ability
+func (s *AdaptiveSession) Answer(db *sql.DB, form AdaptiveAnswerForm) (*QuestionResult, error) {
+	if s.Current == nil {
+		return nil, fmt.Errorf("no question to answer")
+	}
+	if form.QuestionId != s.Current.Quiz.ID {
+		return nil, fmt.Errorf("question %s is not the current question", form.QuestionId)
+	}
+
+	item := s.Current
+	s.Current = nil
+
+	question := item.Quiz
+	correct := question.IsCorrect(form.Answer)
+	s.Ability.Update(item.Difficulty, correct)
+	if err := s.Ability.Save(db, s.UserId, s.Rated); err == nil {
+		s.Rated = true
+	}
+
+	if !correct && item.QuizId > 0 {
+		_ = EnqueueReview(db, s.UserId, item.QuizId, question.ID)
+	}
+
+	return &QuestionResult{
+		ID:          question.ID,
+		Selected:    form.Answer,
+		Answer:      question.AnswerKey(),
+		Correct:     correct,
+		Explanation: question.Explain(form.Answer),
+		Description: question.Description,
+		ModelAnswer: question.ModelAnswer,
+	}, nil
+}
+
+// AdaptiveAPI serves an adaptive quiz via WebSocket, the first message is the adaptive form
+// and each following message answers the last served question
+func AdaptiveAPI(c *gin.Context) {
+	var conn *utils.WebSocket
+	if conn = utils.NewWebsocket(c, false); conn == nil {
+		return
+	}
+	defer conn.DeferClose()
+
+	form, err := utils.ReadForm[AdaptiveForm](conn)
+	if err != nil {
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	user := auth.ParseToken(c, form.Token)
+	if user == nil {
+		conn.Send(AdaptiveResponse{Message: "user not found", End: true, Error: "user not found"})
+		return
+	}
+
+	if !auth.HitGroups(db, user, QuizPermissionGroup) {
+		conn.Send(AdaptiveResponse{
+			Message: "permission denied: quiz feature not available",
+			End:     true,
+			Error:   "permission denied",
+		})
+		return
+	}
+
+	// the adaptive quiz is metered as one attempt, the usage is reverted if no question is served
+	revert, err := useAttemptFeature(c, user)
+	if err != nil {
+		conn.Send(AdaptiveResponse{Message: err.Error(), End: true, Error: err.Error()})
+		return
+	}
+
+	session := NewAdaptiveSession(db, user.GetID(db), *form)
+	send := func(response AdaptiveResponse) {
+		conn.Send(response)
+	}
+
+	for {
+		item, err := session.Next(c, user, send)
+		if err != nil {
+			if session.Index == 0 {
+				revert()
+			}
+			send(AdaptiveResponse{
+				Message: fmt.Sprintf("failed to serve the next question: %s", err.Error()),
+				Ability: session.Ability,
+				Quota:   session.Quota,
+				End:     true,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if item == nil {
+			send(AdaptiveResponse{
+				Message: "adaptive quiz completed",
+				Ability: session.Ability,
+				Quota:   session.Quota,
+				End:     true,
+			})
+			return
+		}
+
+		question := item.Quiz.Hide()
+		send(AdaptiveResponse{
+			Message:    "next question",
+			Index:      session.Index,
+			Total:      session.Form.Count,
+			Question:   &question,
+			Difficulty: item.Difficulty,
+			Ability:    session.Ability,
+			Quota:      session.Quota,
+		})
+
+		answer, err := utils.ReadForm[AdaptiveAnswerForm](conn)
+		if err != nil {
+			return
+		}
+
+		result, err := session.Answer(db, *answer)
+		if err != nil {
+			send(AdaptiveResponse{Message: err.Error(), End: true, Error: err.Error()})
+			return
+		}
+
+		send(AdaptiveResponse{
+			Message: "answer graded",
+			Index:   session.Index,
+			Total:   session.Form.Count,
+			Result:  result,
+			Ability: session.Ability,
+			Quota:   session.Quota,
+		})
+	}
+}
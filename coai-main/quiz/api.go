@@ -0,0 +1,1784 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RenameQuizForm struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type ImportQuizForm struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename"` // optional, used to detect the format
+	Format   string `json:"format"`   // gift, moodle, csv or json, detected if empty
+	Content  string `json:"content"`
+}
+
+type StartAttemptForm struct {
+	QuizId        int64 `json:"quiz_id"`
+	TimeLimit     int   `json:"time_limit"`     // optional seconds of the whole attempt
+	QuestionLimit int   `json:"question_limit"` // optional seconds per question
+}
+
+type SubmitAnswerForm struct {
+	Id         int64  `json:"id"`
+	QuestionId string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+type FinishAttemptForm struct {
+	Id int64 `json:"id"`
+}
+
+type ShareQuizForm struct {
+	Id int64 `json:"id"`
+}
+
+type StartSharedAttemptForm struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"` // display name of anonymous attempts
+}
+
+type SubmitSharedAnswerForm struct {
+	Id         int64  `json:"id"`
+	Token      string `json:"token"`
+	QuestionId string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+type FinishSharedAttemptForm struct {
+	Id    int64  `json:"id"`
+	Token string `json:"token"`
+}
+
+type GradeReviewForm struct {
+	QuizId     int64  `json:"quiz_id"`
+	QuestionId string `json:"question_id"`
+	Answer     string `json:"answer"`
+	Quality    int    `json:"quality"` // optional self-rated recall quality (3 to 5) of a correct answer
+}
+
+type TranslateQuizForm struct {
+	Id       int64  `json:"id"`
+	Language string `json:"language"` // target language name or locale code
+	Model    string `json:"model"`    // optional, the quiz model by default
+}
+
+type ExplainQuestionForm struct {
+	QuizId      int64  `json:"quiz_id"` // quiz of the owner, optional if the attempt is set
+	QuestionId  string `json:"question_id"`
+	Answer      string `json:"answer"`       // selected option, taken from the attempt if empty
+	AttemptId   int64  `json:"attempt_id"`   // optional attempt of the user, the quiz is loaded through it
+	AttemptType string `json:"attempt_type"` // quiz (default), assignment or share attempt
+	Model       string `json:"model"`        // optional model of the conversation, the quiz model by default
+}
+
+type CreateClassroomForm struct {
+	Name string `json:"name"`
+}
+
+type JoinClassroomForm struct {
+	Code string `json:"code"`
+}
+
+type ClassroomMemberForm struct {
+	ClassroomId int64 `json:"classroom_id"`
+	UserId      int64 `json:"user_id"`
+}
+
+type AssignQuizForm struct {
+	ClassroomId   int64 `json:"classroom_id"`
+	QuizId        int64 `json:"quiz_id"`
+	OpenAt        int64 `json:"open_at"`        // optional unix timestamp of the open time
+	CloseAt       int64 `json:"close_at"`       // optional unix timestamp of the close time
+	MaxAttempts   int   `json:"max_attempts"`   // optional attempt limit per student, 0 for unlimited attempts
+	TimeLimit     int   `json:"time_limit"`     // optional seconds of each attempt
+	QuestionLimit int   `json:"question_limit"` // optional seconds per question
+}
+
+type StartAssignmentForm struct {
+	Id int64 `json:"id"`
+}
+
+type PreviewTemplateForm struct {
+	QuizGenerationRequest
+	Template *PromptTemplate `json:"template,omitempty"` // unsaved template to preview, admins only
+}
+
+// ListTemplateAPI returns the prompt templates visible to the user
+func ListTemplateAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	user := auth.GetUser(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    TemplateInstance.GetVisibleTemplates(db, user),
+	})
+}
+
+// PreviewTemplateAPI renders the messages of the first generation request with the template
+func PreviewTemplateAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	user := auth.GetUser(c)
+
+	var form PreviewTemplateForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	template := form.Template
+	if template != nil {
+		if user == nil || !user.IsAdmin(db) {
+			c.JSON(http.StatusOK, gin.H{
+				"status":  false,
+				"message": "admin required",
+			})
+			return
+		}
+
+		if err := template.Validate(); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"status":  false,
+				"message": err.Error(),
+			})
+			return
+		}
+	} else {
+		var err error
+		if template, err = loadQuizTemplate(db, user, form.TemplateId); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"status":  false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if form.QuizCount <= 0 {
+		form.QuizCount = 5
+	}
+	if form.Difficulty == "" {
+		form.Difficulty = "Easy"
+	}
+
+	task := quizTask{Source: strings.TrimSpace(form.Notes), Count: form.QuizCount}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    buildQuizMessages(form.QuizGenerationRequest, task, template),
+	})
+}
+
+func ListAllTemplateAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data":   TemplateInstance.GetTemplates(),
+	})
+}
+
+func UpdateTemplateAPI(c *gin.Context) {
+	var form PromptTemplateList
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	err := TemplateInstance.SetTemplates(form)
+	c.JSON(http.StatusOK, gin.H{
+		"status": err == nil,
+		"error":  utils.GetError(err),
+	})
+}
+
+// CostAPI previews the cost of generating count questions, data is null if the quiz feature is billed by tokens
+func CostAPI(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid count",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    EstimateQuizCost(count),
+	})
+}
+
+func ListAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    LoadQuizList(db, user.GetID(db)),
+	})
+}
+
+func LoadAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    quiz,
+	})
+}
+
+func RenameAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form RenameQuizForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), form.Id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	if !quiz.RenameQuiz(db, form.Name) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "failed to rename quiz",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func DeleteAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	if !quiz.DeleteQuiz(db) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "failed to delete quiz",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func ExportAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	file, err := ExportQuiz(quiz, strings.ToLower(strings.TrimSpace(c.Query("format"))))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+	defer file.Clean()
+
+	c.Writer.Header().Add("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": file.Filename,
+	}))
+	c.File(file.Path)
+}
+
+func ImportAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form ImportQuizForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	if len(form.Content) > MaxFileSize {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": fmt.Sprintf("file exceeds the size limit of %d MiB", MaxFileSize/1024/1024),
+		})
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(form.Format))
+	if len(format) == 0 {
+		format = DetectImportFormat(form.Filename, []byte(form.Content))
+	}
+
+	result, err := ImportQuiz(db, user.GetID(db), form.Name, format, []byte(form.Content))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if result.Imported == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "no new valid questions to import",
+			"data":    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+// useAttemptFeature meters the quiz attempt if the plan of the user has a quiz attempt item,
+// the returned function reverts the usage if the attempt fails to start
+func useAttemptFeature(c *gin.Context, user *auth.User) (func(), error) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	metered, ok := auth.HandleFeatureUsage(db, cache, user, globals.QuizAttemptFeature)
+	if metered && !ok {
+		return nil, errors.New("the quiz attempts of your subscription are used up this month")
+	}
+
+	return func() {
+		if metered {
+			auth.RevertFeatureUsage(db, cache, user, globals.QuizAttemptFeature)
+		}
+	}, nil
+}
+
+func StartAttemptAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form StartAttemptForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), form.QuizId)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	timer, err := NewAttemptTimer(form.TimeLimit, form.QuestionLimit, len(quiz.Data), nil)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	revert, err := useAttemptFeature(c, user)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	attempt, err := StartAttempt(db, user.GetID(db), quiz, timer)
+	if err != nil {
+		revert()
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "failed to start attempt",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data": gin.H{
+			"id":             attempt.Id,
+			"quiz_id":        quiz.Id,
+			"name":           quiz.Name,
+			"questions":      HideQuizzes(quiz.Data),
+			"deadline":       timer.Deadline,
+			"remaining":      timer.Remaining(),
+			"question_limit": timer.QuestionLimit,
+		},
+	})
+}
+
+func SubmitAnswerAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form SubmitAnswerForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadAttempt(db, user.GetID(db), form.Id)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), attempt.QuizId)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	result, err := attempt.SubmitAnswer(db, quiz, form.QuestionId, form.Answer)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func FinishAttemptAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form FinishAttemptForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadAttempt(db, user.GetID(db), form.Id)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), attempt.QuizId)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	result, err := attempt.Finish(db, quiz)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func ViewAttemptAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	result := LoadAttemptResult(db, user.GetID(db), id)
+	if result == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func AttemptHistoryAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	// quiz_id is optional, list all attempts if not provided
+	db := utils.GetDBFromContext(c)
+	quizId, _ := strconv.ParseInt(c.Query("quiz_id"), 10, 64)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    LoadAttemptHistory(db, user.GetID(db), quizId),
+	})
+}
+
+func DueReviewAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    LoadDueReviews(db, user.GetID(db), limit),
+	})
+}
+
+func GradeReviewAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form GradeReviewForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	result, err := GradeReview(db, user.GetID(db), form.QuizId, form.QuestionId, form.Answer, form.Quality)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func ShareAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form ShareQuizForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	hash, err := ShareQuiz(db, user, form.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    hash,
+	})
+}
+
+func ViewAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	hash := strings.TrimSpace(c.Query("hash"))
+	if hash == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid hash",
+		})
+		return
+	}
+
+	shared, err := GetSharedQuiz(db, hash)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    shared,
+	})
+}
+
+func ListSharingAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    ListSharedQuiz(db, user),
+	})
+}
+
+func DeleteSharingAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	hash := strings.TrimSpace(c.Query("hash"))
+	if hash == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid hash",
+		})
+		return
+	}
+
+	if err := DeleteSharedQuiz(db, user, hash); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func SharingResultsAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	hash := strings.TrimSpace(c.Query("hash"))
+	if hash == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid hash",
+		})
+		return
+	}
+
+	results, err := GetSharedQuizResults(db, user, hash)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    results,
+	})
+}
+
+// StartSharedAttemptAPI starts an attempt against the share link, login is optional
+func StartSharedAttemptAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	var form StartSharedAttemptForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	// anonymous attempts are not metered
+	user := auth.GetUser(c)
+	revert, err := useAttemptFeature(c, user)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	attempt, shared, err := StartSharedAttempt(db, user, strings.TrimSpace(form.Hash), form.Name)
+	if err != nil {
+		revert()
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data": gin.H{
+			"id":        attempt.Id,
+			"token":     attempt.Token,
+			"name":      shared.Name,
+			"questions": shared.Questions,
+		},
+	})
+}
+
+func SubmitSharedAnswerAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	var form SubmitSharedAnswerForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadSharedAttempt(db, form.Id, form.Token)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	result, err := attempt.SubmitAnswer(db, form.QuestionId, form.Answer)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func FinishSharedAttemptAPI(c *gin.Context) {
+	db := utils.GetDBFromContext(c)
+	var form FinishSharedAttemptForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadSharedAttempt(db, form.Id, form.Token)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	result, err := attempt.Finish(db)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func ExplainAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form ExplainQuestionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	instance, err := ExplainQuestion(db, user.GetID(db), form)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data": gin.H{
+			"id":   instance.GetId(),
+			"name": instance.GetName(),
+		},
+	})
+}
+
+func AbilityListAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    LoadAbilityList(db, user.GetID(db)),
+	})
+}
+
+func TranslateAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form TranslateQuizForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	language := getLanguageName(form.Language)
+	if len(language) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "language is required",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), form.Id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	model := form.Model
+	if len(model) == 0 && quiz.Model != importModel {
+		model = quiz.Model
+	}
+	if len(model) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "model is required",
+		})
+		return
+	}
+
+	id, quota, err := processQuizTranslation(c, user, quiz, language, model)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+			"quota":   quota,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"quota":   quota,
+		"data":    LoadQuiz(db, user.GetID(db), id),
+	})
+}
+
+func VariantsAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), id)
+	if quiz == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "quiz not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    LoadQuizVariants(db, quiz),
+	})
+}
+
+func CreateClassroomAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form CreateClassroomForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	classroom, err := CreateClassroom(db, user, form.Name)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    classroom,
+	})
+}
+
+func ListClassroomAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data": gin.H{
+			"teach":    CanTeach(db, user),
+			"teaching": ListTeachingClassrooms(db, user),
+			"joined":   ListJoinedClassrooms(db, user),
+		},
+	})
+}
+
+func DeleteClassroomAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	if err := DeleteClassroom(db, user, id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func ResetClassroomCodeAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	code, err := ResetClassroomCode(db, user, id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    code,
+	})
+}
+
+func JoinClassroomAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form JoinClassroomForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	classroom, err := JoinClassroom(db, user, form.Code)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    classroom,
+	})
+}
+
+func LeaveClassroomAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	if err := LeaveClassroom(db, user, id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func RosterAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	roster, err := GetRoster(db, user, id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    roster,
+	})
+}
+
+func RemoveMemberAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form ClassroomMemberForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	if err := RemoveClassroomMember(db, user, form.ClassroomId, form.UserId); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func AssignAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form AssignQuizForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	assignment, err := CreateAssignment(db, user, form)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    assignment,
+	})
+}
+
+func ListAssignmentAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	assignments, err := ListAssignments(db, user, id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    assignments,
+	})
+}
+
+func UnassignAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	if err := DeleteAssignment(db, user, id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+	})
+}
+
+func StartAssignmentAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form StartAssignmentForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	revert, err := useAttemptFeature(c, user)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	attempt, quiz, err := StartAssignmentAttempt(db, user, form.Id)
+	if err != nil {
+		revert()
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data": gin.H{
+			"id":             attempt.Id,
+			"assignment_id":  attempt.AssignmentId,
+			"name":           quiz.Name,
+			"questions":      HideQuizzes(quiz.Data),
+			"deadline":       attempt.Timer.Deadline,
+			"remaining":      attempt.Timer.Remaining(),
+			"question_limit": attempt.Timer.QuestionLimit,
+		},
+	})
+}
+
+func SubmitAssignmentAnswerAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form SubmitAnswerForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadAssignmentAttempt(db, user.GetID(db), form.Id)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	result, err := attempt.SubmitAnswer(db, form.QuestionId, form.Answer)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func FinishAssignmentAttemptAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	var form FinishAttemptForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid form",
+		})
+		return
+	}
+
+	attempt := LoadAssignmentAttempt(db, user.GetID(db), form.Id)
+	if attempt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "attempt not found",
+		})
+		return
+	}
+
+	result, err := attempt.Finish(db)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+// GradebookAPI returns the gradebook of the classroom, or downloads it as csv if format is csv
+func GradebookAPI(c *gin.Context) {
+	user := auth.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "user not found",
+		})
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": "invalid id",
+		})
+		return
+	}
+
+	gradebook, err := GetGradebook(db, user, id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if strings.ToLower(strings.TrimSpace(c.Query("format"))) != ExportCsv {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  true,
+			"message": "",
+			"data":    gradebook,
+		})
+		return
+	}
+
+	data, err := gradebook.ToCsv()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Writer.Header().Add("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": exportFilename(fmt.Sprintf("%s gradebook", gradebook.Classroom.Name), ExportCsv),
+	}))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(data))
+}
@@ -0,0 +1,313 @@
+package quiz
+
+import (
+	"chat/admin"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// QuizAttempt represents a user's attempt against a saved quiz
+type QuizAttempt struct {
+	Id         int64             `json:"id"`
+	UserId     int64             `json:"user_id"`
+	QuizId     int64             `json:"quiz_id"`
+	Answers    map[string]string `json:"answers"` // question id -> selected option
+	Score      int               `json:"score"`
+	Total      int               `json:"total"`
+	Finished   bool              `json:"finished"`
+	StartedAt  *time.Time        `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at"`
+	Timer      *AttemptTimer     `json:"timer"`
+}
+
+// QuestionResult represents the graded result of a single question
+type QuestionResult struct {
+	ID          string `json:"id"`
+	Selected    string `json:"selected"`
+	Answer      string `json:"answer"`
+	Correct     bool   `json:"correct"`
+	Explanation string `json:"explanation,omitempty"`  // explanation of the selected options
+	Description string `json:"description,omitempty"`  // explanation of the answer, only returned after grading
+	ModelAnswer string `json:"model_answer,omitempty"` // reference answer of short_answer questions
+	Elapsed     int64  `json:"elapsed"`                // elapsed milliseconds of the question
+	Timeout     bool   `json:"timeout,omitempty"`      // answered over the per-question time limit
+}
+
+// AttemptResult represents a graded attempt shown in the attempt history
+type AttemptResult struct {
+	Id       int64            `json:"id"`
+	QuizId   int64            `json:"quiz_id"`
+	QuizName string           `json:"quiz_name"`
+	Score    int              `json:"score"`
+	Total    int              `json:"total"`
+	Finished bool             `json:"finished"`
+	Duration int64            `json:"duration"` // time taken in seconds
+	Expired  bool             `json:"expired"`  // submitted after the deadline
+	Results  []QuestionResult `json:"results"`
+	Time     *time.Time       `json:"time"`
+}
+
+func findQuestion(quizzes []Quiz, id string) *Quiz {
+	for _, q := range quizzes {
+		if q.ID == id {
+			return &q
+		}
+	}
+	return nil
+}
+
+// GradeAttempt grades the selections against the quiz and returns the score and per-question results
+func GradeAttempt(quizzes []Quiz, answers map[string]string) (int, []QuestionResult) {
+	score := 0
+	results := make([]QuestionResult, 0, len(quizzes))
+	for _, q := range quizzes {
+		selected := answers[q.ID]
+		correct := q.IsCorrect(selected)
+		if correct {
+			score++
+		}
+
+		results = append(results, QuestionResult{
+			ID:          q.ID,
+			Selected:    selected,
+			Answer:      q.AnswerKey(),
+			Correct:     correct,
+			Explanation: q.Explain(selected),
+			Description: q.Description,
+			ModelAnswer: q.ModelAnswer,
+		})
+	}
+
+	return score, results
+}
+
+// StartAttempt starts an attempt with the timer, the deadline of the timer is fixed at the start
+func StartAttempt(db *sql.DB, userId int64, quiz *SavedQuiz, timer *AttemptTimer) (*QuizAttempt, error) {
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz_attempt (user_id, quiz_id, answers, total, timer, deadline) VALUES (?, ?, ?, ?, ?, ?)
+	`, userId, quiz.Id, "{}", len(quiz.Data), utils.Marshal(timer), timer.Deadline)
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during start attempt: %s", err.Error()))
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuizAttempt{
+		Id:      id,
+		UserId:  userId,
+		QuizId:  quiz.Id,
+		Answers: map[string]string{},
+		Total:   len(quiz.Data),
+		Timer:   timer,
+	}, nil
+}
+
+func LoadAttempt(db *sql.DB, userId int64, id int64) *QuizAttempt {
+	attempt := QuizAttempt{
+		Id:     id,
+		UserId: userId,
+	}
+
+	var (
+		answers           string
+		timer             sql.NullString
+		started, finished []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT quiz_id, answers, score, total, finished, created_at, finished_at, timer FROM quiz_attempt
+		WHERE user_id = ? AND id = ?
+	`, userId, id).Scan(&attempt.QuizId, &answers, &attempt.Score, &attempt.Total, &attempt.Finished, &started, &finished, &timer); err != nil {
+		return nil
+	}
+
+	attempt.Timer = loadAttemptTimer(timer)
+
+	attempt.Answers, _ = utils.UnmarshalString[map[string]string](answers)
+	if attempt.Answers == nil {
+		attempt.Answers = map[string]string{}
+	}
+
+	attempt.StartedAt = utils.ConvertTime(started)
+	attempt.FinishedAt = utils.ConvertTime(finished)
+
+	return &attempt
+}
+
+// SubmitAnswer records the selection of a question, each question can only be answered once
+func (a *QuizAttempt) SubmitAnswer(db *sql.DB, quiz *SavedQuiz, questionId string, selected string) (*QuestionResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	question := findQuestion(quiz.Data, questionId)
+	if question == nil {
+		return nil, fmt.Errorf("question not found")
+	}
+
+	if _, ok := a.Answers[questionId]; ok {
+		return nil, fmt.Errorf("question is already answered")
+	}
+
+	timeout, err := a.Timer.Track(questionId)
+	if err != nil {
+		return nil, err
+	}
+
+	selected = question.NormalizeSelection(selected)
+	if timeout {
+		// the answer over the question time limit is recorded as unanswered
+		selected = ""
+	}
+	a.Answers[questionId] = selected
+
+	if _, err := globals.ExecDb(db, `
+		UPDATE quiz_attempt SET answers = ?, timer = ? WHERE user_id = ? AND id = ?
+	`, utils.Marshal(a.Answers), utils.Marshal(a.Timer), a.UserId, a.Id); err != nil {
+		delete(a.Answers, questionId)
+		delete(a.Timer.Elapsed, questionId)
+		return nil, err
+	}
+
+	return newQuestionResult(question, selected, a.Timer, timeout), nil
+}
+
+func newQuestionResult(question *Quiz, selected string, timer *AttemptTimer, timeout bool) *QuestionResult {
+	return &QuestionResult{
+		ID:          question.ID,
+		Selected:    selected,
+		Answer:      question.AnswerKey(),
+		Correct:     question.IsCorrect(selected),
+		Explanation: question.Explain(selected),
+		Description: question.Description,
+		ModelAnswer: question.ModelAnswer,
+		Elapsed:     timer.Elapsed[question.ID],
+		Timeout:     timeout,
+	}
+}
+
+// Finish grades the attempt and stores the score
+func (a *QuizAttempt) Finish(db *sql.DB, quiz *SavedQuiz) (*AttemptResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	a.Timer.Expired = a.Timer.IsExpired(time.Now().UnixMilli())
+	score, results := GradeAttempt(quiz.Data, a.Answers)
+	res, err := globals.ExecDb(db, `
+		UPDATE quiz_attempt SET score = ?, total = ?, finished = ?, timer = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND id = ? AND finished = ?
+	`, score, len(quiz.Data), true, utils.Marshal(a.Timer), a.UserId, a.Id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sweeper and the submission of the user may finish the attempt at the same time, only one grades it
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	// bring the missed questions back in the review queue
+	EnqueueMissedQuestions(db, a.UserId, quiz.Id, results)
+	admin.AnalyseQuizAttempt(score, len(quiz.Data))
+
+	return LoadAttemptResult(db, a.UserId, a.Id), nil
+}
+
+func newAttemptResult(attempt *QuizAttempt, name string, quizzes []Quiz) AttemptResult {
+	result := AttemptResult{
+		Id:       attempt.Id,
+		QuizId:   attempt.QuizId,
+		QuizName: name,
+		Score:    attempt.Score,
+		Total:    attempt.Total,
+		Finished: attempt.Finished,
+		Time:     attempt.StartedAt,
+	}
+
+	if attempt.Finished {
+		_, result.Results = GradeAttempt(quizzes, attempt.Answers)
+		if attempt.Timer != nil {
+			attempt.Timer.Apply(result.Results)
+			result.Expired = attempt.Timer.Expired
+		}
+		if attempt.StartedAt != nil && attempt.FinishedAt != nil {
+			result.Duration = int64(attempt.FinishedAt.Sub(*attempt.StartedAt).Seconds())
+		}
+	}
+
+	return result
+}
+
+func LoadAttemptResult(db *sql.DB, userId int64, id int64) *AttemptResult {
+	attempt := LoadAttempt(db, userId, id)
+	if attempt == nil {
+		return nil
+	}
+
+	quiz := LoadQuiz(db, userId, attempt.QuizId)
+	if quiz == nil {
+		return nil
+	}
+
+	result := newAttemptResult(attempt, quiz.Name, quiz.Data)
+	return &result
+}
+
+// LoadAttemptHistory returns the attempts of the user, filtered by quiz if quizId is positive
+func LoadAttemptHistory(db *sql.DB, userId int64, quizId int64) []AttemptResult {
+	list := make([]AttemptResult, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT quiz_attempt.id, quiz_attempt.quiz_id, quiz_attempt.answers, quiz_attempt.score,
+		       quiz_attempt.total, quiz_attempt.finished, quiz_attempt.created_at, quiz_attempt.finished_at,
+		       quiz_attempt.timer, quiz.quiz_name, quiz.data
+		FROM quiz_attempt
+		INNER JOIN quiz ON quiz.id = quiz_attempt.quiz_id
+		WHERE quiz_attempt.user_id = ? AND (? <= 0 OR quiz_attempt.quiz_id = ?)
+		ORDER BY quiz_attempt.id DESC LIMIT 100
+	`, userId, quizId, quizId)
+	if err != nil {
+		return list
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			return
+		}
+	}(rows)
+
+	for rows.Next() {
+		var (
+			attempt             QuizAttempt
+			answers, name, data string
+			timer               sql.NullString
+			started, finished   []uint8
+		)
+		if err := rows.Scan(&attempt.Id, &attempt.QuizId, &answers, &attempt.Score, &attempt.Total,
+			&attempt.Finished, &started, &finished, &timer, &name, &data); err != nil {
+			continue
+		}
+
+		attempt.UserId = userId
+		attempt.Answers, _ = utils.UnmarshalString[map[string]string](answers)
+		attempt.StartedAt = utils.ConvertTime(started)
+		attempt.FinishedAt = utils.ConvertTime(finished)
+		attempt.Timer = loadAttemptTimer(timer)
+
+		quizzes, _ := utils.UnmarshalString[[]Quiz](data)
+		list = append(list, newAttemptResult(&attempt, name, quizzes))
+	}
+
+	return list
+}
+
+func DeleteQuizAttempts(db *sql.DB, userId int64, quizId int64) error {
+	_, err := globals.ExecDb(db, "DELETE FROM quiz_attempt WHERE user_id = ? AND quiz_id = ?", userId, quizId)
+	return err
+}
@@ -0,0 +1,59 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"database/sql"
+	"fmt"
+)
+
+// the quiz feature rule prices a generation by the questions the user receives instead of the
+// tokens of the model requests. the cost is estimated from the requested count before the
+// generation starts and charged from the count of the questions passing the validation.
+
+// QuizCost represents the cost of a generation priced by the quiz feature rule
+type QuizCost struct {
+	Type  string  `json:"type"`  // question-billing or quiz-billing
+	Price float32 `json:"price"` // price per question or per quiz
+	Count int     `json:"count"` // requested questions in the estimate, validated questions in the final frame
+	Quota float32 `json:"quota"`
+}
+
+func getQuizCharge() *channel.Charge {
+	return channel.ChargeInstance.GetFeatureCharge(globals.QuizFeature)
+}
+
+func newQuizCost(charge *channel.Charge, count int) *QuizCost {
+	return &QuizCost{
+		Type:  charge.GetType(),
+		Price: charge.GetOutput(),
+		Count: count,
+		Quota: charge.GetFeatureQuota(count),
+	}
+}
+
+// EstimateQuizCost returns the cost of generating count questions, nil if the quiz feature is billed by tokens
+func EstimateQuizCost(count int) *QuizCost {
+	charge := getQuizCharge()
+	if charge == nil {
+		return nil
+	}
+
+	if count <= 0 {
+		count = 5
+	}
+	return newQuizCost(charge, count)
+}
+
+// checkQuizCost checks if the user can pay the estimated cost of the generation
+func checkQuizCost(db *sql.DB, user *auth.User, cost *QuizCost) error {
+	if user == nil {
+		return fmt.Errorf("not authenticated error (feature: %s)", globals.QuizFeature)
+	}
+
+	if quota := user.GetQuota(db); quota < cost.Quota {
+		return fmt.Errorf("estimated cost exceeds user quota (feature: %s, estimated cost: %0.2f, your quota: %0.2f)", globals.QuizFeature, cost.Quota, quota)
+	}
+	return nil
+}
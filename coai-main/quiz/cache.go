@@ -0,0 +1,159 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// the quiz cache keeps a pool of the generated questions of each identical request. a request
+// is served from the pool without billing once the pool holds QuizCachePool times the question
+// count, otherwise it is generated and billed as usual and its questions are added to the pool.
+// a pool size of 1 serves the same questions to every request, larger pools serve a random subset.
+
+// IsQuizCacheAccepted checks if the generations of the model participate in the quiz cache
+func IsQuizCacheAccepted(model string) bool {
+	return utils.Contains(model, globals.QuizCacheModels)
+}
+
+// GetQuizCacheKey returns the cache key of the request by the normalized notes, the file hashes,
+// the topic, the difficulty, the question count, the question types, the model, the language, the grounding
+// and the prompt template
+func GetQuizCacheKey(form QuizGenerationRequest) string {
+	files := utils.Each(form.Files, utils.Sha2Encrypt)
+	types := form.GetQuestionTypes()
+	sort.Strings(types)
+
+	fields := []string{
+		normalizeText(form.Notes),
+		strings.Join(files, ","),
+		normalizeText(form.Topic),
+		normalizeText(form.Difficulty),
+		fmt.Sprintf("%d", form.QuizCount),
+		strings.Join(types, ","),
+		form.Model,
+	}
+	if language := getLanguageName(form.Language); language != "" {
+		fields = append(fields, fmt.Sprintf("language:%s", strings.ToLower(language)))
+	}
+	if form.Grounding {
+		// keeps the keys of the ungrounded requests unchanged
+		fields = append(fields, "grounding")
+	}
+	if template := TemplateInstance.GetTemplate(form.TemplateId); template != nil {
+		// the edits of the template are not served from the pool of the previous version
+		fields = append(fields, fmt.Sprintf("template:%s:%s", template.Id, utils.Sha2Encrypt(template.System+template.Prompt)))
+	}
+
+	return fmt.Sprintf("quiz-cache:%s", utils.Sha2Encrypt(strings.Join(fields, "\n")))
+}
+
+func getQuizPoolSize(count int) int {
+	return count * int(globals.QuizCachePool)
+}
+
+func loadQuizPool(cache *redis.Client, key string) []Quiz {
+	raw, err := cache.Get(cache.Context(), key).Result()
+	if err != nil {
+		return nil
+	}
+
+	pool, err := utils.UnmarshalString[[]Quiz](raw)
+	if err != nil {
+		return nil
+	}
+	return pool
+}
+
+func storeQuizPool(cache *redis.Client, key string, pool []Quiz) {
+	expire := time.Duration(globals.QuizCacheExpire) * time.Second
+	cache.Set(cache.Context(), key, utils.Marshal(pool), expire)
+}
+
+// pickFromPool picks a random subset of count questions from the pool, the options
+// of the choice and ordering questions are shuffled if QuizCacheShuffle is enabled
+func pickFromPool(pool []Quiz, count int) []Quiz {
+	quizzes := make([]Quiz, 0, count)
+	for _, idx := range rand.Perm(len(pool)) {
+		if len(quizzes) >= count {
+			break
+		}
+
+		quiz := pool[idx]
+		if globals.QuizCacheShuffle {
+			quiz = quiz.Variant()
+		}
+		quizzes = append(quizzes, quiz)
+	}
+
+	return quizzes
+}
+
+// Variant returns the question with the options relabeled in a random order
+func (q Quiz) Variant() Quiz {
+	switch q.GetType() {
+	case MultipleChoice, MultiSelect, Ordering:
+	default:
+		return q
+	}
+
+	keys := q.Options.Keys()
+	perm := rand.Perm(len(keys))
+	labels := map[string]string{}
+	options := QuizOption{}
+	for i, key := range keys {
+		labels[key] = keys[perm[i]]
+		options[keys[perm[i]]] = q.Options[key]
+	}
+
+	q.Options = options
+	if len(q.Explanations) > 0 {
+		explanations := QuizOption{}
+		for key, explanation := range q.Explanations {
+			explanations[labels[key]] = explanation
+		}
+		q.Explanations = explanations
+	}
+	if len(q.Answer) > 0 {
+		q.Answer = labels[q.Answer]
+	}
+	q.Answers = utils.Each(q.Answers, func(key string) string {
+		return labels[key]
+	})
+
+	return q
+}
+
+// generateQuizWithCache serves the request from the cached pool if the pool is full,
+// otherwise it generates the quiz and adds the questions to the pool
+func generateQuizWithCache(c *gin.Context, user *auth.User, form QuizGenerationRequest, send func(response QuizGenerationResponse)) ([]Quiz, float32, bool, error) {
+	if !IsQuizCacheAccepted(form.Model) {
+		quizzes, quota, err := generateQuiz(c, user, form, send)
+		return quizzes, quota, false, err
+	}
+
+	cache := utils.GetCacheFromContext(c)
+	key := GetQuizCacheKey(form)
+	size := getQuizPoolSize(form.QuizCount)
+
+	pool := loadQuizPool(cache, key)
+	if len(pool) >= size {
+		return renumberQuizzes(pickFromPool(pool, form.QuizCount)), 0, true, nil
+	}
+
+	quizzes, quota, err := generateQuiz(c, user, form, send)
+	if err != nil {
+		return nil, quota, false, err
+	}
+
+	storeQuizPool(cache, key, mergeQuizzes(pool, quizzes, size))
+	return quizzes, quota, false, nil
+}
@@ -0,0 +1,37 @@
+package quiz
+
+import "testing"
+
+func TestGetQuizCacheKey(t *testing.T) {
+	form := QuizGenerationRequest{
+		Notes:         "Photosynthesis converts light  into chemical energy.",
+		Topic:         "Biology",
+		Difficulty:    "Medium",
+		QuizCount:     5,
+		QuestionTypes: []string{TrueFalse, MultipleChoice},
+		Model:         "gpt-4o",
+	}
+	key := GetQuizCacheKey(form)
+
+	same := form
+	same.Notes = "  photosynthesis converts LIGHT into chemical energy. "
+	same.Topic = "biology"
+	same.QuestionTypes = []string{MultipleChoice, TrueFalse}
+	if GetQuizCacheKey(same) != key {
+		t.Errorf("expected the normalized requests to share the cache key")
+	}
+
+	for name, change := range map[string]func(form *QuizGenerationRequest){
+		"count":     func(form *QuizGenerationRequest) { form.QuizCount = 6 },
+		"model":     func(form *QuizGenerationRequest) { form.Model = "gpt-4o-mini" },
+		"types":     func(form *QuizGenerationRequest) { form.QuestionTypes = []string{MultipleChoice} },
+		"grounding": func(form *QuizGenerationRequest) { form.Grounding = true },
+		"files":     func(form *QuizGenerationRequest) { form.Files = []string{"ZmlsZQ=="} },
+	} {
+		changed := form
+		change(&changed)
+		if GetQuizCacheKey(changed) == key {
+			t.Errorf("expected the %s to change the cache key", name)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	chunkTokenBudget  = 3000  // min tokens of each source chunk
+	maxChunkTokens    = 12000 // max tokens of each source chunk, so that every prompt fits the model context
+	maxChunks         = 12    // max chunks generated of a source, the budget grows with the source up to maxChunkTokens
+	maxParallelChunks = 4     // max concurrent chunk requests
+)
+
+// quizTask represents a generation request of the whole source or a chunk of it
+type quizTask struct {
+	Source string
+	Images []string
+	Count  int
+	Avoid  []Quiz // questions already generated which must not be repeated
+}
+
+type chunkResult struct {
+	Index   int
+	Quizzes []Quiz
+	Quota   float32
+	Err     error
+}
+
+// buildSource joins the notes and the extracted text files into the source text,
+// images are returned separately as they cannot be chunked
+func buildSource(notes string, files []SourceFile) (string, []string) {
+	parts := make([]string, 0)
+	images := make([]string, 0)
+
+	if notes = strings.TrimSpace(notes); len(notes) > 0 {
+		parts = append(parts, notes)
+	}
+
+	for i, file := range files {
+		if file.IsImage {
+			images = append(images, file.Content)
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf("=== Source file %d ===\n%s", i+1, file.Content))
+	}
+
+	return strings.Join(parts, "\n\n"), images
+}
+
+func countTokens(text string, model string) int {
+	return utils.NumTokensFromMessages([]globals.Message{{Role: globals.User, Content: text}}, model, false)
+}
+
+// splitSource splits the source into token-budgeted chunks by paragraphs, the budget never exceeds
+// maxChunkTokens whatever the question count is. the chunks are sampled evenly down to the question
+// count (and maxChunks) so that every generated chunk gets at least one question, the count of the
+// chunks left out is returned so that the partial coverage of the source is reported to the user
+func splitSource(source string, model string, count int) ([]string, int) {
+	source = strings.TrimSpace(source)
+	if len(source) == 0 {
+		return nil, 0
+	}
+
+	total := countTokens(source, model)
+	budget := total/maxChunks + 1
+	if budget < chunkTokenBudget {
+		budget = chunkTokenBudget
+	}
+	if budget > maxChunkTokens {
+		budget = maxChunkTokens
+	}
+
+	if total <= budget {
+		return []string{source}, 0
+	}
+
+	limit := maxChunks
+	if count > 0 && count < limit {
+		limit = count
+	}
+
+	chunks := buildChunks(source, model, budget)
+	sampled := sampleChunks(chunks, limit)
+	return sampled, len(chunks) - len(sampled)
+}
+
+// sampleChunks picks limit chunks evenly spread across the source
+func sampleChunks(chunks []string, limit int) []string {
+	if limit <= 0 || len(chunks) <= limit {
+		return chunks
+	}
+
+	sampled := make([]string, 0, limit)
+	for i := 0; i < limit; i++ {
+		sampled = append(sampled, chunks[i*len(chunks)/limit])
+	}
+	return sampled
+}
+
+// buildChunks joins the paragraphs of the source into chunks of at most budget tokens
+func buildChunks(source string, model string, budget int) []string {
+	chunks := make([]string, 0)
+	var (
+		current strings.Builder
+		tokens  int
+	)
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); len(text) > 0 {
+			chunks = append(chunks, text)
+		}
+		current.Reset()
+		tokens = 0
+	}
+
+	for _, paragraph := range splitParagraphs(source, model, budget) {
+		size := countTokens(paragraph, model)
+		if tokens > 0 && tokens+size > budget {
+			flush()
+		}
+
+		current.WriteString(paragraph)
+		current.WriteString("\n\n")
+		tokens += size
+	}
+	flush()
+
+	return chunks
+}
+
+// splitParagraphs splits the text by blank lines, paragraphs over the budget are split by lines
+// and then by the longest rune prefixes within the budget so that no piece exceeds the budget
+func splitParagraphs(text string, model string, budget int) []string {
+	result := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); len(paragraph) == 0 {
+			continue
+		}
+
+		if countTokens(paragraph, model) <= budget {
+			result = append(result, paragraph)
+			continue
+		}
+
+		for _, line := range strings.Split(paragraph, "\n") {
+			runes := []rune(line)
+			for len(runes) > 0 && countTokens(string(runes), model) > budget {
+				size := fitRunes(runes, model, budget)
+				result = append(result, string(runes[:size]))
+				runes = runes[size:]
+			}
+
+			if len(runes) > 0 {
+				result = append(result, string(runes))
+			}
+		}
+	}
+
+	return result
+}
+
+// fitRunes returns the length of the longest rune prefix within the token budget by binary search,
+// at least one rune is returned so that the split always makes progress
+func fitRunes(runes []rune, model string, budget int) int {
+	low, high := 1, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if countTokens(string(runes[:mid]), model) <= budget {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low
+}
+
+// allocateQuestions distributes the question count across the chunks
+func allocateQuestions(count int, chunks int) []int {
+	quotas := make([]int, chunks)
+	for i := range quotas {
+		quotas[i] = count / chunks
+		if i < count%chunks {
+			quotas[i]++
+		}
+	}
+	return quotas
+}
+
+// generateChunks generates candidate questions of each chunk in parallel and balances them
+// into form.QuizCount questions, hook is called with the progress after each chunk is done
+func generateChunks(c *gin.Context, user *auth.User, form QuizGenerationRequest, chunks []string, images []string, hook func(progress QuizProgress, quota float32)) ([]Quiz, float32, error) {
+	quotas := allocateQuestions(form.QuizCount, len(chunks))
+	results := make(chan chunkResult, len(chunks))
+	semaphore := make(chan struct{}, maxParallelChunks)
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(index int, source string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			task := quizTask{
+				Source: source,
+				// one more candidate of each chunk for deduplication
+				Count: quotas[index] + 1,
+			}
+			if index == 0 {
+				task.Images = images
+			}
+
+			quizzes, quota, err := generateTask(c, user, form, task, nil)
+			results <- chunkResult{Index: index, Quizzes: quizzes, Quota: quota, Err: err}
+		}(i, chunk)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		quota float32
+		err   error
+	)
+	candidates := make([][]Quiz, len(chunks))
+	current := 0
+	hook(QuizProgress{Current: current, Total: len(chunks)}, quota)
+
+	for result := range results {
+		current++
+		quota += result.Quota
+		if result.Err != nil {
+			globals.Warn(fmt.Sprintf("[quiz] chunk %d generation failed: %s", result.Index+1, result.Err.Error()))
+			err = result.Err
+		} else {
+			candidates[result.Index] = result.Quizzes
+		}
+
+		hook(QuizProgress{Current: current, Total: len(chunks)}, quota)
+	}
+
+	quizzes := balanceQuizzes(candidates, quotas, form.QuizCount)
+	if len(quizzes) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no valid questions generated")
+		}
+		return nil, quota, err
+	}
+
+	// top up the missing questions of failed or duplicated chunks from the least covered chunk
+	if missing := form.QuizCount - len(quizzes); missing > 0 {
+		task := quizTask{
+			Source: chunks[leastCoveredChunk(candidates, quotas)],
+			Count:  missing,
+			Avoid:  quizzes,
+		}
+
+		extra, q, err := generateTask(c, user, form, task, nil)
+		quota += q
+		if err == nil {
+			quizzes = mergeQuizzes(quizzes, extra, form.QuizCount)
+		}
+	}
+
+	return quizzes, quota, nil
+}
+
+// balanceQuizzes picks the allocated questions of each chunk first, then fills the rest
+// with the spare candidates in round-robin order to keep questions spread across the source
+func balanceQuizzes(candidates [][]Quiz, quotas []int, count int) []Quiz {
+	quizzes := make([]Quiz, 0, count)
+	spares := make([][]Quiz, len(candidates))
+
+	for i, list := range candidates {
+		picked := 0
+		for j, quiz := range list {
+			if picked >= quotas[i] {
+				spares[i] = list[j:]
+				break
+			}
+
+			if !hasDuplicateQuiz(quizzes, quiz) {
+				quizzes = append(quizzes, quiz)
+				picked++
+			}
+		}
+	}
+
+	for len(quizzes) < count {
+		added := false
+		for i := range spares {
+			if len(spares[i]) == 0 || len(quizzes) >= count {
+				continue
+			}
+
+			quiz := spares[i][0]
+			spares[i] = spares[i][1:]
+			if !hasDuplicateQuiz(quizzes, quiz) {
+				quizzes = append(quizzes, quiz)
+			}
+			added = true
+		}
+
+		if !added {
+			break
+		}
+	}
+
+	return quizzes
+}
+
+func leastCoveredChunk(candidates [][]Quiz, quotas []int) int {
+	index, lack := 0, -1
+	for i, list := range candidates {
+		if diff := quotas[i] - len(list); diff > lack {
+			index, lack = i, diff
+		}
+	}
+	return index
+}
@@ -0,0 +1,80 @@
+package quiz
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSampleChunks(t *testing.T) {
+	chunks := []string{"c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9"}
+
+	cases := []struct {
+		limit    int
+		expected []string
+	}{
+		{1, []string{"c0"}},
+		{2, []string{"c0", "c5"}},
+		{4, []string{"c0", "c2", "c5", "c7"}},
+		{10, chunks},
+		{20, chunks},
+	}
+
+	for _, c := range cases {
+		if sampled := sampleChunks(chunks, c.limit); !reflect.DeepEqual(sampled, c.expected) {
+			t.Errorf("sampleChunks(%d) = %v, expected %v", c.limit, sampled, c.expected)
+		}
+	}
+}
+
+func TestAllocateQuestions(t *testing.T) {
+	cases := []struct {
+		count    int
+		chunks   int
+		expected []int
+	}{
+		{10, 3, []int{4, 3, 3}},
+		{3, 3, []int{1, 1, 1}},
+		{12, 4, []int{3, 3, 3, 3}},
+	}
+
+	for _, c := range cases {
+		if quotas := allocateQuestions(c.count, c.chunks); !reflect.DeepEqual(quotas, c.expected) {
+			t.Errorf("allocateQuestions(%d, %d) = %v, expected %v", c.count, c.chunks, quotas, c.expected)
+		}
+	}
+}
+
+func newTestQuiz(question string) Quiz {
+	return Quiz{
+		ID:       question,
+		Type:     MultipleChoice,
+		Question: question,
+		Options:  QuizOption{"a": "1", "b": "2", "c": "3", "d": "4"},
+		Answer:   "a",
+	}
+}
+
+func TestBalanceQuizzes(t *testing.T) {
+	candidates := make([][]Quiz, 3)
+	for i := range candidates {
+		for j := 0; j < 3; j++ {
+			candidates[i] = append(candidates[i], newTestQuiz(fmt.Sprintf("what is item %d of part %d?", j, i)))
+		}
+	}
+	// the second chunk failed, its questions are filled with the spares of the others
+	candidates[1] = nil
+
+	quizzes := balanceQuizzes(candidates, []int{2, 2, 2}, 6)
+	if len(quizzes) != 6 {
+		t.Fatalf("expected 6 questions, got %d", len(quizzes))
+	}
+
+	parts := map[string]int{}
+	for _, quiz := range quizzes {
+		parts[quiz.Question[len(quiz.Question)-2:]]++
+	}
+	if parts["0?"] != 3 || parts["2?"] != 3 {
+		t.Errorf("unexpected spread of the questions: %v", parts)
+	}
+}
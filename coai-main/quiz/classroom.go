@@ -0,0 +1,969 @@
+package quiz
+
+import (
+	"bytes"
+	"chat/admin"
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a teacher (the users of the classroom permission group) creates classrooms and the students join
+// them with the join code. the saved quizzes of the teacher are assigned to the classroom with an
+// optional open window and attempt limit, the attempts of the students are graded like the shared
+// attempts and collected in the gradebook of the classroom.
+
+const (
+	classroomCodeLength   = 8
+	classroomCodeRetries  = 5
+	classroomNameLength   = 50
+	classroomListLimit    = 100
+	assignmentMaxAttempts = 100
+)
+
+// Classroom represents a classroom in the teaching or the joined list of the user
+type Classroom struct {
+	Id      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Code    string    `json:"code,omitempty"` // only shown to the teacher
+	Teacher string    `json:"teacher"`
+	Members int       `json:"members"`
+	Time    time.Time `json:"time"`
+
+	userId int64
+}
+
+// ClassroomMember represents a student in the roster of the classroom
+type ClassroomMember struct {
+	UserId   int64      `json:"user_id"`
+	Username string     `json:"username"`
+	Time     *time.Time `json:"time"`
+}
+
+// Assignment represents a saved quiz assigned to the classroom
+type Assignment struct {
+	Id            int64      `json:"id"`
+	ClassroomId   int64      `json:"classroom_id"`
+	QuizId        int64      `json:"quiz_id"`
+	QuizName      string     `json:"quiz_name"`
+	OpenAt        *time.Time `json:"open_at"`
+	CloseAt       *time.Time `json:"close_at"`
+	MaxAttempts   int        `json:"max_attempts"`   // 0 for unlimited attempts
+	TimeLimit     int        `json:"time_limit"`     // seconds of each attempt, 0 for no limit
+	QuestionLimit int        `json:"question_limit"` // seconds per question, 0 for no limit
+	Attempts      int        `json:"attempts"`       // attempts of the student, or all attempts for the teacher
+	Time          *time.Time `json:"time"`
+}
+
+// AssignmentAttempt represents an attempt of a student against an assignment
+type AssignmentAttempt struct {
+	Id           int64             `json:"id"`
+	AssignmentId int64             `json:"assignment_id"`
+	UserId       int64             `json:"user_id"`
+	Answers      map[string]string `json:"answers"`
+	Score        int               `json:"score"`
+	Total        int               `json:"total"`
+	Finished     bool              `json:"finished"`
+	StartedAt    *time.Time        `json:"started_at"`
+	FinishedAt   *time.Time        `json:"finished_at"`
+	Timer        *AttemptTimer     `json:"timer"`
+}
+
+// GradebookRecord represents an attempt in the gradebook, students without attempts have a record with attempt id 0
+type GradebookRecord struct {
+	UserId       int64            `json:"user_id"`
+	Username     string           `json:"username"`
+	AssignmentId int64            `json:"assignment_id"`
+	QuizName     string           `json:"quiz_name"`
+	AttemptId    int64            `json:"attempt_id"`
+	Score        int              `json:"score"`
+	Total        int              `json:"total"`
+	Finished     bool             `json:"finished"`
+	Duration     int64            `json:"duration"`
+	Expired      bool             `json:"expired"` // submitted after the deadline
+	Elapsed      map[string]int64 `json:"elapsed"` // question id -> elapsed milliseconds
+	StartedAt    *time.Time       `json:"started_at"`
+	FinishedAt   *time.Time       `json:"finished_at"`
+
+	timer *AttemptTimer
+}
+
+// Gradebook represents the attempts of every student of the classroom
+type Gradebook struct {
+	Classroom   Classroom         `json:"classroom"`
+	Assignments []Assignment      `json:"assignments"`
+	Records     []GradebookRecord `json:"records"`
+}
+
+// CanTeach checks if the user is in the classroom permission group
+func CanTeach(db *sql.DB, user *auth.User) bool {
+	return auth.HitGroups(db, user, globals.ClassroomPermissionGroup)
+}
+
+func generateClassroomCode() string {
+	return strings.ToUpper(utils.GenerateChar(classroomCodeLength))
+}
+
+func normalizeClassroomName(name string) string {
+	return utils.Extract(strings.TrimSpace(name), classroomNameLength, "...")
+}
+
+// CreateClassroom creates a classroom of the teacher with a new join code
+func CreateClassroom(db *sql.DB, user *auth.User, name string) (*Classroom, error) {
+	if !CanTeach(db, user) {
+		return nil, fmt.Errorf("permission denied: classroom feature not available")
+	}
+
+	if name = normalizeClassroomName(name); len(name) == 0 {
+		return nil, fmt.Errorf("classroom name is empty")
+	}
+
+	userId := user.GetID(db)
+	for i := 0; i < classroomCodeRetries; i++ {
+		code := generateClassroomCode()
+		res, err := globals.ExecDb(db, `
+			INSERT INTO classroom (user_id, name, code) VALUES (?, ?, ?)
+		`, userId, name, code)
+		if err != nil {
+			// unique constraint of the code
+			globals.Debug(fmt.Sprintf("[quiz] failed to create classroom with code %s: %s", code, err.Error()))
+			continue
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		return LoadClassroom(db, id), nil
+	}
+
+	return nil, fmt.Errorf("failed to generate join code")
+}
+
+// LoadClassroom loads the classroom with the join code and the member count
+func LoadClassroom(db *sql.DB, id int64) *Classroom {
+	var (
+		classroom Classroom
+		created   []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT classroom.id, classroom.user_id, classroom.name, classroom.code, auth.username, classroom.created_at,
+		       (SELECT COUNT(*) FROM classroom_member WHERE classroom_member.classroom_id = classroom.id)
+		FROM classroom
+		INNER JOIN auth ON auth.id = classroom.user_id
+		WHERE classroom.id = ?
+	`, id).Scan(&classroom.Id, &classroom.userId, &classroom.Name, &classroom.Code, &classroom.Teacher, &created, &classroom.Members); err != nil {
+		return nil
+	}
+
+	if t := utils.ConvertTime(created); t != nil {
+		classroom.Time = *t
+	}
+	return &classroom
+}
+
+// LoadTeachingClassroom loads the classroom if the user is its teacher
+func LoadTeachingClassroom(db *sql.DB, user *auth.User, id int64) (*Classroom, error) {
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	classroom := LoadClassroom(db, id)
+	if classroom == nil || classroom.userId != user.GetID(db) {
+		return nil, fmt.Errorf("classroom not found")
+	}
+	return classroom, nil
+}
+
+// IsMember checks if the user is the teacher or a student of the classroom
+func (c *Classroom) IsMember(db *sql.DB, userId int64) bool {
+	if c.userId == userId {
+		return true
+	}
+
+	var count int
+	if err := globals.QueryRowDb(db, `
+		SELECT COUNT(*) FROM classroom_member WHERE classroom_id = ? AND user_id = ?
+	`, c.Id, userId).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func scanClassrooms(rows *sql.Rows, code bool) []Classroom {
+	result := make([]Classroom, 0)
+	for rows.Next() {
+		var (
+			classroom Classroom
+			created   []uint8
+		)
+		if err := rows.Scan(&classroom.Id, &classroom.userId, &classroom.Name, &classroom.Code, &classroom.Teacher, &created, &classroom.Members); err != nil {
+			continue
+		}
+
+		if !code {
+			classroom.Code = ""
+		}
+		if t := utils.ConvertTime(created); t != nil {
+			classroom.Time = *t
+		}
+		result = append(result, classroom)
+	}
+	return result
+}
+
+// ListTeachingClassrooms returns the classrooms of the teacher
+func ListTeachingClassrooms(db *sql.DB, user *auth.User) []Classroom {
+	if user == nil {
+		return []Classroom{}
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT classroom.id, classroom.user_id, classroom.name, classroom.code, auth.username, classroom.created_at,
+		       (SELECT COUNT(*) FROM classroom_member WHERE classroom_member.classroom_id = classroom.id)
+		FROM classroom
+		INNER JOIN auth ON auth.id = classroom.user_id
+		WHERE classroom.user_id = ?
+		ORDER BY classroom.id DESC
+		LIMIT ?
+	`, user.GetID(db), classroomListLimit)
+	if err != nil {
+		return []Classroom{}
+	}
+	defer rows.Close()
+
+	return scanClassrooms(rows, true)
+}
+
+// ListJoinedClassrooms returns the classrooms the student has joined, the join codes are hidden
+func ListJoinedClassrooms(db *sql.DB, user *auth.User) []Classroom {
+	if user == nil {
+		return []Classroom{}
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT classroom.id, classroom.user_id, classroom.name, classroom.code, auth.username, classroom.created_at,
+		       (SELECT COUNT(*) FROM classroom_member WHERE classroom_member.classroom_id = classroom.id)
+		FROM classroom_member
+		INNER JOIN classroom ON classroom.id = classroom_member.classroom_id
+		INNER JOIN auth ON auth.id = classroom.user_id
+		WHERE classroom_member.user_id = ?
+		ORDER BY classroom_member.id DESC
+		LIMIT ?
+	`, user.GetID(db), classroomListLimit)
+	if err != nil {
+		return []Classroom{}
+	}
+	defer rows.Close()
+
+	return scanClassrooms(rows, false)
+}
+
+// ResetClassroomCode replaces the join code of the classroom, the old code can no longer be used to join
+func ResetClassroomCode(db *sql.DB, user *auth.User, id int64) (string, error) {
+	classroom, err := LoadTeachingClassroom(db, user, id)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < classroomCodeRetries; i++ {
+		code := generateClassroomCode()
+		if _, err := globals.ExecDb(db, `
+			UPDATE classroom SET code = ? WHERE id = ?
+		`, code, classroom.Id); err != nil {
+			continue
+		}
+		return code, nil
+	}
+
+	return "", fmt.Errorf("failed to generate join code")
+}
+
+// DeleteClassroom deletes the classroom with its roster, assignments and attempts
+func DeleteClassroom(db *sql.DB, user *auth.User, id int64) error {
+	classroom, err := LoadTeachingClassroom(db, user, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := globals.ExecDb(db, `
+		DELETE FROM quiz_assignment_attempt WHERE assignment_id IN (
+			SELECT id FROM quiz_assignment WHERE classroom_id = ?
+		)
+	`, classroom.Id); err != nil {
+		return err
+	}
+
+	if _, err := globals.ExecDb(db, "DELETE FROM quiz_assignment WHERE classroom_id = ?", classroom.Id); err != nil {
+		return err
+	}
+
+	if _, err := globals.ExecDb(db, "DELETE FROM classroom_member WHERE classroom_id = ?", classroom.Id); err != nil {
+		return err
+	}
+
+	_, err = globals.ExecDb(db, "DELETE FROM classroom WHERE id = ?", classroom.Id)
+	return err
+}
+
+// JoinClassroom adds the user to the roster of the classroom of the join code
+func JoinClassroom(db *sql.DB, user *auth.User, code string) (*Classroom, error) {
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var id int64
+	if err := globals.QueryRowDb(db, `
+		SELECT id FROM classroom WHERE code = ?
+	`, strings.ToUpper(strings.TrimSpace(code))).Scan(&id); err != nil {
+		return nil, fmt.Errorf("invalid join code")
+	}
+
+	classroom := LoadClassroom(db, id)
+	if classroom == nil {
+		return nil, fmt.Errorf("classroom not found")
+	}
+
+	userId := user.GetID(db)
+	if classroom.IsMember(db, userId) {
+		return nil, fmt.Errorf("already a member of the classroom")
+	}
+
+	if _, err := globals.ExecDb(db, `
+		INSERT INTO classroom_member (classroom_id, user_id) VALUES (?, ?)
+	`, classroom.Id, userId); err != nil {
+		return nil, err
+	}
+
+	classroom.Code = ""
+	classroom.Members++
+	return classroom, nil
+}
+
+// LeaveClassroom removes the user from the roster, the attempts are kept in the gradebook
+func LeaveClassroom(db *sql.DB, user *auth.User, id int64) error {
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	return removeClassroomMember(db, id, user.GetID(db))
+}
+
+// RemoveClassroomMember removes the student from the roster of the teacher's classroom
+func RemoveClassroomMember(db *sql.DB, user *auth.User, id int64, memberId int64) error {
+	classroom, err := LoadTeachingClassroom(db, user, id)
+	if err != nil {
+		return err
+	}
+
+	return removeClassroomMember(db, classroom.Id, memberId)
+}
+
+func removeClassroomMember(db *sql.DB, id int64, userId int64) error {
+	res, err := globals.ExecDb(db, `
+		DELETE FROM classroom_member WHERE classroom_id = ? AND user_id = ?
+	`, id, userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("member not found")
+	}
+	return nil
+}
+
+// GetRoster returns the students of the teacher's classroom
+func GetRoster(db *sql.DB, user *auth.User, id int64) ([]ClassroomMember, error) {
+	classroom, err := LoadTeachingClassroom(db, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadRoster(db, classroom.Id), nil
+}
+
+func loadRoster(db *sql.DB, id int64) []ClassroomMember {
+	result := make([]ClassroomMember, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT classroom_member.user_id, auth.username, classroom_member.joined_at
+		FROM classroom_member
+		INNER JOIN auth ON auth.id = classroom_member.user_id
+		WHERE classroom_member.classroom_id = ?
+		ORDER BY auth.username ASC
+	`, id)
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			member ClassroomMember
+			joined []uint8
+		)
+		if err := rows.Scan(&member.UserId, &member.Username, &joined); err != nil {
+			continue
+		}
+
+		member.Time = utils.ConvertTime(joined)
+		result = append(result, member)
+	}
+
+	return result
+}
+
+// convertWindowTime converts the unix timestamp of the assignment window to the utc sql time, 0 is no limit
+func convertWindowTime(stamp int64) interface{} {
+	if stamp <= 0 {
+		return nil
+	}
+	return utils.ConvertSqlTime(time.Unix(stamp, 0).UTC())
+}
+
+// CreateAssignment assigns the teacher's saved quiz to the classroom
+func CreateAssignment(db *sql.DB, user *auth.User, form AssignQuizForm) (*Assignment, error) {
+	classroom, err := LoadTeachingClassroom(db, user, form.ClassroomId)
+	if err != nil {
+		return nil, err
+	}
+
+	if LoadQuiz(db, classroom.userId, form.QuizId) == nil {
+		return nil, fmt.Errorf("quiz not found")
+	}
+
+	if form.OpenAt > 0 && form.CloseAt > 0 && form.CloseAt <= form.OpenAt {
+		return nil, fmt.Errorf("close time must be later than open time")
+	}
+
+	if form.MaxAttempts < 0 || form.MaxAttempts > assignmentMaxAttempts {
+		return nil, fmt.Errorf("attempt limit must be between 0 and %d", assignmentMaxAttempts)
+	}
+
+	// validate the time limits
+	if _, err := NewAttemptTimer(form.TimeLimit, form.QuestionLimit, 0, nil); err != nil {
+		return nil, err
+	}
+
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz_assignment (classroom_id, quiz_id, open_at, close_at, max_attempts, time_limit, question_limit)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, classroom.Id, form.QuizId, convertWindowTime(form.OpenAt), convertWindowTime(form.CloseAt), form.MaxAttempts,
+		form.TimeLimit, form.QuestionLimit)
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during create assignment: %s", err.Error()))
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadAssignment(db, id), nil
+}
+
+// LoadAssignment loads the assignment with the name of the assigned quiz
+func LoadAssignment(db *sql.DB, id int64) *Assignment {
+	var (
+		assignment                Assignment
+		openAt, closeAt, assigned []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT quiz_assignment.id, quiz_assignment.classroom_id, quiz_assignment.quiz_id, quiz.quiz_name,
+		       quiz_assignment.open_at, quiz_assignment.close_at, quiz_assignment.max_attempts,
+		       quiz_assignment.time_limit, quiz_assignment.question_limit, quiz_assignment.created_at
+		FROM quiz_assignment
+		INNER JOIN quiz ON quiz.id = quiz_assignment.quiz_id
+		WHERE quiz_assignment.id = ?
+	`, id).Scan(&assignment.Id, &assignment.ClassroomId, &assignment.QuizId, &assignment.QuizName,
+		&openAt, &closeAt, &assignment.MaxAttempts, &assignment.TimeLimit, &assignment.QuestionLimit, &assigned); err != nil {
+		return nil
+	}
+
+	assignment.OpenAt = utils.ConvertTime(openAt)
+	assignment.CloseAt = utils.ConvertTime(closeAt)
+	assignment.Time = utils.ConvertTime(assigned)
+	return &assignment
+}
+
+// IsOpen checks if the assignment accepts attempts and answers at the moment
+func (a *Assignment) IsOpen() error {
+	now := time.Now().UTC()
+	if a.OpenAt != nil && now.Before(*a.OpenAt) {
+		return fmt.Errorf("assignment is not open yet")
+	}
+	if a.CloseAt != nil && !now.Before(*a.CloseAt) {
+		return fmt.Errorf("assignment is closed")
+	}
+	return nil
+}
+
+// ListAssignments returns the assignments of the classroom, the attempt count is the count of
+// the user's attempts for the students and of all the attempts for the teacher
+func ListAssignments(db *sql.DB, user *auth.User, id int64) ([]Assignment, error) {
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	classroom := LoadClassroom(db, id)
+	userId := user.GetID(db)
+	if classroom == nil || !classroom.IsMember(db, userId) {
+		return nil, fmt.Errorf("classroom not found")
+	}
+
+	var filter interface{}
+	if classroom.userId != userId {
+		filter = userId
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT quiz_assignment.id, quiz_assignment.classroom_id, quiz_assignment.quiz_id, quiz.quiz_name,
+		       quiz_assignment.open_at, quiz_assignment.close_at, quiz_assignment.max_attempts,
+		       quiz_assignment.time_limit, quiz_assignment.question_limit, quiz_assignment.created_at,
+		       (SELECT COUNT(*) FROM quiz_assignment_attempt
+		        WHERE quiz_assignment_attempt.assignment_id = quiz_assignment.id
+		          AND (? IS NULL OR quiz_assignment_attempt.user_id = ?))
+		FROM quiz_assignment
+		INNER JOIN quiz ON quiz.id = quiz_assignment.quiz_id
+		WHERE quiz_assignment.classroom_id = ?
+		ORDER BY quiz_assignment.id DESC
+	`, filter, filter, classroom.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Assignment, 0)
+	for rows.Next() {
+		var (
+			assignment                Assignment
+			openAt, closeAt, assigned []uint8
+		)
+		if err := rows.Scan(&assignment.Id, &assignment.ClassroomId, &assignment.QuizId, &assignment.QuizName,
+			&openAt, &closeAt, &assignment.MaxAttempts, &assignment.TimeLimit, &assignment.QuestionLimit,
+			&assigned, &assignment.Attempts); err != nil {
+			continue
+		}
+
+		assignment.OpenAt = utils.ConvertTime(openAt)
+		assignment.CloseAt = utils.ConvertTime(closeAt)
+		assignment.Time = utils.ConvertTime(assigned)
+		result = append(result, assignment)
+	}
+
+	return result, nil
+}
+
+// DeleteAssignment removes the assignment and its attempts from the teacher's classroom
+func DeleteAssignment(db *sql.DB, user *auth.User, id int64) error {
+	assignment := LoadAssignment(db, id)
+	if assignment == nil {
+		return fmt.Errorf("assignment not found")
+	}
+
+	if _, err := LoadTeachingClassroom(db, user, assignment.ClassroomId); err != nil {
+		return fmt.Errorf("assignment not found")
+	}
+
+	if _, err := globals.ExecDb(db, "DELETE FROM quiz_assignment_attempt WHERE assignment_id = ?", assignment.Id); err != nil {
+		return err
+	}
+
+	_, err := globals.ExecDb(db, "DELETE FROM quiz_assignment WHERE id = ?", assignment.Id)
+	return err
+}
+
+// DeleteQuizAssignments removes the assignments of the quiz and their attempts
+func DeleteQuizAssignments(db *sql.DB, userId int64, quizId int64) error {
+	if _, err := globals.ExecDb(db, `
+		DELETE FROM quiz_assignment_attempt WHERE assignment_id IN (
+			SELECT quiz_assignment.id FROM quiz_assignment
+			INNER JOIN classroom ON classroom.id = quiz_assignment.classroom_id
+			WHERE classroom.user_id = ? AND quiz_assignment.quiz_id = ?
+		)
+	`, userId, quizId); err != nil {
+		return err
+	}
+
+	_, err := globals.ExecDb(db, `
+		DELETE FROM quiz_assignment WHERE quiz_id = ? AND classroom_id IN (
+			SELECT id FROM classroom WHERE user_id = ?
+		)
+	`, quizId, userId)
+	return err
+}
+
+// loadAssignedQuiz loads the assignment of the classroom member and the assigned quiz of the teacher
+func loadAssignedQuiz(db *sql.DB, userId int64, id int64) (*Assignment, *SavedQuiz, error) {
+	assignment := LoadAssignment(db, id)
+	if assignment == nil {
+		return nil, nil, fmt.Errorf("assignment not found")
+	}
+
+	classroom := LoadClassroom(db, assignment.ClassroomId)
+	if classroom == nil || !classroom.IsMember(db, userId) {
+		return nil, nil, fmt.Errorf("assignment not found")
+	}
+
+	quiz := LoadQuiz(db, classroom.userId, assignment.QuizId)
+	if quiz == nil {
+		return nil, nil, fmt.Errorf("quiz not found")
+	}
+
+	return assignment, quiz, nil
+}
+
+// StartAssignmentAttempt starts an attempt of the student if the assignment is open and the attempt limit is not reached
+func StartAssignmentAttempt(db *sql.DB, user *auth.User, id int64) (*AssignmentAttempt, *SavedQuiz, error) {
+	if user == nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
+	userId := user.GetID(db)
+	assignment, quiz, err := loadAssignedQuiz(db, userId, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := assignment.IsOpen(); err != nil {
+		return nil, nil, err
+	}
+
+	timer, err := NewAttemptTimer(assignment.TimeLimit, assignment.QuestionLimit, len(quiz.Data), assignment.CloseAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the attempt limit is checked by the insert itself, so that concurrent starts cannot exceed it
+	limit := assignment.MaxAttempts
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz_assignment_attempt (assignment_id, user_id, answers, total, timer, deadline)
+		SELECT ?, ?, ?, ?, ?, ? FROM (
+			SELECT COUNT(*) AS attempts FROM quiz_assignment_attempt WHERE assignment_id = ? AND user_id = ?
+		) AS counter WHERE counter.attempts < ?
+	`, assignment.Id, userId, "{}", len(quiz.Data), utils.Marshal(timer), timer.Deadline, assignment.Id, userId, limit)
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during start assignment attempt: %s", err.Error()))
+		return nil, nil, err
+	}
+
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return nil, nil, fmt.Errorf("attempt limit reached (%d)", assignment.MaxAttempts)
+	}
+
+	attemptId, err := res.LastInsertId()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &AssignmentAttempt{
+		Id:           attemptId,
+		AssignmentId: assignment.Id,
+		UserId:       userId,
+		Answers:      map[string]string{},
+		Total:        len(quiz.Data),
+		Timer:        timer,
+	}, quiz, nil
+}
+
+func LoadAssignmentAttempt(db *sql.DB, userId int64, id int64) *AssignmentAttempt {
+	attempt := AssignmentAttempt{
+		Id:     id,
+		UserId: userId,
+	}
+
+	var (
+		answers           string
+		timer             sql.NullString
+		started, finished []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT assignment_id, answers, score, total, finished, created_at, finished_at, timer FROM quiz_assignment_attempt
+		WHERE user_id = ? AND id = ?
+	`, userId, id).Scan(&attempt.AssignmentId, &answers, &attempt.Score, &attempt.Total, &attempt.Finished, &started, &finished, &timer); err != nil {
+		return nil
+	}
+
+	attempt.Timer = loadAttemptTimer(timer)
+
+	attempt.Answers, _ = utils.UnmarshalString[map[string]string](answers)
+	if attempt.Answers == nil {
+		attempt.Answers = map[string]string{}
+	}
+
+	attempt.StartedAt = utils.ConvertTime(started)
+	attempt.FinishedAt = utils.ConvertTime(finished)
+
+	return &attempt
+}
+
+// SubmitAnswer records the selection of a question, the answers are rejected after the assignment is closed
+func (a *AssignmentAttempt) SubmitAnswer(db *sql.DB, questionId string, selected string) (*QuestionResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	assignment, quiz, err := loadAssignedQuiz(db, a.UserId, a.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := assignment.IsOpen(); err != nil {
+		return nil, err
+	}
+
+	question := findQuestion(quiz.Data, questionId)
+	if question == nil {
+		return nil, fmt.Errorf("question not found")
+	}
+
+	if _, ok := a.Answers[questionId]; ok {
+		return nil, fmt.Errorf("question is already answered")
+	}
+
+	timeout, err := a.Timer.Track(questionId)
+	if err != nil {
+		return nil, err
+	}
+
+	selected = question.NormalizeSelection(selected)
+	if timeout {
+		selected = ""
+	}
+	a.Answers[questionId] = selected
+
+	if _, err := globals.ExecDb(db, `
+		UPDATE quiz_assignment_attempt SET answers = ?, timer = ? WHERE user_id = ? AND id = ?
+	`, utils.Marshal(a.Answers), utils.Marshal(a.Timer), a.UserId, a.Id); err != nil {
+		delete(a.Answers, questionId)
+		delete(a.Timer.Elapsed, questionId)
+		return nil, err
+	}
+
+	return newQuestionResult(question, selected, a.Timer, timeout), nil
+}
+
+// Finish grades the attempt with the recorded answers, an attempt can be finished after the assignment is closed
+func (a *AssignmentAttempt) Finish(db *sql.DB) (*AttemptResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	assignment, quiz, err := loadAssignedQuiz(db, a.UserId, a.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Timer.Expired = a.Timer.IsExpired(time.Now().UnixMilli())
+	score, results := GradeAttempt(quiz.Data, a.Answers)
+	a.Timer.Apply(results)
+	res, err := globals.ExecDb(db, `
+		UPDATE quiz_assignment_attempt SET score = ?, total = ?, finished = ?, timer = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND id = ? AND finished = ?
+	`, score, len(quiz.Data), true, utils.Marshal(a.Timer), a.UserId, a.Id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sweeper and the submission of the student may finish the attempt at the same time, only one grades it
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+	admin.AnalyseQuizAttempt(score, len(quiz.Data))
+
+	attempt := LoadAssignmentAttempt(db, a.UserId, a.Id)
+	if attempt == nil {
+		return nil, fmt.Errorf("attempt not found")
+	}
+
+	result := AttemptResult{
+		Id:       attempt.Id,
+		QuizId:   assignment.QuizId,
+		QuizName: quiz.Name,
+		Score:    score,
+		Total:    len(quiz.Data),
+		Finished: true,
+		Expired:  a.Timer.Expired,
+		Results:  results,
+		Time:     attempt.StartedAt,
+	}
+	if attempt.StartedAt != nil && attempt.FinishedAt != nil {
+		result.Duration = int64(attempt.FinishedAt.Sub(*attempt.StartedAt).Seconds())
+	}
+
+	return &result, nil
+}
+
+// GetGradebook collects the attempts of every student of the teacher's classroom by assignment,
+// the students who left the classroom are kept if they have attempts
+func GetGradebook(db *sql.DB, user *auth.User, id int64) (*Gradebook, error) {
+	classroom, err := LoadTeachingClassroom(db, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := ListAssignments(db, user, classroom.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT quiz_assignment_attempt.id, quiz_assignment_attempt.assignment_id, quiz_assignment_attempt.user_id, auth.username,
+		       quiz_assignment_attempt.score, quiz_assignment_attempt.total, quiz_assignment_attempt.finished,
+		       quiz_assignment_attempt.created_at, quiz_assignment_attempt.finished_at, quiz_assignment_attempt.timer
+		FROM quiz_assignment_attempt
+		INNER JOIN quiz_assignment ON quiz_assignment.id = quiz_assignment_attempt.assignment_id
+		INNER JOIN auth ON auth.id = quiz_assignment_attempt.user_id
+		WHERE quiz_assignment.classroom_id = ?
+		ORDER BY quiz_assignment_attempt.id ASC
+	`, classroom.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// attempts by assignment id and user id
+	attempts := map[int64]map[int64][]GradebookRecord{}
+	students := map[int64]string{}
+	for rows.Next() {
+		var (
+			record            GradebookRecord
+			timer             sql.NullString
+			started, finished []uint8
+		)
+		if err := rows.Scan(&record.AttemptId, &record.AssignmentId, &record.UserId, &record.Username,
+			&record.Score, &record.Total, &record.Finished, &started, &finished, &timer); err != nil {
+			continue
+		}
+
+		record.timer = loadAttemptTimer(timer)
+		record.Elapsed, record.Expired = record.timer.Elapsed, record.timer.Expired
+
+		record.StartedAt = utils.ConvertTime(started)
+		record.FinishedAt = utils.ConvertTime(finished)
+		if record.Finished && record.StartedAt != nil && record.FinishedAt != nil {
+			record.Duration = int64(record.FinishedAt.Sub(*record.StartedAt).Seconds())
+		}
+
+		if attempts[record.AssignmentId] == nil {
+			attempts[record.AssignmentId] = map[int64][]GradebookRecord{}
+		}
+		attempts[record.AssignmentId][record.UserId] = append(attempts[record.AssignmentId][record.UserId], record)
+		students[record.UserId] = record.Username
+	}
+
+	roster := loadRoster(db, classroom.Id)
+	for _, member := range roster {
+		delete(students, member.UserId)
+	}
+
+	left := make([]ClassroomMember, 0, len(students))
+	for userId, username := range students {
+		left = append(left, ClassroomMember{UserId: userId, Username: username})
+	}
+	sort.Slice(left, func(i, j int) bool {
+		return left[i].Username < left[j].Username
+	})
+	roster = append(roster, left...)
+
+	gradebook := Gradebook{
+		Classroom:   *classroom,
+		Assignments: assignments,
+		Records:     make([]GradebookRecord, 0),
+	}
+	for _, member := range roster {
+		for _, assignment := range assignments {
+			records := attempts[assignment.Id][member.UserId]
+			if len(records) == 0 {
+				gradebook.Records = append(gradebook.Records, GradebookRecord{
+					UserId:       member.UserId,
+					Username:     member.Username,
+					AssignmentId: assignment.Id,
+					QuizName:     assignment.QuizName,
+				})
+				continue
+			}
+
+			for _, record := range records {
+				record.QuizName = assignment.QuizName
+				gradebook.Records = append(gradebook.Records, record)
+			}
+		}
+	}
+
+	return &gradebook, nil
+}
+
+func formatGradebookTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// escapeCsvCell prefixes the cells which spreadsheets would evaluate as formulas (csv injection)
+func escapeCsvCell(value string) string {
+	if len(value) > 0 && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ToCsv exports the gradebook records to csv with a header row
+func (g *Gradebook) ToCsv() (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write([]string{
+		"student", "assignment", "quiz", "attempt", "score", "total", "finished", "expired", "duration",
+		"question_seconds", "started_at", "finished_at",
+	}); err != nil {
+		return "", err
+	}
+
+	for _, record := range g.Records {
+		attempt, score, total, finished, expired, elapsed := "", "", "", "", "", ""
+		if record.AttemptId > 0 {
+			attempt = strconv.FormatInt(record.AttemptId, 10)
+			score = strconv.Itoa(record.Score)
+			total = strconv.Itoa(record.Total)
+			finished = strconv.FormatBool(record.Finished)
+			expired = strconv.FormatBool(record.Expired)
+			elapsed = record.timer.FormatElapsed()
+		}
+
+		if err := writer.Write([]string{
+			escapeCsvCell(record.Username),
+			strconv.FormatInt(record.AssignmentId, 10),
+			escapeCsvCell(record.QuizName),
+			attempt,
+			score,
+			total,
+			finished,
+			expired,
+			strconv.FormatInt(record.Duration, 10),
+			elapsed,
+			formatGradebookTime(record.StartedAt),
+			formatGradebookTime(record.FinishedAt),
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	writer.Flush()
+	return buffer.String(), writer.Error()
+}
@@ -0,0 +1,48 @@
+package quiz
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestEscapeCsvCell(t *testing.T) {
+	cases := map[string]string{
+		"alice":                    "alice",
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1":                       "'+1",
+		"-cmd":                     "'-cmd",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"\tname":                   "'\tname",
+		"\rname":                   "'\rname",
+		"":                         "",
+	}
+
+	for value, expected := range cases {
+		if escaped := escapeCsvCell(value); escaped != expected {
+			t.Errorf("escapeCsvCell(%q) = %q, expected %q", value, escaped, expected)
+		}
+	}
+}
+
+func TestGradebookCsv(t *testing.T) {
+	gradebook := &Gradebook{Records: []GradebookRecord{
+		{Username: "=HYPERLINK(\"http://evil\",\"click\")", AssignmentId: 1, QuizName: "@quiz"},
+	}}
+
+	data, err := gradebook.ToCsv()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %s", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the header and one record, got %d rows", len(records))
+	}
+	if records[1][0] != "'=HYPERLINK(\"http://evil\",\"click\")" || records[1][2] != "'@quiz" {
+		t.Errorf("unexpected cells: %q", records[1])
+	}
+}
@@ -7,15 +7,18 @@ import (
 	"chat/channel"
 	"chat/globals"
 	"chat/utils"
-	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 var QuizPermissionGroup = []string{"quiz"}
 
+// progressInterval is the minimum interval of the progress frames of the same generation step
+const progressInterval = time.Second
+
 // GenerateQuizAPI handles quiz generation via WebSocket
 func GenerateQuizAPI(c *gin.Context) {
 	var conn *utils.WebSocket
@@ -30,29 +33,63 @@ func GenerateQuizAPI(c *gin.Context) {
 	}
 
 	user := auth.ParseToken(c, form.Token)
+	processQuizGeneration(c, user, form, func(response QuizGenerationResponse) {
+		conn.Send(response)
+	})
+}
 
+// processQuizGeneration checks the permission and the quota of the user, generates the quiz
+// and bills it, the frames are sent through send and the last frame is the end frame
+func processQuizGeneration(c *gin.Context, user *auth.User, form *QuizGenerationRequest, send func(response QuizGenerationResponse)) {
 	db := utils.GetDBFromContext(c)
 	cache := utils.GetCacheFromContext(c)
 
 	// Check if user has permission to use quiz feature
 	if !auth.HitGroups(db, user, QuizPermissionGroup) {
-		conn.Send(QuizGenerationResponse{
+		send(QuizGenerationResponse{
 			Message: "permission denied: quiz feature not available",
 			Quota:   0,
 			End:     true,
 			Error:   "permission denied",
+			Type:    PermissionError,
+		})
+		return
+	}
+
+	// Check if the prompt template is visible to the user
+	form.TemplateId = strings.TrimSpace(form.TemplateId)
+	if _, err := loadQuizTemplate(db, user, form.TemplateId); err != nil {
+		send(QuizGenerationResponse{
+			Message: err.Error(),
+			Quota:   0,
+			End:     true,
+			Error:   err.Error(),
+			Type:    InvalidRequestError,
+		})
+		return
+	}
+
+	// Check if the model can read the image files
+	if err := CheckImageFiles(form.Model, form.Files, form.FileMimes); err != nil {
+		send(QuizGenerationResponse{
+			Message: err.Error(),
+			Quota:   0,
+			End:     true,
+			Error:   err.Error(),
+			Type:    InvalidRequestError,
 		})
 		return
 	}
 
-	// Validate model and subscription
-	check, plan := auth.CanEnableModelWithSubscription(db, cache, user, form.Model, []globals.Message{})
+	// Validate model and subscription, the quiz item of the plan is used before the model item
+	check, plan := auth.CanEnableFeatureWithSubscription(db, cache, user, globals.QuizFeature, form.Model, []globals.Message{})
 	if check != nil {
-		conn.Send(QuizGenerationResponse{
+		send(QuizGenerationResponse{
 			Message: check.Error(),
 			Quota:   0,
 			End:     true,
 			Error:   check.Error(),
+			Type:    QuotaExceededError,
 		})
 		return
 	}
@@ -65,80 +102,136 @@ func GenerateQuizAPI(c *gin.Context) {
 		form.Difficulty = "Easy"
 	}
 
-	// Generate quiz using the model
-	var instance *utils.Buffer
-	err = generateQuiz(c, user, *form, plan, conn, &instance)
+	// Price the generation by the quiz feature rule if it is set, the estimated cost is sent before the generation starts
+	var (
+		charge *channel.Charge
+		cost   *QuizCost
+	)
+	if !plan {
+		charge = getQuizCharge()
+	}
+
+	progress := send
+	if charge != nil {
+		cost = newQuizCost(charge, form.QuizCount)
+		if err := checkQuizCost(db, user, cost); err != nil {
+			send(QuizGenerationResponse{
+				Message: err.Error(),
+				Quota:   0,
+				End:     true,
+				Error:   err.Error(),
+				Type:    QuotaExceededError,
+				Cost:    cost,
+			})
+			return
+		}
+
+		send(QuizGenerationResponse{
+			Message: fmt.Sprintf("estimated cost: %0.2f quota for %d questions", cost.Quota, cost.Count),
+			Quota:   0,
+			End:     false,
+			Cost:    cost,
+		})
+
+		// the token quota of the model requests is not charged
+		progress = func(response QuizGenerationResponse) {
+			response.Quota = 0
+			send(response)
+		}
+	}
+
+	// the partial coverage of long sources is repeated in the end frame
+	var coverage *QuizProgress
+	report := progress
+	progress = func(response QuizGenerationResponse) {
+		if response.Progress != nil && response.Progress.Skipped > 0 {
+			coverage = response.Progress
+		}
+		report(response)
+	}
+
+	// Generate quiz using the model, identical requests may be served from the quiz cache
+	start := time.Now()
+	quizzes, quota, hit, err := generateQuizWithCache(c, user, *form, progress)
+	if err == nil && !hit {
+		admin.IncrQuizGeneration(cache, form.Model, int64(len(quizzes)), time.Since(start))
+	}
+
+	// Only the validated questions are charged by the feature rule, the cached questions are free
+	if charge != nil {
+		count := len(quizzes)
+		if err != nil || hit {
+			count = 0
+		}
+
+		cost = newQuizCost(charge, count)
+		quota = cost.Quota
+	}
 
 	// Deduct quota if not using subscription
-	if instance != nil && !plan && instance.GetQuota() > 0 && user != nil {
-		user.UseQuota(db, instance.GetQuota())
+	if !plan && quota > 0 && user != nil {
+		user.UseQuota(db, quota)
+	}
+
+	// the plan usage is counted before the cache lookup, failed and cached generations do not consume it
+	if plan && (err != nil || hit) {
+		auth.RevertFeatureWithSubscription(db, cache, user, globals.QuizFeature, form.Model)
 	}
 
 	if err != nil {
-		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
-		conn.Send(QuizGenerationResponse{
+		send(QuizGenerationResponse{
 			Message: fmt.Sprintf("failed to generate quiz: %s", err.Error()),
-			Quota:   instance.GetQuota(),
+			Quota:   quota,
 			End:     true,
 			Error:   err.Error(),
+			Type:    GenerationError,
+			Cost:    cost,
 		})
 		return
 	}
 
-	conn.Send(QuizGenerationResponse{
-		Message: "quiz generation completed",
-		Quota:   instance.GetQuota(),
-		End:     true,
+	// Save the generated quiz into the library
+	var id int64
+	if user != nil {
+		if id, err = SaveQuiz(db, user.GetID(db), *form, quizzes); err != nil {
+			id = 0
+		}
+	}
+
+	send(QuizGenerationResponse{
+		Id:       id,
+		Message:  "quiz generation completed",
+		Quota:    quota,
+		End:      true,
+		Cached:   hit,
+		Progress: coverage,
+		Cost:     cost,
+		Data:     quizzes,
 	})
 }
 
-// generateQuiz handles the actual quiz generation logic
-func generateQuiz(c *gin.Context, user *auth.User, form QuizGenerationRequest, plan bool, conn *utils.WebSocket, bufferPtr **utils.Buffer) error {
-	db := utils.GetDBFromContext(c)
-
-	// Build the prompt for quiz generation
-	prompt := buildQuizPrompt(form)
-
-	// Create messages for the chat model
-	messages := []globals.Message{
-		{
-			Role:    globals.User,
-			Content: prompt,
-		},
-	}
+// requestQuiz sends a single request to the channel, hook is called with each chunk if not nil
+func requestQuiz(c *gin.Context, user *auth.User, form QuizGenerationRequest, messages []globals.Message, hook func(data *globals.Chunk, buffer *utils.Buffer)) (*utils.Buffer, error) {
+	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+	err := sendQuizRequest(c, user, form, &adaptercommon.ChatProps{
+		OriginalModel: form.Model,
+		Message:       messages,
+	}, buffer, hook)
 
-	// Add file content if provided
-	if len(form.Files) > 0 && len(form.FileMimes) > 0 {
-		for i, fileData := range form.Files {
-			if i < len(form.FileMimes) {
-				messages = append(messages, globals.Message{
-					Role:    globals.User,
-					Content: fmt.Sprintf("data:%s;base64,%s", form.FileMimes[i], fileData),
-				})
-			}
-		}
-	}
+	return buffer, err
+}
 
-	// Create buffer
-	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
-	*bufferPtr = buffer
+func sendQuizRequest(c *gin.Context, user *auth.User, form QuizGenerationRequest, props *adaptercommon.ChatProps, buffer *utils.Buffer, hook func(data *globals.Chunk, buffer *utils.Buffer)) error {
+	db := utils.GetDBFromContext(c)
 
-	// Stream the response using channel
 	err := channel.NewChatRequest(
 		auth.GetGroup(db, user),
-		adaptercommon.CreateChatProps(&adaptercommon.ChatProps{
-			OriginalModel: form.Model,
-			Message:       messages,
-		}, buffer),
+		adaptercommon.CreateChatProps(props, buffer),
 		func(data *globals.Chunk) error {
 			buffer.WriteChunk(data)
-			// Send intermediate updates
-			conn.Send(QuizGenerationResponse{
-				Message: "generating quiz...",
-				Data:    data.Content,
-				Quota:   buffer.GetQuota(),
-				End:     false,
-			})
+			if hook != nil {
+				hook(data, buffer)
+			}
 			return nil
 		},
 	)
@@ -146,77 +239,233 @@ func generateQuiz(c *gin.Context, user *auth.User, form QuizGenerationRequest, p
 	// Analyse request for admin dashboard
 	admin.AnalyseRequest(form.Model, buffer, err)
 
+	return err
+}
+
+// generateQuiz handles the actual quiz generation logic, long sources are split into
+// token-budgeted chunks which are generated in parallel and balanced into QuizCount questions
+func generateQuiz(c *gin.Context, user *auth.User, form QuizGenerationRequest, send func(response QuizGenerationResponse)) ([]Quiz, float32, error) {
+	// Extract the file content if provided, images are kept for vision models
+	files, err := ExtractSourceFiles(form.Files, form.FileMimes)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
-	// Get the complete response
-	response := string(buffer.ReadBytes())
+	source, images := buildSource(form.Notes, files)
+	chunks, skipped := splitSource(source, form.Model, form.QuizCount)
+	if skipped > 0 {
+		send(QuizGenerationResponse{
+			Message:  fmt.Sprintf("the source is too long for %d questions, only %d of its %d chunks are used", form.QuizCount, len(chunks), len(chunks)+skipped),
+			End:      false,
+			Progress: &QuizProgress{Current: 0, Total: len(chunks), Skipped: skipped},
+		})
+	}
 
-	// Send final response with the generated quiz data
-	conn.Send(QuizGenerationResponse{
-		Message: "quiz generation completed",
-		Data:    response,
-		Quota:   buffer.GetQuota(),
+	if len(chunks) <= 1 {
+		task := quizTask{Source: source, Images: images, Count: form.QuizCount}
+		if len(chunks) == 1 {
+			task.Source = chunks[0]
+		}
+		// the raw model output is buffered until it is validated, only the progress frames are sent
+		var last string
+		var sent time.Time
+		quizzes, quota, err := generateTask(c, user, form, task, func(message string, buffer *utils.Buffer) {
+			if message == last && time.Since(sent) < progressInterval {
+				return
+			}
+			last, sent = message, time.Now()
+
+			send(QuizGenerationResponse{
+				Message: message,
+				Quota:   buffer.GetQuota(),
+				End:     false,
+			})
+		})
+		if err != nil {
+			return nil, quota, err
+		}
+
+		return finishQuizzes(c, user, form, renumberQuizzes(quizzes), quota, send)
+	}
+
+	quizzes, quota, err := generateChunks(c, user, form, chunks, images, func(progress QuizProgress, quota float32) {
+		progress.Skipped = skipped
+		send(QuizGenerationResponse{
+			Message:  fmt.Sprintf("generating quiz from chunk %d of %d...", progress.Current, progress.Total),
+			Quota:    quota,
+			End:      false,
+			Progress: &progress,
+		})
+	})
+	if err != nil {
+		return nil, quota, err
+	}
+
+	return finishQuizzes(c, user, form, renumberQuizzes(quizzes), quota, send)
+}
+
+// finishQuizzes runs the optional answer key verification and grounding of the resource links on the generated questions
+func finishQuizzes(c *gin.Context, user *auth.User, form QuizGenerationRequest, quizzes []Quiz, quota float32, send func(response QuizGenerationResponse)) ([]Quiz, float32, error) {
+	if IsJudgeEnabled(form.Model) {
+		send(QuizGenerationResponse{
+			Message: "verifying answer keys...",
+			Quota:   quota,
+			End:     false,
+		})
+
+		var verified float32
+		quizzes, verified = verifyQuizzes(c, user, form, quizzes)
+		quota += verified
+		if len(quizzes) == 0 {
+			return nil, quota, fmt.Errorf("no verified questions generated")
+		}
+		quizzes = renumberQuizzes(quizzes)
+	}
+
+	if !form.Grounding {
+		return quizzes, quota, nil
+	}
+
+	send(QuizGenerationResponse{
+		Message: "verifying resource links...",
+		Quota:   quota,
 		End:     false,
 	})
 
-	return nil
+	quizzes, grounded := groundResources(c, user, form, quizzes)
+	return quizzes, quota + grounded, nil
 }
 
-// buildQuizPrompt constructs the prompt for quiz generation
-func buildQuizPrompt(form QuizGenerationRequest) string {
-	var builder strings.Builder
+// generateTask generates the questions of the task, the model output is validated
+// and re-prompted with the validation problems for at most maxRepairRounds rounds
+func generateTask(c *gin.Context, user *auth.User, form QuizGenerationRequest, task quizTask, hook func(message string, buffer *utils.Buffer)) ([]Quiz, float32, error) {
+	// Create messages for the chat model
+	messages := buildQuizMessages(form, task, TemplateInstance.GetTemplate(form.TemplateId))
 
-	builder.WriteString(fmt.Sprintf(
-		"You are an all-rounder tutor with professional expertise in different fields. "+
-			"You are to generate a list of quiz questions with a difficulty of %s. ",
-		form.Difficulty,
-	))
+	for _, image := range task.Images {
+		messages = append(messages, globals.Message{
+			Role:    globals.User,
+			Content: image,
+		})
+	}
 
-	if form.Topic != "" {
-		builder.WriteString(fmt.Sprintf("The topic is: %s. ", form.Topic))
+	var quota float32
+	quizzes := make([]Quiz, len(task.Avoid))
+	copy(quizzes, task.Avoid)
+	total := len(task.Avoid) + task.Count
+	message := "generating quiz..."
+
+	for round := 0; round <= maxRepairRounds; round++ {
+		var streamHook func(data *globals.Chunk, buffer *utils.Buffer)
+		if hook != nil {
+			current := message
+			streamHook = func(_ *globals.Chunk, buffer *utils.Buffer) {
+				hook(current, buffer)
+			}
+		}
+
+		response, buffer, err := requestStructuredQuiz(c, user, form, messages, streamHook)
+		quota += buffer.GetQuota()
+		if err != nil {
+			return nil, quota, err
+		}
+
+		valid, problems := ValidateQuizResponse(response, form.GetQuestionTypes())
+		malformed := isMalformedQuizResponse(response)
+		admin.IncrQuizResponse(utils.GetCacheFromContext(c), form.Model, malformed, !malformed && len(problems) > 0)
+		quizzes = mergeQuizzes(quizzes, valid, total)
+
+		missing := total - len(quizzes)
+		if missing <= 0 {
+			break
+		}
+
+		if len(problems) == 0 {
+			problems = append(problems, fmt.Sprintf("expected %d questions, but got %d valid questions", task.Count, len(quizzes)-len(task.Avoid)))
+		}
+
+		globals.Debug(fmt.Sprintf("[quiz] repair round %d, %d questions missing: %s", round+1, missing, strings.Join(problems, "; ")))
+
+		messages = append(messages,
+			globals.Message{Role: globals.Assistant, Content: response},
+			globals.Message{Role: globals.User, Content: buildRepairPrompt(problems, missing)},
+		)
+		message = fmt.Sprintf("repairing quiz (round %d)...", round+1)
 	}
 
-	if form.Notes != "" {
-		builder.WriteString(fmt.Sprintf("Use the following notes as the basis for the quiz:\n%s\n\n", form.Notes))
+	quizzes = quizzes[len(task.Avoid):]
+	if len(quizzes) == 0 {
+		return nil, quota, fmt.Errorf("no valid questions generated")
 	}
 
-	builder.WriteString(fmt.Sprintf(
-		"Your response should be in JSON as an array of objects. Generate exactly %d different questions. "+
-			"Each question should follow this structure:\n"+
-			"{\n"+
-			"  \"id\": \"1\",\n"+
-			"  \"question\": \"Question text here\",\n"+
-			"  \"description\": \"Additional context or explanation\",\n"+
-			"  \"options\": {\n"+
-			"    \"a\": \"Option A text\",\n"+
-			"    \"b\": \"Option B text\",\n"+
-			"    \"c\": \"Option C text\",\n"+
-			"    \"d\": \"Option D text\"\n"+
-			"  },\n"+
-			"  \"answer\": \"a\" // The correct answer key (a, b, c, or d)\n"+
-			"}\n\n"+
-			"Return only the JSON array without any additional text or markdown formatting.",
-		form.QuizCount,
-	))
+	return quizzes, quota, nil
+}
 
-	return builder.String()
+// buildQuizMessages constructs the messages of the task, the prompt template replaces the built-in persona if it is not nil
+func buildQuizMessages(form QuizGenerationRequest, task quizTask, template *PromptTemplate) []globals.Message {
+	messages := make([]globals.Message, 0, 2)
+	if template != nil && len(template.System) > 0 {
+		messages = append(messages, globals.Message{
+			Role:    globals.System,
+			Content: template.Render(template.System, form, task),
+		})
+	}
+
+	return append(messages, globals.Message{
+		Role:    globals.User,
+		Content: buildQuizPrompt(form, task, template),
+	})
 }
 
-// ValidateQuizResponse checks if the generated response is valid JSON
-func ValidateQuizResponse(response string) ([]Quiz, error) {
-	// Remove potential markdown code blocks
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
+// buildQuizPrompt constructs the prompt for quiz generation
+func buildQuizPrompt(form QuizGenerationRequest, task quizTask, template *PromptTemplate) string {
+	var builder strings.Builder
+
+	if template != nil {
+		builder.WriteString(template.Render(template.Prompt, form, task))
+		builder.WriteString("\n\n")
+	} else {
+		builder.WriteString(fmt.Sprintf(
+			"You are an all-rounder tutor with professional expertise in different fields. "+
+				"You are to generate a list of quiz questions with a difficulty of %s. ",
+			form.Difficulty,
+		))
+
+		if form.Topic != "" {
+			builder.WriteString(fmt.Sprintf("The topic is: %s. ", form.Topic))
+		}
+	}
+
+	// the language is already in the prompt if the template uses the language variable
+	if language := getLanguageName(form.Language); language != "" && (template == nil || !template.UsesVariable("language")) {
+		builder.WriteString(fmt.Sprintf(
+			"Write all the text of the questions in %s, regardless of the language of the notes and the topic. ",
+			language,
+		))
+	}
+
+	if task.Source != "" {
+		// the notes are already in the prompt if the template uses the notes variable
+		if template == nil || !template.UsesVariable("notes") {
+			builder.WriteString(fmt.Sprintf("Use the following notes as the basis for the quiz:\n%s\n\n", task.Source))
+		}
+		builder.WriteString("Every question has a \"source\" field quoting the short excerpt of the notes it is based on.\n\n")
+	}
 
-	var quizzes []Quiz
-	if err := json.Unmarshal([]byte(response), &quizzes); err != nil {
-		return nil, fmt.Errorf("invalid quiz response format: %v", err)
+	if len(task.Avoid) > 0 {
+		builder.WriteString("Do not repeat the following questions:\n")
+		for _, quiz := range task.Avoid {
+			builder.WriteString(fmt.Sprintf("- %s\n", quiz.Question))
+		}
+		builder.WriteString("\n")
 	}
 
-	return quizzes, nil
+	builder.WriteString(fmt.Sprintf(
+		"Your response should be in JSON as an array of objects. Generate exactly %d different questions.\n",
+		task.Count,
+	))
+	builder.WriteString(buildTypesPrompt(form.GetQuestionTypes()))
+	builder.WriteString("\nReturn only the JSON array without any additional text or markdown formatting.")
+
+	return builder.String()
 }
@@ -0,0 +1,163 @@
+package quiz
+
+import (
+	"chat/manager/conversation"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// buildExplainPrompt builds the user message with the question, the selected and the correct
+// answer and the source excerpt of the question
+func buildExplainPrompt(q Quiz, selected string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("I answered this quiz question and I would like to understand it.\n\nQuestion: %s\n", q.Question))
+	if len(q.Options) > 0 {
+		builder.WriteString(fmt.Sprintf("Options:\n%s\n", formatOptions(q)))
+	}
+
+	answer := q.FormatSelection(selected)
+	if len(strings.TrimSpace(answer)) == 0 {
+		answer = "(no answer)"
+	}
+	builder.WriteString(fmt.Sprintf("\nMy answer: %s\n", answer))
+	builder.WriteString(fmt.Sprintf("Correct answer: %s\n", formatAnswer(q)))
+
+	if len(q.Source) > 0 {
+		builder.WriteString(fmt.Sprintf("\nSource excerpt: %s\n", q.Source))
+	}
+
+	if q.IsCorrect(selected) {
+		builder.WriteString("\nWhy is my answer correct?")
+	} else {
+		builder.WriteString("\nWhy is my answer wrong?")
+	}
+	return builder.String()
+}
+
+// buildExplainReply builds the assistant message from the stored explanations, no model is called
+func buildExplainReply(q Quiz, selected string) string {
+	parts := make([]string, 0)
+	if explanation := q.Explain(selected); len(explanation) > 0 {
+		parts = append(parts, explanation)
+	}
+
+	if !q.IsCorrect(selected) {
+		if explanation := q.Explain(q.AnswerKey()); len(explanation) > 0 {
+			parts = append(parts, fmt.Sprintf("The correct answer is %s.\n%s", formatAnswer(q), explanation))
+		} else {
+			parts = append(parts, fmt.Sprintf("The correct answer is %s.", formatAnswer(q)))
+		}
+	}
+
+	if len(q.Description) > 0 {
+		parts = append(parts, q.Description)
+	}
+
+	parts = append(parts, "Feel free to ask if anything is still unclear.")
+	return strings.Join(parts, "\n\n")
+}
+
+// attempt types of the explained question
+const (
+	QuizAttemptType       = "quiz"
+	AssignmentAttemptType = "assignment"
+	SharedAttemptType     = "share"
+)
+
+// loadExplainQuiz loads the quiz of the explained question and the answers of the attempt. without an attempt
+// only the owner can open the quiz, otherwise the quiz is loaded through the attempt of the user the same way
+// it is graded, so that the students of assignments and share links can open the questions they answered
+func loadExplainQuiz(db *sql.DB, userId int64, form ExplainQuestionForm) (*SavedQuiz, map[string]string, error) {
+	if form.AttemptId <= 0 {
+		quiz := LoadQuiz(db, userId, form.QuizId)
+		if quiz == nil {
+			return nil, nil, fmt.Errorf("quiz not found")
+		}
+		return quiz, map[string]string{}, nil
+	}
+
+	var (
+		quiz     *SavedQuiz
+		answers  map[string]string
+		finished bool
+	)
+	switch form.AttemptType {
+	case "", QuizAttemptType:
+		attempt := LoadAttempt(db, userId, form.AttemptId)
+		if attempt == nil {
+			return nil, nil, fmt.Errorf("attempt not found")
+		}
+		// the attempts of the own library are explained before they are finished as well
+		quiz, answers, finished = LoadQuiz(db, userId, attempt.QuizId), attempt.Answers, true
+	case AssignmentAttemptType:
+		attempt := LoadAssignmentAttempt(db, userId, form.AttemptId)
+		if attempt == nil {
+			return nil, nil, fmt.Errorf("attempt not found")
+		}
+
+		_, assigned, err := loadAssignedQuiz(db, userId, attempt.AssignmentId)
+		if err != nil {
+			return nil, nil, err
+		}
+		quiz, answers, finished = assigned, attempt.Answers, attempt.Finished
+	case SharedAttemptType:
+		attempt := LoadUserSharedAttempt(db, userId, form.AttemptId)
+		if attempt == nil {
+			return nil, nil, fmt.Errorf("attempt not found")
+		}
+
+		shared, err := GetSharedQuiz(db, attempt.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		quiz, answers, finished = shared.quiz, attempt.Answers, attempt.Finished
+	default:
+		return nil, nil, fmt.Errorf("unknown attempt type %q", form.AttemptType)
+	}
+
+	if quiz == nil || (form.QuizId > 0 && quiz.Id != form.QuizId) {
+		return nil, nil, fmt.Errorf("quiz not found")
+	}
+	if !finished {
+		return nil, nil, fmt.Errorf("the questions are explained after the attempt is finished")
+	}
+
+	return quiz, answers, nil
+}
+
+// ExplainQuestion opens a conversation seeded with the question and the answer of the user,
+// the user continues it in the chat under the own quota
+func ExplainQuestion(db *sql.DB, userId int64, form ExplainQuestionForm) (*conversation.Conversation, error) {
+	quiz, answers, err := loadExplainQuiz(db, userId, form)
+	if err != nil {
+		return nil, err
+	}
+
+	question := findQuestion(quiz.Data, form.QuestionId)
+	if question == nil {
+		return nil, fmt.Errorf("question not found")
+	}
+
+	selected := form.Answer
+	if len(selected) == 0 {
+		selected = answers[question.ID]
+	}
+
+	instance := conversation.NewConversation(db, userId)
+	if len(form.Model) > 0 {
+		instance.SetModel(form.Model)
+	} else if len(quiz.Model) > 0 && quiz.Model != importModel {
+		instance.SetModel(quiz.Model)
+	}
+
+	instance.AddMessageFromUser(buildExplainPrompt(*question, selected))
+	instance.AddMessageFromAssistant(buildExplainReply(*question, selected))
+	instance.Name = utils.Extract(fmt.Sprintf("Explain: %s", question.Question), 50, "...")
+	if !instance.SaveConversation(db) {
+		return nil, fmt.Errorf("failed to save the conversation")
+	}
+
+	return instance, nil
+}
@@ -0,0 +1,421 @@
+package quiz
+
+import (
+	"bytes"
+	"chat/utils"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/lukasjarosch/go-docx"
+)
+
+const (
+	ExportGift   = "gift"
+	ExportMoodle = "moodle"
+	ExportQti    = "qti"
+	ExportCsv    = "csv"
+	ExportTsv    = "tsv"
+	ExportDocx   = "docx"
+)
+
+const exportDir = "storage/quiz/export"
+
+// ExportFile represents an exported quiz file ready to be downloaded
+type ExportFile struct {
+	Path     string
+	Filename string
+	Dir      string // temporary directory of the export, removed after download
+}
+
+func (f ExportFile) Clean() {
+	_ = os.RemoveAll(f.Dir)
+}
+
+func exportFilename(name string, ext string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if len(name) == 0 {
+		name = "quiz"
+	}
+	return fmt.Sprintf("%s.%s", name, ext)
+}
+
+// ExportQuiz exports the saved quiz to the format and writes the file into a temporary directory
+func ExportQuiz(quiz *SavedQuiz, format string) (*ExportFile, error) {
+	dir := fmt.Sprintf("%s/%s", exportDir, utils.Md5Encrypt(fmt.Sprintf("%d:%s:%s", quiz.Id, format, utils.GenerateChar(16))))
+	file := &ExportFile{Dir: dir}
+
+	result, err := exportQuiz(quiz, format, file)
+	if err != nil {
+		file.Clean()
+		return nil, err
+	}
+	return result, nil
+}
+
+func exportQuiz(quiz *SavedQuiz, format string, file *ExportFile) (*ExportFile, error) {
+	write := func(ext string, data string) (*ExportFile, error) {
+		file.Filename = exportFilename(quiz.Name, ext)
+		file.Path = fmt.Sprintf("%s/%s", file.Dir, file.Filename)
+		if err := utils.WriteFile(file.Path, data, true); err != nil {
+			return nil, err
+		}
+		return file, nil
+	}
+
+	switch format {
+	case ExportGift:
+		return write("gift.txt", ToGift(quiz))
+	case ExportMoodle:
+		data, err := ToMoodleXml(quiz)
+		if err != nil {
+			return nil, err
+		}
+		return write("xml", data)
+	case ExportCsv, ExportTsv:
+		data, err := ToAnki(quiz, format == ExportTsv)
+		if err != nil {
+			return nil, err
+		}
+		return write(format, data)
+	case ExportQti:
+		return exportQti(quiz, file)
+	case ExportDocx:
+		return exportDocx(quiz, file)
+	}
+
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// escapeGift escapes the special characters of the gift format
+func escapeGift(text string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, `~`, `\~`, `=`, `\=`, `#`, `\#`, `{`, `\{`, `}`, `\}`, `:`, `\:`, "\n", `\n`,
+	)
+	return replacer.Replace(strings.TrimSpace(text))
+}
+
+// ToGift exports the quiz to the Moodle GIFT format, ordering questions are exported as matching
+func ToGift(quiz *SavedQuiz) string {
+	var builder strings.Builder
+
+	for i, q := range quiz.Data {
+		title := fmt.Sprintf("::Q%d:: ", i+1)
+		feedback := ""
+		if len(strings.TrimSpace(q.Description)) > 0 {
+			feedback = fmt.Sprintf(" ####%s", escapeGift(q.Description))
+		}
+
+		switch q.GetType() {
+		case MultipleChoice:
+			builder.WriteString(title + escapeGift(q.Question) + " {\n")
+			for _, key := range q.Options.Keys() {
+				mark := "~"
+				if key == q.Answer {
+					mark = "="
+				}
+				builder.WriteString(fmt.Sprintf("\t%s%s%s\n", mark, escapeGift(q.Options[key]), giftFeedback(q.Explanations[key])))
+			}
+			builder.WriteString(feedback + "}\n\n")
+		case TrueFalse:
+			answer := "F"
+			if q.Answer == "true" {
+				answer = "T"
+			}
+			// the feedback of the wrong answer comes first
+			wrong, right := q.Explanations["false"], q.Explanations["true"]
+			if answer == "F" {
+				wrong, right = right, wrong
+			}
+			if len(wrong) > 0 || len(right) > 0 {
+				answer += fmt.Sprintf("#%s#%s", escapeGift(wrong), escapeGift(right))
+			}
+			builder.WriteString(fmt.Sprintf("%s%s {%s%s}\n\n", title, escapeGift(q.Question), answer, feedback))
+		case MultiSelect:
+			right, wrong := len(q.Answers), len(q.Options)-len(q.Answers)
+			builder.WriteString(title + escapeGift(q.Question) + " {\n")
+			for _, key := range q.Options.Keys() {
+				weight := 0.0
+				if utils.Contains(key, q.Answers) {
+					weight = 100 / float64(right)
+				} else if wrong > 0 {
+					weight = -100 / float64(wrong)
+				}
+				builder.WriteString(fmt.Sprintf("\t~%%%g%%%s%s\n", roundWeight(weight), escapeGift(q.Options[key]), giftFeedback(q.Explanations[key])))
+			}
+			builder.WriteString(feedback + "}\n\n")
+		case FillBlank:
+			parts := strings.SplitN(q.Question, BlankMarker, 2)
+			answers := utils.Each(q.Answers, func(answer string) string {
+				return "=" + escapeGift(answer)
+			})
+			blank := fmt.Sprintf("{%s%s}", strings.Join(answers, " "), feedback)
+			if len(parts) == 2 {
+				builder.WriteString(fmt.Sprintf("%s%s %s %s\n\n", title, escapeGift(parts[0]), blank, escapeGift(parts[1])))
+			} else {
+				builder.WriteString(fmt.Sprintf("%s%s %s\n\n", title, escapeGift(q.Question), blank))
+			}
+		case Ordering:
+			builder.WriteString(title + escapeGift(q.Question) + " {\n")
+			for i, key := range q.Answers {
+				builder.WriteString(fmt.Sprintf("\t=%s -> %d\n", escapeGift(q.Options[key]), i+1))
+			}
+			builder.WriteString(feedback + "}\n\n")
+		case ShortAnswer:
+			rubric := fmt.Sprintf(" ####%s", escapeGift(strings.Join(q.Rubric, "; ")))
+			builder.WriteString(fmt.Sprintf("%s%s {%s}\n\n", title, escapeGift(q.Question), rubric))
+		}
+	}
+
+	return builder.String()
+}
+
+// giftFeedback formats the answer feedback, empty if the option has no explanation
+func giftFeedback(text string) string {
+	if len(text) == 0 {
+		return ""
+	}
+	return " #" + escapeGift(text)
+}
+
+// roundWeight rounds the gift weight to 5 decimals as moodle accepts
+func roundWeight(weight float64) float64 {
+	return float64(int(weight*100000)) / 100000
+}
+
+type moodleText struct {
+	Format string `xml:"format,attr,omitempty"`
+	Text   string `xml:"text"`
+}
+
+type moodleAnswer struct {
+	Fraction string     `xml:"fraction,attr"`
+	Format   string     `xml:"format,attr,omitempty"`
+	Text     string     `xml:"text"`
+	Feedback moodleText `xml:"feedback"`
+}
+
+type moodleSubQuestion struct {
+	Format string `xml:"format,attr"`
+	Text   string `xml:"text"`
+	Answer struct {
+		Text string `xml:"text"`
+	} `xml:"answer"`
+}
+
+type moodleQuestion struct {
+	Type            string              `xml:"type,attr"`
+	Name            moodleText          `xml:"name"`
+	QuestionText    moodleText          `xml:"questiontext"`
+	GeneralFeedback moodleText          `xml:"generalfeedback"`
+	Single          string              `xml:"single,omitempty"`
+	Shuffle         string              `xml:"shuffleanswers,omitempty"`
+	Answers         []moodleAnswer      `xml:"answer,omitempty"`
+	SubQuestions    []moodleSubQuestion `xml:"subquestion,omitempty"`
+	GraderInfo      *moodleText         `xml:"graderinfo,omitempty"`
+}
+
+type moodleQuiz struct {
+	XMLName   xml.Name         `xml:"quiz"`
+	Questions []moodleQuestion `xml:"question"`
+}
+
+// ToMoodleXml exports the quiz to the Moodle XML format, ordering questions are exported as matching
+func ToMoodleXml(quiz *SavedQuiz) (string, error) {
+	result := moodleQuiz{}
+	for i, q := range quiz.Data {
+		question := moodleQuestion{
+			Name:            moodleText{Text: fmt.Sprintf("Q%d", i+1)},
+			QuestionText:    moodleText{Format: "html", Text: html.EscapeString(q.Question)},
+			GeneralFeedback: moodleText{Format: "html", Text: html.EscapeString(q.Description)},
+		}
+
+		switch q.GetType() {
+		case MultipleChoice, MultiSelect:
+			question.Type = "multichoice"
+			question.Single = "true"
+			question.Shuffle = "1"
+			right, wrong := 1, len(q.Options)-1
+			if q.GetType() == MultiSelect {
+				question.Single = "false"
+				right, wrong = len(q.Answers), len(q.Options)-len(q.Answers)
+			}
+
+			for _, key := range q.Options.Keys() {
+				fraction := 0.0
+				if key == q.Answer || utils.Contains(key, q.Answers) {
+					fraction = 100 / float64(right)
+				} else if q.GetType() == MultiSelect && wrong > 0 {
+					fraction = -100 / float64(wrong)
+				}
+				question.Answers = append(question.Answers, moodleAnswer{
+					Fraction: fmt.Sprintf("%g", roundWeight(fraction)),
+					Format:   "html",
+					Text:     html.EscapeString(q.Options[key]),
+					Feedback: moodleText{Format: "html", Text: html.EscapeString(q.Explanations[key])},
+				})
+			}
+		case TrueFalse:
+			question.Type = "truefalse"
+			for _, value := range []string{"true", "false"} {
+				fraction := "0"
+				if q.Answer == value {
+					fraction = "100"
+				}
+				question.Answers = append(question.Answers, moodleAnswer{
+					Fraction: fraction,
+					Text:     value,
+					Feedback: moodleText{Format: "html", Text: html.EscapeString(q.Explanations[value])},
+				})
+			}
+		case FillBlank:
+			question.Type = "shortanswer"
+			for _, answer := range q.Answers {
+				question.Answers = append(question.Answers, moodleAnswer{Fraction: "100", Text: answer})
+			}
+		case Ordering:
+			question.Type = "matching"
+			question.Shuffle = "1"
+			for i, key := range q.Answers {
+				sub := moodleSubQuestion{Format: "html", Text: html.EscapeString(q.Options[key])}
+				sub.Answer.Text = fmt.Sprintf("%d", i+1)
+				question.SubQuestions = append(question.SubQuestions, sub)
+			}
+		case ShortAnswer:
+			question.Type = "essay"
+			question.GraderInfo = &moodleText{Format: "html", Text: strings.Join(utils.Each(q.Rubric, html.EscapeString), "<br/>")}
+		}
+
+		result.Questions = append(result.Questions, question)
+	}
+
+	data, err := xml.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(data), nil
+}
+
+// formatOptions formats the options of the question as text lines
+func formatOptions(q Quiz) string {
+	lines := make([]string, 0, len(q.Options))
+	for _, key := range q.Options.Keys() {
+		lines = append(lines, fmt.Sprintf("%s. %s", strings.ToUpper(key), q.Options[key]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAnswer formats the readable correct answer of the question
+func formatAnswer(q Quiz) string {
+	if q.GetType() == ShortAnswer && len(strings.TrimSpace(q.ModelAnswer)) > 0 {
+		return fmt.Sprintf("%s\n%s", q.ModelAnswer, q.FormatSelection(q.AnswerKey()))
+	}
+	return q.FormatSelection(q.AnswerKey())
+}
+
+// ToAnki exports the quiz to the Anki importable csv (or tsv) with front and back fields
+func ToAnki(quiz *SavedQuiz, tsv bool) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if tsv {
+		writer.Comma = '\t'
+	}
+
+	for _, q := range quiz.Data {
+		front := q.Question
+		if len(q.Options) > 0 {
+			front = fmt.Sprintf("%s\n\n%s", q.Question, formatOptions(q))
+		}
+
+		back := formatAnswer(q)
+		if len(strings.TrimSpace(q.Description)) > 0 {
+			back = fmt.Sprintf("%s\n\n%s", back, q.Description)
+		}
+
+		// anki renders the fields as html
+		if err := writer.Write([]string{
+			strings.ReplaceAll(html.EscapeString(front), "\n", "<br>"),
+			strings.ReplaceAll(html.EscapeString(back), "\n", "<br>"),
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	writer.Flush()
+	return buffer.String(), writer.Error()
+}
+
+func generateDocxFile(target, title, content string) error {
+	data := docx.PlaceholderMap{
+		"title":   title,
+		"content": content,
+	}
+
+	doc, err := docx.Open("addition/article/template.docx")
+	if err != nil {
+		return err
+	}
+
+	if err := doc.ReplaceAll(data); err != nil {
+		return err
+	}
+
+	return doc.WriteToFile(target)
+}
+
+// exportDocx exports the printable quiz and the separate answer key into a zip
+func exportDocx(quiz *SavedQuiz, file *ExportFile) (*ExportFile, error) {
+	var questions, answers strings.Builder
+	for i, q := range quiz.Data {
+		questions.WriteString(fmt.Sprintf("%d. %s\n", i+1, q.Question))
+		switch q.GetType() {
+		case TrueFalse:
+			questions.WriteString("True / False\n")
+		case Ordering:
+			questions.WriteString("Order the following items:\n" + formatOptions(q) + "\n")
+		case FillBlank, ShortAnswer:
+			questions.WriteString("Answer: ______________________________\n")
+		default:
+			questions.WriteString(formatOptions(q) + "\n")
+		}
+		questions.WriteString("\n")
+
+		answers.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatAnswer(q)))
+		if len(strings.TrimSpace(q.Description)) > 0 {
+			answers.WriteString(q.Description + "\n")
+		}
+		answers.WriteString("\n")
+	}
+
+	source := fmt.Sprintf("%s/source", file.Dir)
+	files := []string{
+		fmt.Sprintf("%s/%s", source, exportFilename(quiz.Name, "docx")),
+		fmt.Sprintf("%s/%s", source, exportFilename(quiz.Name+" - answer key", "docx")),
+	}
+
+	utils.FileDirSafe(files[0])
+	if err := generateDocxFile(files[0], quiz.Name, questions.String()); err != nil {
+		return nil, err
+	}
+	if err := generateDocxFile(files[1], quiz.Name+" - Answer Key", answers.String()); err != nil {
+		return nil, err
+	}
+
+	file.Filename = exportFilename(quiz.Name, "zip")
+	file.Path = fmt.Sprintf("%s/%s", file.Dir, file.Filename)
+	if err := utils.CreateZipObject(file.Path, files, source); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
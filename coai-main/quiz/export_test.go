@@ -0,0 +1,84 @@
+package quiz
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestToQtiItem(t *testing.T) {
+	quiz := newRoundTripQuiz()
+	quiz.Data = append(quiz.Data,
+		Quiz{ID: "5", Type: FillBlank, Question: "The ___ is the control center of the cell.", Answers: []string{"nucleus"}},
+		Quiz{ID: "6", Type: ShortAnswer, Question: "Explain osmosis.", Rubric: []string{"water", "membrane"}},
+	)
+
+	for _, q := range quiz.Data {
+		item := ToQtiItem(q, "ITEM_"+q.ID)
+
+		var parsed struct {
+			Identifier  string `xml:"identifier,attr"`
+			Declaration struct {
+				Values []string `xml:"correctResponse>value"`
+			} `xml:"responseDeclaration"`
+		}
+		if err := xml.Unmarshal([]byte(item), &parsed); err != nil {
+			t.Errorf("question %s: invalid qti item: %s\n%s", q.ID, err, item)
+			continue
+		}
+
+		if parsed.Identifier != "ITEM_"+q.ID || len(parsed.Declaration.Values) == 0 {
+			t.Errorf("question %s: unexpected qti item: %+v", q.ID, parsed)
+		}
+	}
+}
+
+func TestToAnki(t *testing.T) {
+	data, err := ToAnki(newRoundTripQuiz(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("expected a card of each question, got %d lines:\n%s", len(lines), data)
+	}
+	if !strings.Contains(data, "Mitochondrion") {
+		t.Errorf("the answer of the first question is missing:\n%s", data)
+	}
+}
+
+func TestExportQuizCleanup(t *testing.T) {
+	// the docx template is resolved from the working directory, which lacks it in the temp dir
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if _, err := ExportQuiz(newRoundTripQuiz(), ExportDocx); err == nil {
+		t.Fatalf("expected the export to fail without the docx template")
+	}
+
+	entries, err := os.ReadDir(exportDir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("expected the failed export to be removed, got %d directories", len(entries))
+	}
+
+	file, err := ExportQuiz(newRoundTripQuiz(), ExportGift)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer file.Clean()
+
+	if _, err := os.Stat(file.Path); err != nil {
+		t.Errorf("exported file is missing: %s", err)
+	}
+}
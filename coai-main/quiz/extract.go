@@ -0,0 +1,480 @@
+package quiz
+
+import (
+	"archive/zip"
+	"bytes"
+	"chat/globals"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lukasjarosch/go-docx"
+	"golang.org/x/net/html"
+)
+
+const (
+	MaxFileSize    = 10 * 1024 * 1024 // max decoded size of each source file
+	MaxFilePages   = 50               // max pages (pdf), page breaks (docx) or slides (pptx) of each source file
+	MaxExtractSize = 4 * MaxFileSize  // max decompressed size of the streams (pdf) or archive entries (docx, pptx) of each source file
+)
+
+var errExtractLimit = fmt.Errorf("file exceeds the decompressed size limit of %d MiB", MaxExtractSize/1024/1024)
+
+// extractBudget limits the decompressed bytes of a source file, so that a small compressed
+// stream or archive entry cannot expand into gigabytes (zip bomb)
+type extractBudget struct {
+	remaining int64
+}
+
+type budgetReader struct {
+	reader io.Reader
+	budget *extractBudget
+}
+
+func newExtractBudget() *extractBudget {
+	return &extractBudget{remaining: MaxExtractSize}
+}
+
+// Reader wraps the reader, reading beyond the budget fails with errExtractLimit
+func (b *extractBudget) Reader(reader io.Reader) io.Reader {
+	return &budgetReader{reader: reader, budget: b}
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if r.budget.remaining <= 0 {
+		return 0, errExtractLimit
+	}
+
+	if int64(len(p)) > r.budget.remaining {
+		p = p[:r.budget.remaining]
+	}
+	n, err := r.reader.Read(p)
+	r.budget.remaining -= int64(n)
+	return n, err
+}
+
+const (
+	MimePdf      = "application/pdf"
+	MimeDocx     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MimePptx     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	MimeHtml     = "text/html"
+	MimeMarkdown = "text/markdown"
+	MimeText     = "text/plain"
+)
+
+// SourceFile represents a source file of the quiz, Content is the extracted text
+// or the data url of the image for vision models
+type SourceFile struct {
+	Mime    string
+	Content string
+	IsImage bool
+}
+
+func normalizeMime(mime string) string {
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if idx := strings.Index(mime, ";"); idx >= 0 {
+		mime = strings.TrimSpace(mime[:idx])
+	}
+
+	switch mime {
+	case "text/x-markdown", "text/md":
+		return MimeMarkdown
+	case "application/xhtml+xml":
+		return MimeHtml
+	}
+
+	return mime
+}
+
+// trimDataUrl returns the base64 data of the file, the file may be sent as a data url
+func trimDataUrl(data string) string {
+	if strings.HasPrefix(data, "data:") {
+		if idx := strings.Index(data, ","); idx >= 0 {
+			return data[idx+1:]
+		}
+	}
+	return data
+}
+
+func checkFileSize(data string) error {
+	if base64.StdEncoding.DecodedLen(len(data)) > MaxFileSize+3 {
+		return fmt.Errorf("file exceeds the size limit of %d MiB", MaxFileSize/1024/1024)
+	}
+	return nil
+}
+
+func decodeFile(data string) ([]byte, error) {
+	data = trimDataUrl(data)
+	if err := checkFileSize(data); err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 file data")
+	}
+
+	if len(raw) > MaxFileSize {
+		return nil, fmt.Errorf("file exceeds the size limit of %d MiB", MaxFileSize/1024/1024)
+	}
+
+	return raw, nil
+}
+
+// CheckImageFiles refuses the image files of the request if the model has no vision support,
+// the images would otherwise reach the model as base64 text
+func CheckImageFiles(model string, files []string, mimes []string) error {
+	if globals.IsVisionModel(model) {
+		return nil
+	}
+
+	images := make([]string, 0)
+	for i := range files {
+		if i < len(mimes) && strings.HasPrefix(normalizeMime(mimes[i]), "image/") {
+			images = append(images, strconv.Itoa(i+1))
+		}
+	}
+
+	if len(images) > 0 {
+		return fmt.Errorf("model %s does not support images, remove the image files (file %s) or use a vision model", model, strings.Join(images, ", "))
+	}
+	return nil
+}
+
+// ExtractSourceFiles decodes the base64 files of the request and extracts their text,
+// images are kept as data urls for vision models (checked by CheckImageFiles)
+func ExtractSourceFiles(files []string, mimes []string) ([]SourceFile, error) {
+	result := make([]SourceFile, 0, len(files))
+	for i, data := range files {
+		if i >= len(mimes) {
+			break
+		}
+
+		mime := normalizeMime(mimes[i])
+		if strings.HasPrefix(mime, "image/") {
+			if err := checkFileSize(trimDataUrl(data)); err != nil {
+				return nil, fmt.Errorf("file %d: %s", i+1, err.Error())
+			}
+
+			if !strings.HasPrefix(data, "data:") {
+				data = fmt.Sprintf("data:%s;base64,%s", mime, data)
+			}
+			result = append(result, SourceFile{
+				Mime:    mime,
+				Content: data,
+				IsImage: true,
+			})
+			continue
+		}
+
+		raw, err := decodeFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("file %d: %s", i+1, err.Error())
+		}
+
+		content, err := ExtractText(mime, raw)
+		if err != nil {
+			return nil, fmt.Errorf("file %d: %s", i+1, err.Error())
+		}
+
+		if len(strings.TrimSpace(content)) == 0 {
+			return nil, fmt.Errorf("file %d: no text found in the file", i+1)
+		}
+
+		result = append(result, SourceFile{
+			Mime:    mime,
+			Content: content,
+		})
+	}
+
+	return result, nil
+}
+
+// ExtractText turns the document into clean text with page, section or slide markers
+func ExtractText(mime string, data []byte) (string, error) {
+	switch normalizeMime(mime) {
+	case MimePdf:
+		return extractPdf(data)
+	case MimeDocx:
+		return extractDocx(data)
+	case MimePptx:
+		return extractPptx(data)
+	case MimeHtml:
+		return extractHtml(data)
+	case MimeMarkdown, MimeText, "text/csv", "application/json":
+		return cleanText(string(data)), nil
+	}
+
+	if strings.HasPrefix(mime, "text/") {
+		return cleanText(string(data)), nil
+	}
+
+	return "", fmt.Errorf("unsupported file type %q", mime)
+}
+
+var blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+
+func cleanText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	return strings.TrimSpace(blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func pageMarker(name string, index int) string {
+	return fmt.Sprintf("\n\n--- %s %d ---\n", name, index)
+}
+
+func extractDocx(data []byte) (string, error) {
+	content, err := readDocxBody(data)
+	if err == errExtractLimit {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("invalid docx file: %s", err.Error())
+	}
+
+	var (
+		builder   strings.Builder
+		paragraph strings.Builder
+		heading   bool
+		pages     = 1
+		sections  = 0
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("invalid docx file: %s", err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				heading = false
+			case "pStyle":
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "val" && (strings.HasPrefix(attr.Value, "Heading") || attr.Value == "Title") {
+						heading = true
+					}
+				}
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br":
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "type" && attr.Value == "page" {
+						if pages++; pages > MaxFilePages {
+							return "", fmt.Errorf("file exceeds the page limit of %d", MaxFilePages)
+						}
+					}
+				}
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err == nil {
+					paragraph.WriteString(text)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "p" {
+				continue
+			}
+
+			text := strings.TrimSpace(paragraph.String())
+			if len(text) == 0 {
+				continue
+			}
+
+			if heading {
+				sections++
+				builder.WriteString(fmt.Sprintf("\n\n--- Section %d: %s ---\n", sections, text))
+			} else {
+				builder.WriteString(text)
+				builder.WriteString("\n")
+			}
+		}
+	}
+
+	return cleanText(builder.String()), nil
+}
+
+// readDocxBody reads the document body of the docx file from the archive, the decompressed
+// size is limited by the extract budget
+func readDocxBody(data []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range reader.File {
+		if file.Name != docx.DocumentXml {
+			continue
+		}
+
+		body, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(newExtractBudget().Reader(body))
+	}
+
+	return nil, fmt.Errorf("document body not found")
+}
+
+var slideRegex = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+func extractPptx(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid pptx file: %s", err.Error())
+	}
+
+	slides := map[int]*zip.File{}
+	for _, file := range reader.File {
+		if match := slideRegex.FindStringSubmatch(file.Name); match != nil {
+			index, _ := strconv.Atoi(match[1])
+			slides[index] = file
+		}
+	}
+
+	if len(slides) > MaxFilePages {
+		return "", fmt.Errorf("file exceeds the slide limit of %d", MaxFilePages)
+	}
+
+	indexes := make([]int, 0, len(slides))
+	for index := range slides {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var builder strings.Builder
+	budget := newExtractBudget()
+	for i, index := range indexes {
+		text, err := extractSlide(slides[index], budget)
+		if err == errExtractLimit {
+			return "", err
+		} else if err != nil {
+			return "", fmt.Errorf("invalid pptx file: %s", err.Error())
+		}
+
+		builder.WriteString(pageMarker("Slide", i+1))
+		builder.WriteString(text)
+	}
+
+	return cleanText(builder.String()), nil
+}
+
+func extractSlide(file *zip.File, budget *extractBudget) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var builder strings.Builder
+	decoder := xml.NewDecoder(budget.Reader(reader))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "t" {
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err == nil {
+					builder.WriteString(text)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" {
+				builder.WriteString("\n")
+			}
+		}
+	}
+
+	return builder.String(), nil
+}
+
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true,
+	"blockquote": true, "pre": true, "table": true, "ul": true, "ol": true, "hr": true,
+}
+
+func extractHtml(data []byte) (string, error) {
+	var (
+		builder strings.Builder
+		skip    int
+		heading bool
+	)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		token := tokenizer.Next()
+		switch token {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return "", fmt.Errorf("invalid html file: %s", err.Error())
+			}
+			return cleanText(builder.String()), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case tag == "script" || tag == "style" || tag == "noscript" || tag == "head":
+				// self-closing tags have no end tag to leave the skipped section, nor raw text to skip
+				if token == html.SelfClosingTagToken {
+					tokenizer.NextIsNotRawText()
+				} else {
+					skip++
+				}
+			case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6':
+				// headings are kept as markdown sections
+				builder.WriteString("\n\n" + strings.Repeat("#", int(tag[1]-'0')) + " ")
+				heading = true
+			case htmlBlockTags[tag]:
+				builder.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case tag == "script" || tag == "style" || tag == "noscript" || tag == "head":
+				if skip > 0 {
+					skip--
+				}
+			case heading && len(tag) == 2 && tag[0] == 'h':
+				builder.WriteString("\n")
+				heading = false
+			case htmlBlockTags[tag]:
+				builder.WriteString("\n")
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+
+			text := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			if len(text) > 0 {
+				builder.WriteString(text + " ")
+			}
+		}
+	}
+}
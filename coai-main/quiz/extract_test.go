@@ -0,0 +1,149 @@
+package quiz
+
+import (
+	"archive/zip"
+	"bytes"
+	"chat/globals"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func compressZlib(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func buildPdf(stream []byte) []byte {
+	return []byte(fmt.Sprintf(
+		"%%PDF-1.4\n"+
+			"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n"+
+			"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n"+
+			"3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n"+
+			"4 0 obj << /Length %d /Filter /FlateDecode >> stream\n%s\nendstream endobj\n"+
+			"%%%%EOF", len(stream), stream,
+	))
+}
+
+func buildZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, data := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestExtractPdf(t *testing.T) {
+	data := buildPdf(compressZlib(t, []byte("BT /F1 12 Tf (Photosynthesis converts light) Tj ET")))
+
+	text, err := ExtractText(MimePdf, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(text, "--- Page 1 ---") || !strings.Contains(text, "Photosynthesis converts light") {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
+
+func TestExtractLimit(t *testing.T) {
+	bomb := bytes.Repeat([]byte("0"), MaxExtractSize+1024)
+	paragraph := []byte("<w:document><w:body><w:p><w:r><w:t>" + string(bomb) + "</w:t></w:r></w:p></w:body></w:document>")
+	slide := []byte("<p:sld><a:p><a:r><a:t>" + string(bomb) + "</a:t></a:r></a:p></p:sld>")
+
+	cases := []struct {
+		name string
+		mime string
+		data []byte
+	}{
+		{"pdf", MimePdf, buildPdf(compressZlib(t, bomb))},
+		{"docx", MimeDocx, buildZip(t, map[string][]byte{"word/document.xml": paragraph})},
+		{"pptx", MimePptx, buildZip(t, map[string][]byte{"ppt/slides/slide1.xml": slide})},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if len(c.data) > MaxFileSize {
+				t.Fatalf("compressed file is larger than the file size limit: %d", len(c.data))
+			}
+
+			if _, err := ExtractText(c.mime, c.data); err != errExtractLimit {
+				t.Errorf("expected the decompressed size limit error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractImage(t *testing.T) {
+	raw := base64.StdEncoding.EncodeToString([]byte("fake png"))
+
+	files, err := ExtractSourceFiles([]string{raw, "data:image/png;base64," + raw}, []string{"image/png", "image/png"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, file := range files {
+		if file.Content != "data:image/png;base64,"+raw {
+			t.Errorf("unexpected data url: %q", file.Content)
+		}
+	}
+
+	large := base64.StdEncoding.EncodeToString(make([]byte, MaxFileSize+1024))
+	if _, err := ExtractSourceFiles([]string{large}, []string{"image/png"}); err == nil {
+		t.Errorf("expected the size limit error for large images")
+	}
+}
+
+func TestCheckImageFiles(t *testing.T) {
+	files := []string{"bm90ZXM=", "ZmFrZSBwbmc="}
+	mimes := []string{MimeText, "image/png"}
+
+	if err := CheckImageFiles(globals.GPT4O, files, mimes); err != nil {
+		t.Errorf("unexpected error of the vision model: %s", err)
+	}
+	if err := CheckImageFiles(globals.GPT3Turbo, files, mimes); err == nil || !strings.Contains(err.Error(), "file 2") {
+		t.Errorf("expected the image file of the text model to be refused, got %v", err)
+	}
+	if err := CheckImageFiles(globals.GPT3Turbo, files[:1], mimes[:1]); err != nil {
+		t.Errorf("unexpected error of the text files: %s", err)
+	}
+}
+
+func TestExtractHtml(t *testing.T) {
+	data := []byte(`<html><head><title>Cells</title></head><body><script src="app.js"/><style/>` +
+		`<h1>Cells</h1><p>The nucleus stores DNA.</p><script>var hidden = 1;</script><p>Ribosomes make proteins.</p></body></html>`)
+
+	text, err := ExtractText(MimeHtml, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, expected := range []string{"# Cells", "The nucleus stores DNA.", "Ribosomes make proteins."} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in the text: %q", expected, text)
+		}
+	}
+	if strings.Contains(text, "hidden") {
+		t.Errorf("the script is not skipped: %q", text)
+	}
+}
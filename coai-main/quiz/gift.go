@@ -0,0 +1,310 @@
+package quiz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the gift parser supports the question types which map to the quiz model: multiple choice,
+// true false, multiple answers (weighted choices), short answer (as fill_blank), matching
+// of ordered positions (as ordering) and essay (as short_answer with the general feedback as rubric)
+
+type giftAnswer struct {
+	Correct  bool
+	Weight   float64
+	Text     string
+	Feedback string
+}
+
+// giftIndex returns the index of the first unescaped occurrence of sub in text, -1 if not found
+func giftIndex(text string, sub string) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeGift(text string) string {
+	replacer := strings.NewReplacer(
+		`\\`, `\`, `\~`, `~`, `\=`, `=`, `\#`, `#`, `\{`, `{`, `\}`, `}`, `\:`, `:`, `\n`, "\n",
+	)
+	return strings.TrimSpace(replacer.Replace(text))
+}
+
+// splitGiftBlocks splits the gift text into question blocks separated by blank lines,
+// comments and category lines are dropped, the line of each block is returned with it
+func splitGiftBlocks(text string) ([]string, []int) {
+	var (
+		blocks  []string
+		lines   []int
+		current []string
+		start   int
+	)
+
+	flush := func() {
+		if block := strings.TrimSpace(strings.Join(current, "\n")); len(block) > 0 {
+			blocks = append(blocks, block)
+			lines = append(lines, start)
+		}
+		current = nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "$CATEGORY:") {
+			continue
+		}
+
+		if len(trimmed) == 0 {
+			flush()
+			continue
+		}
+
+		if len(current) == 0 {
+			start = i + 1
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return blocks, lines
+}
+
+// parseGift parses the gift question bank, the row of each question is its first line
+func parseGift(text string) []importRow {
+	blocks, lines := splitGiftBlocks(text)
+	rows := make([]importRow, 0, len(blocks))
+	for i, block := range blocks {
+		quiz, err := parseGiftQuestion(block)
+		row := importRow{Row: lines[i], Quiz: quiz}
+		if err != nil {
+			row.Err = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func parseGiftQuestion(block string) (Quiz, error) {
+	// drop the question title
+	if strings.HasPrefix(block, "::") {
+		if end := giftIndex(block[2:], "::"); end >= 0 {
+			block = block[end+4:]
+		}
+	}
+
+	// drop the text format of the question
+	if strings.HasPrefix(block, "[") {
+		if end := strings.Index(block, "]"); end >= 0 {
+			block = block[end+1:]
+		}
+	}
+
+	open := giftIndex(block, "{")
+	if open < 0 {
+		return Quiz{}, fmt.Errorf("answer section not found")
+	}
+	end := giftIndex(block[open:], "}")
+	if end < 0 {
+		return Quiz{}, fmt.Errorf("answer section is not closed")
+	}
+	end += open
+
+	prefix, section, suffix := block[:open], strings.TrimSpace(block[open+1:end]), block[end+1:]
+	quiz := Quiz{Question: unescapeGift(prefix + " " + suffix)}
+
+	if idx := giftIndex(section, "####"); idx >= 0 {
+		quiz.Description = unescapeGift(section[idx+4:])
+		section = strings.TrimSpace(section[:idx])
+	}
+
+	if strings.HasPrefix(section, "#") {
+		return quiz, fmt.Errorf("numerical questions are not supported")
+	}
+
+	// true false answers may have the feedbacks of the wrong and the right answer after #
+	value, feedbacks := section, []string{}
+	if idx := giftIndex(section, "#"); idx >= 0 {
+		value = strings.TrimSpace(section[:idx])
+		feedbacks = splitGiftFeedbacks(section[idx+1:])
+	}
+	switch strings.ToUpper(value) {
+	case "T", "TRUE":
+		quiz.Type, quiz.Answer = TrueFalse, "true"
+		quiz.Explanations = trueFalseExplanations(quiz.Answer, feedbacks)
+		return quiz, nil
+	case "F", "FALSE":
+		quiz.Type, quiz.Answer = TrueFalse, "false"
+		quiz.Explanations = trueFalseExplanations(quiz.Answer, feedbacks)
+		return quiz, nil
+	case "":
+		quiz.Type = ShortAnswer
+		quiz.Rubric = splitList(quiz.Description, ";")
+		return quiz, nil
+	}
+
+	answers := parseGiftAnswers(section)
+	if len(answers) == 0 {
+		return quiz, fmt.Errorf("no answers found")
+	}
+
+	if giftIndex(section, "->") >= 0 {
+		return parseGiftMatching(quiz, answers)
+	}
+
+	choice := false
+	for _, answer := range answers {
+		if !answer.Correct || answer.Weight != 0 {
+			choice = true
+		}
+	}
+
+	if !choice {
+		// every answer is correct, which is a short answer question
+		quiz.Type = FillBlank
+		quiz.Question = joinBlank(unescapeGift(prefix), unescapeGift(suffix))
+		for _, answer := range answers {
+			quiz.Answers = append(quiz.Answers, answer.Text)
+		}
+		return quiz, nil
+	}
+
+	quiz.Options = QuizOption{}
+	correct := make([]string, 0)
+	for i, answer := range answers {
+		key := optionKey(i)
+		quiz.Options[key] = answer.Text
+		if len(answer.Feedback) > 0 {
+			if quiz.Explanations == nil {
+				quiz.Explanations = QuizOption{}
+			}
+			quiz.Explanations[key] = answer.Feedback
+		}
+		if answer.Correct || answer.Weight > 0 {
+			correct = append(correct, key)
+		}
+	}
+
+	switch len(correct) {
+	case 0:
+		return quiz, fmt.Errorf("no correct answer")
+	case 1:
+		quiz.Type, quiz.Answer = MultipleChoice, correct[0]
+	default:
+		quiz.Type, quiz.Answers = MultiSelect, correct
+	}
+
+	return quiz, nil
+}
+
+func splitGiftFeedbacks(text string) []string {
+	feedbacks := make([]string, 0, 2)
+	for idx := giftIndex(text, "#"); idx >= 0; idx = giftIndex(text, "#") {
+		feedbacks = append(feedbacks, unescapeGift(text[:idx]))
+		text = text[idx+1:]
+	}
+	return append(feedbacks, unescapeGift(text))
+}
+
+// trueFalseExplanations maps the gift feedbacks of a true false question, the first one
+// is shown for the wrong answer and the second one for the right answer
+func trueFalseExplanations(answer string, feedbacks []string) QuizOption {
+	if len(feedbacks) == 0 {
+		return nil
+	}
+
+	wrong := "false"
+	if answer == "false" {
+		wrong = "true"
+	}
+
+	explanations := QuizOption{wrong: feedbacks[0]}
+	if len(feedbacks) > 1 {
+		explanations[answer] = feedbacks[1]
+	}
+	return explanations
+}
+
+// joinBlank joins the text around the answer section with the blank marker
+func joinBlank(prefix string, suffix string) string {
+	question := strings.TrimSpace(prefix + " " + BlankMarker)
+	if len(suffix) > 0 && !strings.ContainsAny(suffix[:1], ".,;:!?)") {
+		question += " "
+	}
+	return question + suffix
+}
+
+// parseGiftAnswers splits the answer section into the answers starting with = or ~,
+// the weights (%50%) and the answer feedbacks after # are parsed
+func parseGiftAnswers(section string) []giftAnswer {
+	answers := make([]giftAnswer, 0)
+	start := -1
+
+	push := func(end int) {
+		if start < 0 {
+			return
+		}
+
+		answer := giftAnswer{Correct: section[start] == '='}
+		text := section[start+1 : end]
+		if strings.HasPrefix(text, "%") {
+			if idx := strings.Index(text[1:], "%"); idx >= 0 {
+				answer.Weight, _ = strconv.ParseFloat(text[1:idx+1], 64)
+				text = text[idx+2:]
+			}
+		}
+
+		if idx := giftIndex(text, "#"); idx >= 0 {
+			answer.Feedback = unescapeGift(text[idx+1:])
+			text = text[:idx]
+		}
+
+		answer.Text = unescapeGift(text)
+		answers = append(answers, answer)
+	}
+
+	for i := 0; i < len(section); i++ {
+		switch section[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			push(i)
+			start = i
+		}
+	}
+	push(len(section))
+
+	return answers
+}
+
+// parseGiftMatching parses the matching pairs of ordered positions into an ordering question
+func parseGiftMatching(quiz Quiz, answers []giftAnswer) (Quiz, error) {
+	quiz.Type = Ordering
+	quiz.Options = QuizOption{}
+	sequence := make([]string, len(answers))
+
+	for i, answer := range answers {
+		idx := strings.LastIndex(answer.Text, "->")
+		if idx < 0 {
+			return quiz, fmt.Errorf("invalid matching pair %q", answer.Text)
+		}
+
+		position, err := strconv.Atoi(strings.TrimSpace(answer.Text[idx+2:]))
+		if err != nil || position < 1 || position > len(answers) {
+			return quiz, fmt.Errorf("matching questions are only supported as ordering questions")
+		}
+
+		quiz.Options[optionKey(i)] = strings.TrimSpace(answer.Text[:idx])
+		sequence[position-1] = optionKey(i)
+	}
+
+	quiz.Answers = sequence
+	return quiz, nil
+}
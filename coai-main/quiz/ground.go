@@ -0,0 +1,210 @@
+package quiz
+
+import (
+	"chat/addition/web"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the grounding step replaces the model-written resource links with real search results: each question
+// is searched on the searxng endpoint, the model picks the relevant results of each question and every
+// picked link is checked, the unreachable links are dropped. without a search endpoint (or if the
+// search fails) the model-written links are kept and only checked.
+
+const (
+	groundSearchResults  = 5 // search results offered to the model per question
+	groundMaxResources   = 3 // resources kept per question
+	groundMaxConcurrency = 4 // concurrent searches and link checks
+)
+
+// getModelProxy returns the proxy config of the channel with the highest priority of the model
+func getModelProxy(model string) []globals.ProxyConfig {
+	seq := channel.ConduitInstance.HitSequence(model)
+	if len(seq) == 0 {
+		return nil
+	}
+	return []globals.ProxyConfig{seq[0].GetProxy()}
+}
+
+func buildGroundQuery(form QuizGenerationRequest, q Quiz) string {
+	query := utils.Extract(q.Question, 100, "")
+	if len(form.Topic) > 0 {
+		query = fmt.Sprintf("%s %s", form.Topic, query)
+	}
+	return query
+}
+
+// searchQuestions searches the resources of each question, the questions which fail are nil
+func searchQuestions(form QuizGenerationRequest, quizzes []Quiz) [][]web.SearchResult {
+	results := make([][]web.SearchResult, len(quizzes))
+	semaphore := make(chan struct{}, groundMaxConcurrency)
+
+	var wg sync.WaitGroup
+	for i, q := range quizzes {
+		wg.Add(1)
+		go func(i int, q Quiz) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if res, err := web.GenerateSearchResults(buildGroundQuery(form, q), groundSearchResults); err == nil {
+				results[i] = res
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func buildGroundPrompt(quizzes []Quiz, results [][]web.SearchResult) string {
+	var builder strings.Builder
+	builder.WriteString("For each quiz question below, pick the search results which are good learning resources for the question. ")
+	builder.WriteString(fmt.Sprintf("Pick at most %d results per question and only pick from the listed results. ", groundMaxResources))
+	builder.WriteString("Respond only with a JSON object mapping the question id to the array of the picked result numbers, ")
+	builder.WriteString("for example {\"1\": [2, 1], \"2\": []}, without any additional text.\n")
+
+	for i, q := range quizzes {
+		if len(results[i]) == 0 {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("\nQuestion %s: %s\n", q.ID, q.Question))
+		for j, result := range results[i] {
+			builder.WriteString(fmt.Sprintf("[%d] %s (%s): %s\n", j+1, result.Title, result.Url, result.Content))
+		}
+	}
+
+	return builder.String()
+}
+
+// pickResources asks the model to pick the results of each question, the first results
+// are picked if the model output cannot be parsed
+func pickResources(c *gin.Context, user *auth.User, form QuizGenerationRequest, quizzes []Quiz, results [][]web.SearchResult) ([][]QuizResource, float32) {
+	buffer, err := requestQuiz(c, user, form, []globals.Message{
+		{Role: globals.User, Content: buildGroundPrompt(quizzes, results)},
+	}, nil)
+
+	var picks map[string][]int
+	if err == nil {
+		response := trimResponse(buffer.Read())
+		if idx := strings.Index(response, "{"); idx >= 0 {
+			response = response[idx:]
+		}
+		picks, err = utils.UnmarshalString[map[string][]int](response)
+	}
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[quiz] failed to pick the grounded resources, fallback to the top results: %s", err.Error()))
+	}
+
+	resources := make([][]QuizResource, len(quizzes))
+	for i, q := range quizzes {
+		indexes, ok := picks[q.ID]
+		if !ok {
+			indexes = []int{}
+			for j := 0; j < len(results[i]) && j < groundMaxResources; j++ {
+				indexes = append(indexes, j+1)
+			}
+		}
+
+		for _, idx := range indexes {
+			if idx < 1 || idx > len(results[i]) || len(resources[i]) >= groundMaxResources {
+				continue
+			}
+
+			result := results[i][idx-1]
+			resources[i] = append(resources[i], QuizResource{Title: result.Title, Link: result.Url})
+		}
+	}
+
+	return resources, buffer.GetQuota()
+}
+
+// checkResources drops the unreachable links, each link is only checked once
+func checkResources(resources [][]QuizResource, proxy []globals.ProxyConfig) [][]QuizResource {
+	links := map[string]bool{}
+	for _, items := range resources {
+		for _, item := range items {
+			links[item.Link] = false
+		}
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	semaphore := make(chan struct{}, groundMaxConcurrency)
+	for link := range links {
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			alive := utils.CheckLink(link, proxy...)
+			mutex.Lock()
+			links[link] = alive
+			mutex.Unlock()
+		}(link)
+	}
+	wg.Wait()
+
+	checked := make([][]QuizResource, len(resources))
+	for i, items := range resources {
+		checked[i] = make([]QuizResource, 0, len(items))
+		for _, item := range items {
+			if links[item.Link] {
+				checked[i] = append(checked[i], item)
+			} else {
+				globals.Debug(fmt.Sprintf("[quiz] drop unreachable resource link: %s", item.Link))
+			}
+		}
+	}
+	return checked
+}
+
+// groundResources grounds the resource links of the questions and returns the quota of the pick request
+func groundResources(c *gin.Context, user *auth.User, form QuizGenerationRequest, quizzes []Quiz) ([]Quiz, float32) {
+	resources := make([][]QuizResource, len(quizzes))
+	for i, q := range quizzes {
+		resources[i] = q.Resources
+	}
+
+	var quota float32
+	if len(globals.SearchEndpoint) > 0 {
+		results := searchQuestions(form, quizzes)
+
+		found := false
+		for _, res := range results {
+			if len(res) > 0 {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			var picked [][]QuizResource
+			picked, quota = pickResources(c, user, form, quizzes, results)
+			for i := range quizzes {
+				// keep the model-written links of the questions without search results
+				if len(results[i]) > 0 {
+					resources[i] = picked[i]
+				}
+			}
+		}
+	}
+
+	resources = checkResources(resources, getModelProxy(form.Model))
+	for i := range quizzes {
+		quizzes[i].Resources = resources[i]
+	}
+
+	return quizzes, quota
+}
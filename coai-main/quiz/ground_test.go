@@ -0,0 +1,30 @@
+package quiz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckResources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// the local server and the metadata address are private, their links are dropped
+	resources := [][]QuizResource{
+		{{Title: "local", Link: server.URL + "/page"}, {Title: "metadata", Link: "http://169.254.169.254/latest/meta-data/"}},
+		{{Title: "file", Link: "file:///etc/passwd"}},
+	}
+
+	checked := checkResources(resources, nil)
+	if len(checked) != 2 {
+		t.Fatalf("expected the resources of 2 questions, got %d", len(checked))
+	}
+	for i, items := range checked {
+		if len(items) != 0 {
+			t.Errorf("expected the links of question %d to be dropped, got %+v", i+1, items)
+		}
+	}
+}
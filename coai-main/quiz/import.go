@@ -0,0 +1,573 @@
+package quiz
+
+import (
+	"bytes"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ImportGift   = "gift"
+	ImportMoodle = "moodle"
+	ImportCsv    = "csv"
+	ImportJson   = "json"
+)
+
+const (
+	importModel        = "import" // model name of the imported quizzes in the library
+	maxImportQuestions = 500
+)
+
+var ImportFormats = []string{ImportGift, ImportMoodle, ImportCsv, ImportJson}
+
+// ImportRowError represents a question of the question bank which cannot be imported,
+// row is the line of csv files and gift questions, or the question index of json and xml files
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// ImportDuplicate represents a skipped question which already exists in the question bank,
+// quiz id is 0 if the question is repeated in the imported file itself
+type ImportDuplicate struct {
+	Row      int    `json:"row"`
+	Question string `json:"question"`
+	QuizId   int64  `json:"quiz_id"`
+	QuizName string `json:"quiz_name"`
+}
+
+type ImportResult struct {
+	Id         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Format     string            `json:"format"`
+	Total      int               `json:"total"`
+	Imported   int               `json:"imported"`
+	Duplicates []ImportDuplicate `json:"duplicates"`
+	Errors     []ImportRowError  `json:"errors"`
+}
+
+// importRow is a parsed question of the question bank, err is set if the row cannot be parsed
+type importRow struct {
+	Row  int
+	Quiz Quiz
+	Err  string
+}
+
+// bankQuestion is a question of the user's existing question bank
+type bankQuestion struct {
+	QuizId   int64
+	QuizName string
+	Quiz     Quiz
+}
+
+// DetectImportFormat detects the format of the question bank by the file extension, then by the content
+func DetectImportFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gift":
+		return ImportGift
+	case ".xml":
+		return ImportMoodle
+	case ".csv", ".tsv":
+		return ImportCsv
+	case ".json":
+		return ImportJson
+	}
+
+	content := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(content, []byte("<")):
+		return ImportMoodle
+	case bytes.HasPrefix(content, []byte("[")), bytes.HasPrefix(content, []byte("{")):
+		return ImportJson
+	case bytes.Contains(content, []byte("{")) && bytes.Contains(content, []byte("}")):
+		return ImportGift
+	}
+
+	return ImportCsv
+}
+
+// ParseQuestionBank parses the question bank into questions, the rows which cannot be
+// parsed or fail the validation are returned as errors
+func ParseQuestionBank(format string, data []byte) ([]importRow, []ImportRowError, error) {
+	var (
+		rows []importRow
+		err  error
+	)
+
+	switch format {
+	case ImportGift:
+		rows = parseGift(string(data))
+	case ImportMoodle:
+		rows, err = parseMoodleXml(data)
+	case ImportCsv:
+		rows, err = parseCsv(data)
+	case ImportJson:
+		rows, err = parseJson(data)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import format %q", format)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valid := make([]importRow, 0, len(rows))
+	errors := make([]ImportRowError, 0)
+	for _, row := range rows {
+		if len(row.Err) == 0 && len(valid) >= maxImportQuestions {
+			row.Err = fmt.Sprintf("exceeds the limit of %d questions", maxImportQuestions)
+		}
+
+		if len(row.Err) > 0 {
+			errors = append(errors, ImportRowError{Row: row.Row, Message: row.Err})
+			continue
+		}
+
+		quiz := fitQuestionType(row.Quiz)
+		if problems := quiz.Validate(); len(problems) > 0 {
+			errors = append(errors, ImportRowError{Row: row.Row, Message: strings.Join(problems, "; ")})
+			continue
+		}
+
+		row.Quiz = quiz.Normalize()
+		valid = append(valid, row)
+	}
+
+	return valid, errors, nil
+}
+
+// fitQuestionType normalizes the imported question, single-answer choice questions keep
+// the multiple_choice type whatever the number of their options is
+func fitQuestionType(quiz Quiz) Quiz {
+	quiz.Type = normalizeKey(quiz.Type)
+	quiz.Options = quiz.Options.Normalize()
+	quiz.Question = strings.TrimSpace(quiz.Question)
+	quiz.Description = strings.TrimSpace(quiz.Description)
+	if quiz.GetType() == MultipleChoice {
+		quiz.Answer = normalizeKey(quiz.Answer)
+	}
+
+	return quiz
+}
+
+// optionKey returns the option key of the n-th option (a, b, c...)
+func optionKey(index int) string {
+	return string(rune('a' + index))
+}
+
+// resolveAnswerKey resolves the answer to the option key, the answer can be the key or the option text
+func resolveAnswerKey(options QuizOption, answer string) string {
+	answer = strings.TrimSpace(answer)
+	if _, ok := options[normalizeKey(answer)]; ok {
+		return normalizeKey(answer)
+	}
+
+	for key, option := range options {
+		if normalizeText(option) == normalizeText(answer) {
+			return key
+		}
+	}
+
+	return answer
+}
+
+func splitList(value string, sep string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// loadQuestionBank loads the questions of all quizzes of the user
+func loadQuestionBank(db *sql.DB, userId int64) []bankQuestion {
+	bank := make([]bankQuestion, 0)
+	rows, err := globals.QueryDb(db, "SELECT id, quiz_name, data FROM quiz WHERE user_id = ?", userId)
+	if err != nil {
+		return bank
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+			data string
+		)
+		if err := rows.Scan(&id, &name, &data); err != nil {
+			continue
+		}
+
+		quizzes, err := utils.Unmarshal[[]Quiz]([]byte(data))
+		if err != nil {
+			continue
+		}
+
+		for _, quiz := range quizzes {
+			bank = append(bank, bankQuestion{QuizId: id, QuizName: name, Quiz: quiz})
+		}
+	}
+
+	return bank
+}
+
+func findBankDuplicate(bank []bankQuestion, quiz Quiz) *bankQuestion {
+	for i := range bank {
+		if isDuplicateQuiz(bank[i].Quiz, quiz) {
+			return &bank[i]
+		}
+	}
+	return nil
+}
+
+// ImportQuiz parses the question bank and saves the valid questions which are not in
+// the user's question bank yet as a new quiz of the library
+func ImportQuiz(db *sql.DB, userId int64, name string, format string, data []byte) (*ImportResult, error) {
+	rows, errors, err := ParseQuestionBank(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		Format:     format,
+		Total:      len(rows) + len(errors),
+		Duplicates: make([]ImportDuplicate, 0),
+		Errors:     errors,
+	}
+
+	bank := loadQuestionBank(db, userId)
+	quizzes := make([]Quiz, 0, len(rows))
+	for _, row := range rows {
+		if duplicate := findBankDuplicate(bank, row.Quiz); duplicate != nil {
+			result.Duplicates = append(result.Duplicates, ImportDuplicate{
+				Row:      row.Row,
+				Question: row.Quiz.Question,
+				QuizId:   duplicate.QuizId,
+				QuizName: duplicate.QuizName,
+			})
+			continue
+		}
+
+		if hasDuplicateQuiz(quizzes, row.Quiz) {
+			result.Duplicates = append(result.Duplicates, ImportDuplicate{Row: row.Row, Question: row.Quiz.Question})
+			continue
+		}
+
+		quizzes = append(quizzes, row.Quiz)
+	}
+
+	if len(quizzes) == 0 {
+		return result, nil
+	}
+
+	form := QuizGenerationRequest{
+		Model: importModel,
+		Topic: strings.TrimSpace(name),
+		Notes: string(data),
+	}
+
+	id, err := SaveQuiz(db, userId, form, renumberQuizzes(quizzes))
+	if err != nil {
+		return nil, err
+	}
+
+	result.Id = id
+	result.Name = getQuizName(form, quizzes)
+	result.Imported = len(quizzes)
+	return result, nil
+}
+
+// parseJson parses a json array of questions in the quiz model, the saved quiz and
+// the objects wrapping the array in a "questions" or "data" field are accepted too
+func parseJson(data []byte) ([]importRow, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapper struct {
+			Questions []json.RawMessage `json:"questions"`
+			Data      []json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid json file: %s", err.Error())
+		}
+
+		items = wrapper.Questions
+		if len(items) == 0 {
+			items = wrapper.Data
+		}
+	}
+
+	rows := make([]importRow, 0, len(items))
+	for i, item := range items {
+		row := importRow{Row: i + 1}
+		if err := json.Unmarshal(item, &row.Quiz); err != nil {
+			row.Err = fmt.Sprintf("invalid question: %s", err.Error())
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// csvColumns are the aliases of the recognized csv header columns
+var csvColumns = map[string][]string{
+	"type":        {"type", "question_type"},
+	"question":    {"question", "text", "prompt"},
+	"answer":      {"answer", "answers", "correct", "correct_answer"},
+	"description": {"description", "explanation", "feedback"},
+	"rubric":      {"rubric"},
+}
+
+// parseCsv parses the csv (or tsv) question bank with a header row, options are the
+// columns a to f (or option_a to option_f). multiple answers and ordering sequences are
+// comma separated keys, accepted answers of fill_blank and rubric points are separated by "|"
+func parseCsv(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if line, _, _ := bytes.Cut(data, []byte("\n")); bytes.Contains(line, []byte("\t")) {
+		reader.Comma = '\t'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv file: %s", err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ReplaceAll(normalizeKey(name), " ", "_")
+		for column, aliases := range csvColumns {
+			if utils.Contains(name, aliases) {
+				columns[column] = i
+			}
+		}
+
+		if key := strings.TrimPrefix(name, "option_"); len(key) == 1 && key[0] >= 'a' && key[0] <= 'f' {
+			columns["option_"+key] = i
+		}
+	}
+
+	if _, ok := columns["question"]; !ok {
+		return nil, fmt.Errorf("csv header must contain a question column")
+	}
+
+	rows := make([]importRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{Row: line}
+		if err != nil {
+			row.Err = err.Error()
+			rows = append(rows, row)
+			continue
+		}
+
+		get := func(column string) string {
+			if idx, ok := columns[column]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+
+		if len(strings.Join(record, "")) == 0 {
+			continue
+		}
+
+		row.Quiz = buildImportQuiz(get("type"), get("question"), get("description"), get("answer"), get("rubric"), func(key string) string {
+			return get("option_" + key)
+		})
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// buildImportQuiz builds the question from the flat fields of a csv row
+func buildImportQuiz(t string, question string, description string, answer string, rubric string, option func(key string) string) Quiz {
+	quiz := Quiz{
+		Type:        normalizeKey(t),
+		Question:    question,
+		Description: description,
+		Options:     QuizOption{},
+	}
+
+	for i := 0; i < 6; i++ {
+		if value := option(optionKey(i)); len(value) > 0 {
+			quiz.Options[optionKey(i)] = value
+		}
+	}
+
+	if len(quiz.Type) == 0 {
+		switch {
+		case len(quiz.Options) == 0 && utils.Contains(normalizeKey(answer), []string{"true", "false"}):
+			quiz.Type = TrueFalse
+		case len(quiz.Options) == 0 && len(rubric) > 0:
+			quiz.Type = ShortAnswer
+		case len(quiz.Options) == 0:
+			quiz.Type = FillBlank
+		case strings.Contains(answer, ","):
+			quiz.Type = MultiSelect
+		default:
+			quiz.Type = MultipleChoice
+		}
+	}
+
+	switch quiz.Type {
+	case MultiSelect, Ordering:
+		quiz.Answers = utils.Each(splitList(answer, ","), func(item string) string {
+			return resolveAnswerKey(quiz.Options, item)
+		})
+	case FillBlank:
+		quiz.Answers = splitList(answer, "|")
+		if !strings.Contains(quiz.Question, BlankMarker) {
+			quiz.Question = fmt.Sprintf("%s %s", quiz.Question, BlankMarker)
+		}
+	case ShortAnswer:
+		quiz.Rubric = splitList(rubric, "|")
+		if len(quiz.Rubric) == 0 {
+			quiz.Rubric = splitList(answer, "|")
+		}
+	default:
+		quiz.Answer = resolveAnswerKey(quiz.Options, answer)
+	}
+
+	return quiz
+}
+
+// htmlText converts the html text of moodle questions to plain text
+func htmlText(text moodleText) string {
+	if text.Format != "html" && text.Format != "moodle_auto_format" {
+		return strings.TrimSpace(text.Text)
+	}
+
+	plain, err := extractHtml([]byte(text.Text))
+	if err != nil {
+		return strings.TrimSpace(text.Text)
+	}
+	return plain
+}
+
+// parseMoodleXml parses the moodle xml question bank, category and description entries are skipped
+func parseMoodleXml(data []byte) ([]importRow, error) {
+	var bank moodleQuiz
+	if err := xml.Unmarshal(data, &bank); err != nil {
+		return nil, fmt.Errorf("invalid moodle xml file: %s", err.Error())
+	}
+
+	rows := make([]importRow, 0, len(bank.Questions))
+	index := 0
+	for _, question := range bank.Questions {
+		if question.Type == "category" || question.Type == "description" {
+			continue
+		}
+
+		index++
+		row := importRow{Row: index}
+		quiz := Quiz{
+			Question:    htmlText(question.QuestionText),
+			Description: htmlText(question.GeneralFeedback),
+		}
+
+		switch question.Type {
+		case "multichoice":
+			quiz.Options = QuizOption{}
+			correct := make([]string, 0)
+			for i, answer := range question.Answers {
+				quiz.Options[optionKey(i)] = htmlText(moodleText{Format: answer.Format, Text: answer.Text})
+				if feedback := htmlText(answer.Feedback); len(feedback) > 0 {
+					if quiz.Explanations == nil {
+						quiz.Explanations = QuizOption{}
+					}
+					quiz.Explanations[optionKey(i)] = feedback
+				}
+				if fraction := parseFraction(answer.Fraction); fraction > 0 {
+					correct = append(correct, optionKey(i))
+				}
+			}
+
+			if question.Single == "false" || len(correct) > 1 {
+				quiz.Type = MultiSelect
+				quiz.Answers = correct
+			} else if len(correct) == 1 {
+				quiz.Type = MultipleChoice
+				quiz.Answer = correct[0]
+			} else {
+				row.Err = "no correct answer"
+			}
+		case "truefalse":
+			quiz.Type = TrueFalse
+			for _, answer := range question.Answers {
+				value := normalizeKey(htmlText(moodleText{Format: answer.Format, Text: answer.Text}))
+				if parseFraction(answer.Fraction) > 0 {
+					quiz.Answer = value
+				}
+				if feedback := htmlText(answer.Feedback); len(feedback) > 0 {
+					if quiz.Explanations == nil {
+						quiz.Explanations = QuizOption{}
+					}
+					quiz.Explanations[value] = feedback
+				}
+			}
+		case "shortanswer":
+			quiz.Type = FillBlank
+			for _, answer := range question.Answers {
+				if parseFraction(answer.Fraction) > 0 {
+					quiz.Answers = append(quiz.Answers, strings.TrimSpace(answer.Text))
+				}
+			}
+			if !strings.Contains(quiz.Question, BlankMarker) {
+				quiz.Question = fmt.Sprintf("%s %s", quiz.Question, BlankMarker)
+			}
+		case "matching":
+			// only the matching questions of ordered positions (as exported) are ordering questions
+			quiz.Type = Ordering
+			quiz.Options = QuizOption{}
+			sequence := make([]string, len(question.SubQuestions))
+			for i, sub := range question.SubQuestions {
+				quiz.Options[optionKey(i)] = htmlText(moodleText{Format: sub.Format, Text: sub.Text})
+
+				var position int
+				if _, err := fmt.Sscanf(strings.TrimSpace(sub.Answer.Text), "%d", &position); err != nil || position < 1 || position > len(sequence) {
+					row.Err = "matching questions are only supported as ordering questions"
+					break
+				}
+				sequence[position-1] = optionKey(i)
+			}
+			quiz.Answers = sequence
+		case "essay":
+			quiz.Type = ShortAnswer
+			if question.GraderInfo != nil {
+				quiz.Rubric = splitList(htmlText(*question.GraderInfo), "\n")
+			}
+		default:
+			row.Err = fmt.Sprintf("question type %q is not supported", question.Type)
+		}
+
+		row.Quiz = quiz
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseFraction(value string) float64 {
+	var fraction float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(value), "%g", &fraction); err != nil {
+		return 0
+	}
+	return fraction
+}
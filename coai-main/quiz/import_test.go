@@ -0,0 +1,138 @@
+package quiz
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newRoundTripQuiz() *SavedQuiz {
+	return &SavedQuiz{
+		Id:   1,
+		Name: "Cells",
+		Data: []Quiz{
+			{
+				ID:          "1",
+				Type:        MultipleChoice,
+				Question:    "Which organelle produces ATP?",
+				Description: "Mitochondria are the powerhouse of the cell.",
+				Options:     QuizOption{"a": "Mitochondrion", "b": "Ribosome", "c": "Nucleus", "d": "Vacuole"},
+				Answer:      "a",
+			},
+			{
+				ID:       "2",
+				Type:     MultipleChoice,
+				Question: "Is a virus a cell?",
+				Options:  QuizOption{"a": "Yes", "b": "No"},
+				Answer:   "b",
+			},
+			{
+				ID:       "3",
+				Type:     TrueFalse,
+				Question: "Plant cells have a cell wall.",
+				Answer:   "true",
+			},
+			{
+				ID:       "4",
+				Type:     MultiSelect,
+				Question: "Which of these are found in plant cells?",
+				Options:  QuizOption{"a": "Chloroplast", "b": "Cell wall", "c": "Centriole"},
+				Answers:  []string{"a", "b"},
+			},
+		},
+	}
+}
+
+func TestImportChoiceOptions(t *testing.T) {
+	gift := strings.Join([]string{
+		"::Q1:: Is water wet? {=Yes ~No}",
+		"",
+		"::Q2:: Pick the prime number. {~4 ~6 ~8 ~9 ~10 =11 ~12}",
+	}, "\n")
+
+	rows, errors, err := ParseQuestionBank(ImportGift, []byte(gift))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(errors) > 0 {
+		t.Fatalf("unexpected row errors: %+v", errors)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 questions, got %d", len(rows))
+	}
+
+	for _, row := range rows {
+		if row.Quiz.GetType() != MultipleChoice {
+			t.Errorf("question %q is imported as %s", row.Quiz.Question, row.Quiz.GetType())
+		}
+		if option := row.Quiz.Options[row.Quiz.Answer]; option != "Yes" && option != "11" {
+			t.Errorf("unexpected answer %q of question %q", option, row.Quiz.Question)
+		}
+	}
+}
+
+func TestImportExportRoundTrip(t *testing.T) {
+	quiz := newRoundTripQuiz()
+
+	moodle, err := ToMoodleXml(quiz)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		format string
+		data   string
+	}{
+		{ImportGift, ToGift(quiz)},
+		{ImportMoodle, moodle},
+	}
+
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			rows, errors, err := ParseQuestionBank(c.format, []byte(c.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(errors) > 0 {
+				t.Fatalf("unexpected row errors: %+v\n%s", errors, c.data)
+			}
+			if len(rows) != len(quiz.Data) {
+				t.Fatalf("expected %d questions, got %d", len(quiz.Data), len(rows))
+			}
+
+			for i, row := range rows {
+				expected, actual := quiz.Data[i], row.Quiz
+				if actual.GetType() != expected.GetType() || actual.Question != expected.Question {
+					t.Errorf("question %d: expected %s %q, got %s %q", i+1, expected.GetType(), expected.Question, actual.GetType(), actual.Question)
+					continue
+				}
+
+				if expected.GetType() == TrueFalse {
+					if actual.Answer != expected.Answer {
+						t.Errorf("question %d: expected answer %q, got %q", i+1, expected.Answer, actual.Answer)
+					}
+					continue
+				}
+
+				if !reflect.DeepEqual(getCorrectOptions(actual), getCorrectOptions(expected)) {
+					t.Errorf("question %d: expected correct options %v, got %v", i+1, getCorrectOptions(expected), getCorrectOptions(actual))
+				}
+				if len(actual.Options) != len(expected.Options) {
+					t.Errorf("question %d: expected %d options, got %d", i+1, len(expected.Options), len(actual.Options))
+				}
+			}
+		})
+	}
+}
+
+// getCorrectOptions returns the text of the correct options, the keys may be reordered by the formats
+func getCorrectOptions(quiz Quiz) map[string]bool {
+	correct := map[string]bool{}
+	if quiz.GetType() == MultipleChoice {
+		correct[quiz.Options[quiz.Answer]] = true
+	}
+	for _, key := range quiz.Answers {
+		correct[quiz.Options[normalizeKey(key)]] = true
+	}
+	return correct
+}
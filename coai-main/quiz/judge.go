@@ -0,0 +1,247 @@
+package quiz
+
+import (
+	"chat/admin"
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the judge model answers the generated questions without the answer keys, a question is disputed
+// if the judge answer does not match the key. disputed questions are flagged, rewritten by the
+// generator or dropped by the policy. short answer questions are not judged as they are graded by rubric.
+
+// judgeAnswer is the answer of the judge model to a single question
+type judgeAnswer struct {
+	ID         string      `json:"id"`
+	Answer     interface{} `json:"answer"` // a key, a comma separated (or array of) keys or a word
+	Confidence float64     `json:"confidence"`
+}
+
+// judgeQuestion is the question shown to the judge model without the answer key
+type judgeQuestion struct {
+	ID       string     `json:"id"`
+	Type     string     `json:"type"`
+	Question string     `json:"question"`
+	Options  QuizOption `json:"options,omitempty"`
+}
+
+// IsJudgeEnabled checks if the answer keys of the model are verified by the judge model
+func IsJudgeEnabled(model string) bool {
+	return globals.QuizJudgePolicy != globals.QuizJudgeOff && len(globals.QuizJudgeModel) > 0 && model != importModel
+}
+
+func (a judgeAnswer) Selection() string {
+	switch answer := a.Answer.(type) {
+	case string:
+		return answer
+	case []interface{}:
+		return strings.Join(utils.Each(answer, func(item interface{}) string {
+			return utils.ToString(item)
+		}), ",")
+	case nil:
+		return ""
+	}
+	return utils.ToString(a.Answer)
+}
+
+func (a judgeAnswer) GetConfidence() float64 {
+	if a.Confidence <= 0 || a.Confidence > 1 {
+		// the judge did not report a valid confidence
+		return 0.5
+	}
+	return a.Confidence
+}
+
+func buildJudgePrompt(quizzes []Quiz) string {
+	questions := make([]judgeQuestion, 0, len(quizzes))
+	for _, q := range quizzes {
+		questions = append(questions, judgeQuestion{ID: q.ID, Type: q.GetType(), Question: q.Question, Options: q.Options})
+	}
+
+	return fmt.Sprintf(
+		"Answer each of the following quiz questions independently.\n%s\n\n"+
+			"Answer formats by type: multiple_choice is the option key (e.g. \"b\"), true_false is \"true\" or \"false\", "+
+			"multi_select is the comma separated keys of all correct options (e.g. \"a,c\"), ordering is the comma separated "+
+			"keys in the correct order and fill_blank is the word or phrase of the blank (%s).\n"+
+			"Respond only with a JSON array of objects with the fields \"id\", \"answer\" and \"confidence\" "+
+			"(a number between 0 and 1 of how sure you are), without any additional text.",
+		utils.Marshal(questions), BlankMarker,
+	)
+}
+
+// decodeJudgeAnswers decodes the judge output into the answers by question id
+func decodeJudgeAnswers(response string) (map[string]judgeAnswer, error) {
+	answers, err := utils.UnmarshalString[[]judgeAnswer](trimResponse(response))
+	if err != nil {
+		return nil, fmt.Errorf("judge response is not a valid JSON array: %s", err.Error())
+	}
+
+	result := map[string]judgeAnswer{}
+	for _, answer := range answers {
+		result[strings.TrimSpace(answer.ID)] = answer
+	}
+	return result, nil
+}
+
+// judgeQuizzes asks the judge model to answer the questions and returns the answers by question id
+func judgeQuizzes(c *gin.Context, user *auth.User, form QuizGenerationRequest, quizzes []Quiz) (map[string]judgeAnswer, float32, error) {
+	candidates := make([]Quiz, 0, len(quizzes))
+	for _, q := range quizzes {
+		if q.GetType() != ShortAnswer {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) == 0 {
+		return map[string]judgeAnswer{}, 0, nil
+	}
+
+	judge := form
+	judge.Model = globals.QuizJudgeModel
+	buffer, err := requestQuiz(c, user, judge, []globals.Message{
+		{Role: globals.User, Content: buildJudgePrompt(candidates)},
+	}, nil)
+	if err != nil {
+		return nil, buffer.GetQuota(), err
+	}
+
+	answers, err := decodeJudgeAnswers(buffer.Read())
+	return answers, buffer.GetQuota(), err
+}
+
+// applyJudgement sets the confidence of the judged questions and returns the ids of the disputed questions,
+// the confidence is the judge confidence if the judge agrees with the key and its complement otherwise
+func applyJudgement(quizzes []Quiz, answers map[string]judgeAnswer) (int, []string) {
+	checked := 0
+	disputed := make([]string, 0)
+	for i, q := range quizzes {
+		answer, ok := answers[q.ID]
+		if !ok || q.GetType() == ShortAnswer {
+			continue
+		}
+
+		checked++
+		confidence := answer.GetConfidence()
+		selection := answer.Selection()
+		if q.IsCorrect(selection) {
+			quizzes[i].Confidence = &confidence
+			quizzes[i].Flagged, quizzes[i].JudgeAnswer = false, ""
+			continue
+		}
+
+		confidence = math.Round((1-confidence)*100) / 100
+		quizzes[i].Confidence = &confidence
+		quizzes[i].Flagged, quizzes[i].JudgeAnswer = true, selection
+		disputed = append(disputed, q.ID)
+	}
+
+	return checked, disputed
+}
+
+func buildRegeneratePrompt(disputed []Quiz, types []string) string {
+	lines := make([]string, 0, len(disputed))
+	for _, q := range disputed {
+		lines = append(lines, fmt.Sprintf("- question %s: the answer key is %q, but an independent reviewer answered %q", q.ID, q.AnswerKey(), q.JudgeAnswer))
+	}
+
+	for i := range disputed {
+		disputed[i].Confidence, disputed[i].Flagged, disputed[i].JudgeAnswer = nil, false, ""
+	}
+
+	return fmt.Sprintf(
+		"The answer keys of the following quiz questions are disputed:\n%s\n\n%s\n\n"+
+			"Rewrite each question so that it has exactly one unambiguous correct answer and the answer key is correct. "+
+			"Keep the id and the type of each question.\n%s\n"+
+			"Return only the JSON array without any additional text or markdown formatting.",
+		strings.Join(lines, "\n"), utils.Marshal(disputed), buildTypesPrompt(types),
+	)
+}
+
+// regenerateQuizzes asks the generator model to rewrite the disputed questions, the rewritten
+// questions are returned by id and the questions which are not rewritten validly are missing
+func regenerateQuizzes(c *gin.Context, user *auth.User, form QuizGenerationRequest, disputed []Quiz) (map[string]Quiz, float32) {
+	response, buffer, err := requestStructuredQuiz(c, user, form, []globals.Message{
+		{Role: globals.User, Content: buildRegeneratePrompt(disputed, form.GetQuestionTypes())},
+	}, nil)
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[quiz] failed to regenerate the disputed questions: %s", err.Error()))
+		return map[string]Quiz{}, buffer.GetQuota()
+	}
+
+	valid, _ := ValidateQuizResponse(response, form.GetQuestionTypes())
+	rewritten := map[string]Quiz{}
+	for _, q := range valid {
+		for _, origin := range disputed {
+			if q.ID == origin.ID && q.GetType() == origin.GetType() {
+				rewritten[q.ID] = q
+			}
+		}
+	}
+	return rewritten, buffer.GetQuota()
+}
+
+// verifyQuizzes verifies the answer keys with the judge model and applies the judge policy,
+// the verification is skipped if the judge model fails so that the generation is kept
+func verifyQuizzes(c *gin.Context, user *auth.User, form QuizGenerationRequest, quizzes []Quiz) ([]Quiz, float32) {
+	answers, quota, err := judgeQuizzes(c, user, form, quizzes)
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[quiz] failed to verify the answer keys with judge model %s: %s", globals.QuizJudgeModel, err.Error()))
+		return quizzes, quota
+	}
+
+	checked, disputed := applyJudgement(quizzes, answers)
+	admin.IncrQuizJudge(utils.GetCacheFromContext(c), form.Model, int64(checked), int64(len(disputed)))
+	globals.Debug(fmt.Sprintf("[quiz] judge model %s disputed %d of %d questions (policy: %s)", globals.QuizJudgeModel, len(disputed), checked, globals.QuizJudgePolicy))
+
+	if len(disputed) == 0 {
+		return quizzes, quota
+	}
+
+	switch globals.QuizJudgePolicy {
+	case globals.QuizJudgeDrop:
+		kept := make([]Quiz, 0, len(quizzes))
+		for _, q := range quizzes {
+			if !q.Flagged {
+				kept = append(kept, q)
+			}
+		}
+		return kept, quota
+	case globals.QuizJudgeRegenerate:
+		targets := make([]Quiz, 0, len(disputed))
+		for _, q := range quizzes {
+			if q.Flagged {
+				targets = append(targets, q)
+			}
+		}
+
+		rewritten, used := regenerateQuizzes(c, user, form, targets)
+		quota += used
+		if len(rewritten) == 0 {
+			return quizzes, quota
+		}
+
+		replaced := make([]Quiz, 0, len(rewritten))
+		for i, q := range quizzes {
+			if next, ok := rewritten[q.ID]; ok {
+				quizzes[i] = next
+				replaced = append(replaced, next)
+			}
+		}
+
+		// the rewritten questions are verified once more, the questions still disputed stay flagged
+		answers, used, err := judgeQuizzes(c, user, form, replaced)
+		quota += used
+		if err != nil {
+			globals.Warn(fmt.Sprintf("[quiz] failed to verify the regenerated questions: %s", err.Error()))
+			return quizzes, quota
+		}
+		applyJudgement(quizzes, answers)
+	}
+
+	return quizzes, quota
+}
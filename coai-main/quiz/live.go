@@ -0,0 +1,734 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// a live room is hosted on a saved quiz and joined by the players with the pin. the room state, the
+// players, the answers and the leaderboard live in redis and every change of the room is published
+// on the room channel, so the host and the players can be connected to different backend instances.
+// only the host connection changes the room state, the players only add their answers and scores.
+
+const (
+	liveRoomExpire      = 2 * time.Hour
+	livePinRetries      = 5
+	liveDefaultDuration = 20 // seconds per question
+	liveMinDuration     = 5
+	liveMaxDuration     = 300
+	liveMaxPlayers      = 200
+	liveNameLength      = 24
+	liveLeaderboardSize = 10
+	liveMinPoints       = 500 // points of a correct answer at the deadline
+	liveMaxPoints       = 1000
+	liveStackSize       = 16
+)
+
+// stages of the live room
+const (
+	LiveLobby    = "lobby"
+	LiveQuestion = "question"
+	LiveReveal   = "reveal"
+	LiveEnded    = "ended"
+)
+
+// event types of the live room
+const (
+	LiveRoomEvent     = "room"    // the room is created, sent to the host
+	LiveWelcomeEvent  = "welcome" // the current state of the room, sent to the joined player
+	LiveJoinEvent     = "join"
+	LiveLeaveEvent    = "leave"
+	LiveQuestionEvent = "question"
+	LiveAnsweredEvent = "answered"
+	LiveResultEvent   = "result" // the score of the answer, sent to the player
+	LiveRevealEvent   = "reveal"
+	LiveEndEvent      = "end"
+	LiveErrorEvent    = "error"
+)
+
+// action types of the live room
+const (
+	LiveNextAction   = "next" // lobby -> first question, question -> reveal, reveal -> next question or end
+	LiveEndAction    = "end"
+	LiveAnswerAction = "answer"
+)
+
+// LiveRoom represents the state of a live room, the questions keep the answer keys and are never sent to the clients
+type LiveRoom struct {
+	Pin       string `json:"pin"`
+	HostId    int64  `json:"host_id"`
+	QuizId    int64  `json:"quiz_id"`
+	Name      string `json:"name"`
+	Quizzes   []Quiz `json:"quizzes"`
+	Duration  int    `json:"duration"` // seconds per question
+	Current   int    `json:"current"`  // index of the current question, -1 in the lobby
+	Stage     string `json:"stage"`
+	StartedAt int64  `json:"started_at"` // unix milliseconds of the current question
+}
+
+// LiveScore represents a player in the leaderboard
+type LiveScore struct {
+	Rank  int    `json:"rank"`
+	Name  string `json:"name"`
+	Score int64  `json:"score"`
+}
+
+// LiveEvent represents an event of the live room, the fields are set by the event type
+type LiveEvent struct {
+	Type        string         `json:"type"`
+	Pin         string         `json:"pin,omitempty"`
+	Name        string         `json:"name,omitempty"` // quiz name, or the player name of join and leave events
+	Stage       string         `json:"stage,omitempty"`
+	Players     int64          `json:"players"`
+	Answered    int64          `json:"answered"`
+	Index       int            `json:"index"`
+	Total       int            `json:"total"`
+	Duration    int            `json:"duration,omitempty"`
+	Deadline    int64          `json:"deadline,omitempty"` // unix milliseconds of the question deadline
+	Question    *QuizQuestion  `json:"question,omitempty"`
+	Answer      string         `json:"answer,omitempty"`
+	Correct     *bool          `json:"correct,omitempty"`
+	Points      int64          `json:"points"`
+	Score       int64          `json:"score"`
+	Counts      map[string]int `json:"counts,omitempty"`
+	Leaderboard []LiveScore    `json:"leaderboard,omitempty"`
+	Message     string         `json:"message,omitempty"`
+}
+
+type LiveHostForm struct {
+	Token    string `json:"token"`
+	QuizId   int64  `json:"quiz_id"`
+	Duration int    `json:"duration"` // optional seconds per question
+}
+
+type LivePlayerForm struct {
+	Token string `json:"token"` // optional, the username is the player name of the logged-in players
+	Pin   string `json:"pin"`
+	Name  string `json:"name"`
+}
+
+// LiveAction represents a command of the host or an answer of a player
+type LiveAction struct {
+	Type   string `json:"type"`
+	Index  int    `json:"index"`
+	Answer string `json:"answer"`
+}
+
+func getLiveRoomKey(pin string) string {
+	return fmt.Sprintf("quiz-live:%s", pin)
+}
+
+func getLivePlayersKey(pin string) string {
+	return fmt.Sprintf("quiz-live-players:%s", pin)
+}
+
+func getLiveBoardKey(pin string) string {
+	return fmt.Sprintf("quiz-live-board:%s", pin)
+}
+
+func getLiveAnswersKey(pin string, index int) string {
+	return fmt.Sprintf("quiz-live-answers:%s:%d", pin, index)
+}
+
+func getLiveChannel(pin string) string {
+	return fmt.Sprintf("quiz-live-channel:%s", pin)
+}
+
+func normalizeLiveDuration(duration int) int {
+	if duration <= 0 {
+		return liveDefaultDuration
+	}
+	return int(math.Min(math.Max(float64(duration), liveMinDuration), liveMaxDuration))
+}
+
+// getLivePoints returns the points of an answer, a correct answer earns from liveMaxPoints
+// (answered at once) to liveMinPoints (answered at the deadline)
+func getLivePoints(correct bool, elapsed int64, duration int) int64 {
+	if !correct {
+		return 0
+	}
+
+	ratio := math.Min(math.Max(float64(elapsed)/float64(duration*1000), 0), 1)
+	return int64(math.Round(liveMaxPoints - (liveMaxPoints-liveMinPoints)*ratio))
+}
+
+// CreateLiveRoom creates a live room of the saved quiz with a new pin
+func CreateLiveRoom(cache *redis.Client, userId int64, quiz *SavedQuiz, duration int) (*LiveRoom, error) {
+	if len(quiz.Data) == 0 {
+		return nil, fmt.Errorf("quiz has no questions")
+	}
+
+	room := &LiveRoom{
+		HostId:   userId,
+		QuizId:   quiz.Id,
+		Name:     quiz.Name,
+		Quizzes:  quiz.Data,
+		Duration: normalizeLiveDuration(duration),
+		Current:  -1,
+		Stage:    LiveLobby,
+	}
+
+	for i := 0; i < livePinRetries; i++ {
+		room.Pin = fmt.Sprintf("%06d", rand.Intn(1000000))
+		ok, err := cache.SetNX(cache.Context(), getLiveRoomKey(room.Pin), utils.Marshal(room), liveRoomExpire).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return room, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to generate room pin")
+}
+
+func LoadLiveRoom(cache *redis.Client, pin string) *LiveRoom {
+	raw, err := cache.Get(cache.Context(), getLiveRoomKey(pin)).Result()
+	if err != nil {
+		return nil
+	}
+
+	room, err := utils.UnmarshalString[LiveRoom](raw)
+	if err != nil {
+		return nil
+	}
+	return &room
+}
+
+func (r *LiveRoom) Save(cache *redis.Client) error {
+	return cache.Set(cache.Context(), getLiveRoomKey(r.Pin), utils.Marshal(r), liveRoomExpire).Err()
+}
+
+// Publish sends the event to the host and all the players of the room
+func (r *LiveRoom) Publish(cache *redis.Client, event LiveEvent) {
+	event.Pin = r.Pin
+	if err := cache.Publish(cache.Context(), getLiveChannel(r.Pin), utils.Marshal(event)).Err(); err != nil {
+		globals.Warn(fmt.Sprintf("[quiz] failed to publish live event %s: %s", event.Type, err.Error()))
+	}
+}
+
+func (r *LiveRoom) CountPlayers(cache *redis.Client) int64 {
+	count, _ := cache.HLen(cache.Context(), getLivePlayersKey(r.Pin)).Result()
+	return count
+}
+
+func (r *LiveRoom) CountAnswers(cache *redis.Client, index int) int64 {
+	count, _ := cache.HLen(cache.Context(), getLiveAnswersKey(r.Pin, index)).Result()
+	return count
+}
+
+// GetLeaderboard returns the top players of the room, all the players if limit is not positive
+func (r *LiveRoom) GetLeaderboard(cache *redis.Client, limit int) []LiveScore {
+	stop := int64(limit - 1)
+	if limit <= 0 {
+		stop = -1
+	}
+
+	result := make([]LiveScore, 0)
+	scores, err := cache.ZRevRangeWithScores(cache.Context(), getLiveBoardKey(r.Pin), 0, stop).Result()
+	if err != nil {
+		return result
+	}
+
+	for i, item := range scores {
+		result = append(result, LiveScore{
+			Rank:  i + 1,
+			Name:  utils.ToString(item.Member),
+			Score: int64(item.Score),
+		})
+	}
+	return result
+}
+
+// GetCurrentQuestion returns the event of the current question with the answer hidden
+func (r *LiveRoom) GetCurrentQuestion(cache *redis.Client) LiveEvent {
+	question := r.Quizzes[r.Current].Hide()
+	return LiveEvent{
+		Type:     LiveQuestionEvent,
+		Name:     r.Name,
+		Stage:    r.Stage,
+		Players:  r.CountPlayers(cache),
+		Answered: r.CountAnswers(cache, r.Current),
+		Index:    r.Current,
+		Total:    len(r.Quizzes),
+		Duration: r.Duration,
+		Deadline: r.StartedAt + int64(r.Duration)*1000,
+		Question: &question,
+	}
+}
+
+// JoinLiveRoom adds the player to the room, a name is taken until its player leaves
+func JoinLiveRoom(cache *redis.Client, pin string, name string) (*LiveRoom, string, error) {
+	room := LoadLiveRoom(cache, strings.TrimSpace(pin))
+	if room == nil || room.Stage == LiveEnded {
+		return nil, "", fmt.Errorf("room not found")
+	}
+
+	if name = utils.Extract(strings.TrimSpace(name), liveNameLength, ""); len(name) == 0 {
+		return nil, "", fmt.Errorf("player name is empty")
+	}
+
+	if room.CountPlayers(cache) >= liveMaxPlayers {
+		return nil, "", fmt.Errorf("room is full")
+	}
+
+	ctx := cache.Context()
+	ok, err := cache.HSetNX(ctx, getLivePlayersKey(room.Pin), name, time.Now().UnixMilli()).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("player name is already taken")
+	}
+	cache.Expire(ctx, getLivePlayersKey(room.Pin), liveRoomExpire)
+
+	// the players who rejoin keep their scores
+	cache.ZAddNX(ctx, getLiveBoardKey(room.Pin), &redis.Z{Score: 0, Member: name})
+	cache.Expire(ctx, getLiveBoardKey(room.Pin), liveRoomExpire)
+
+	room.Publish(cache, LiveEvent{Type: LiveJoinEvent, Name: name, Players: room.CountPlayers(cache)})
+	return room, name, nil
+}
+
+// LeaveLiveRoom releases the name of the player, the score is kept in the leaderboard
+func LeaveLiveRoom(cache *redis.Client, pin string, name string) {
+	cache.HDel(cache.Context(), getLivePlayersKey(pin), name)
+	if room := LoadLiveRoom(cache, pin); room != nil && room.Stage != LiveEnded {
+		room.Publish(cache, LiveEvent{Type: LiveLeaveEvent, Name: name, Players: room.CountPlayers(cache)})
+	}
+}
+
+// SubmitLiveAnswer scores the answer of the player to the current question, each question can only be answered once
+func SubmitLiveAnswer(cache *redis.Client, pin string, name string, index int, answer string) (*LiveEvent, error) {
+	room := LoadLiveRoom(cache, pin)
+	if room == nil {
+		return nil, fmt.Errorf("room not found")
+	}
+
+	if room.Stage != LiveQuestion || room.Current != index {
+		return nil, fmt.Errorf("question is closed")
+	}
+
+	elapsed := time.Now().UnixMilli() - room.StartedAt
+	if elapsed > int64(room.Duration)*1000 {
+		return nil, fmt.Errorf("time is up")
+	}
+
+	question := room.Quizzes[index]
+	selected := question.NormalizeSelection(answer)
+	correct := question.IsCorrect(selected)
+
+	ctx := cache.Context()
+	key := getLiveAnswersKey(room.Pin, index)
+	ok, err := cache.HSetNX(ctx, key, name, selected).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("question is already answered")
+	}
+	cache.Expire(ctx, key, liveRoomExpire)
+
+	points := getLivePoints(correct, elapsed, room.Duration)
+	score, err := cache.ZIncrBy(ctx, getLiveBoardKey(room.Pin), float64(points), name).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	room.Publish(cache, LiveEvent{
+		Type:     LiveAnsweredEvent,
+		Index:    index,
+		Players:  room.CountPlayers(cache),
+		Answered: room.CountAnswers(cache, index),
+	})
+
+	return &LiveEvent{
+		Type:    LiveResultEvent,
+		Index:   index,
+		Correct: &correct,
+		Points:  points,
+		Score:   int64(score),
+	}, nil
+}
+
+// LiveHost drives the room of the host connection, the timer reveals the question at the deadline
+type LiveHost struct {
+	room  *LiveRoom
+	cache *redis.Client
+	mutex sync.Mutex
+	timer *time.Timer
+}
+
+func NewLiveHost(cache *redis.Client, room *LiveRoom) *LiveHost {
+	return &LiveHost{
+		room:  room,
+		cache: cache,
+	}
+}
+
+func (h *LiveHost) stopTimer() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+}
+
+// Next advances the room to the next stage
+func (h *LiveHost) Next() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	switch h.room.Stage {
+	case LiveQuestion:
+		return h.reveal()
+	case LiveLobby, LiveReveal:
+		if h.room.Current+1 >= len(h.room.Quizzes) {
+			return h.end()
+		}
+		return h.ask(h.room.Current + 1)
+	}
+	return fmt.Errorf("room is ended")
+}
+
+func (h *LiveHost) ask(index int) error {
+	h.room.Current = index
+	h.room.Stage = LiveQuestion
+	h.room.StartedAt = time.Now().UnixMilli()
+	if err := h.room.Save(h.cache); err != nil {
+		return err
+	}
+
+	h.room.Publish(h.cache, h.room.GetCurrentQuestion(h.cache))
+
+	h.stopTimer()
+	h.timer = time.AfterFunc(time.Duration(h.room.Duration)*time.Second, func() {
+		h.Reveal(index)
+	})
+	return nil
+}
+
+// Reveal reveals the question if it is still open, it is called at the deadline or when all the players answered
+func (h *LiveHost) Reveal(index int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.room.Stage != LiveQuestion || h.room.Current != index {
+		return
+	}
+	_ = h.reveal()
+}
+
+func (h *LiveHost) reveal() error {
+	h.stopTimer()
+	h.room.Stage = LiveReveal
+	if err := h.room.Save(h.cache); err != nil {
+		return err
+	}
+
+	question := h.room.Quizzes[h.room.Current]
+	counts := map[string]int{}
+	answers, _ := h.cache.HVals(h.cache.Context(), getLiveAnswersKey(h.room.Pin, h.room.Current)).Result()
+	for _, answer := range answers {
+		counts[answer]++
+	}
+
+	h.room.Publish(h.cache, LiveEvent{
+		Type:        LiveRevealEvent,
+		Stage:       h.room.Stage,
+		Players:     h.room.CountPlayers(h.cache),
+		Answered:    int64(len(answers)),
+		Index:       h.room.Current,
+		Total:       len(h.room.Quizzes),
+		Answer:      question.FormatSelection(question.AnswerKey()),
+		Counts:      counts,
+		Leaderboard: h.room.GetLeaderboard(h.cache, liveLeaderboardSize),
+		Message:     question.Description,
+	})
+	return nil
+}
+
+// End ends the room with the final leaderboard and removes the room state
+func (h *LiveHost) End() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.room.Stage != LiveEnded {
+		_ = h.end()
+	}
+}
+
+func (h *LiveHost) end() error {
+	h.stopTimer()
+	h.room.Stage = LiveEnded
+
+	h.room.Publish(h.cache, LiveEvent{
+		Type:        LiveEndEvent,
+		Stage:       h.room.Stage,
+		Players:     h.room.CountPlayers(h.cache),
+		Total:       len(h.room.Quizzes),
+		Leaderboard: h.room.GetLeaderboard(h.cache, 0),
+	})
+
+	keys := []string{getLiveRoomKey(h.room.Pin), getLivePlayersKey(h.room.Pin), getLiveBoardKey(h.room.Pin)}
+	for i := range h.room.Quizzes {
+		keys = append(keys, getLiveAnswersKey(h.room.Pin, i))
+	}
+	return h.cache.Del(h.cache.Context(), keys...).Err()
+}
+
+// LiveConnection wraps the websocket of the host or a player, the actions are read by the read
+// worker into the stack like manager.Connection and the room events are forwarded from the room channel
+type LiveConnection struct {
+	conn   *utils.WebSocket
+	stack  chan *LiveAction
+	mutex  sync.Mutex
+	pubsub *redis.PubSub
+}
+
+func NewLiveConnection(conn *utils.WebSocket) *LiveConnection {
+	return &LiveConnection{
+		conn:  conn,
+		stack: make(chan *LiveAction, liveStackSize),
+	}
+}
+
+// Send writes the event to the client, the writes of the handler and the channel are serialized
+func (c *LiveConnection) Send(event LiveEvent) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn.Send(event)
+}
+
+func (c *LiveConnection) SendText(message string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn.SendText(message)
+}
+
+func (c *LiveConnection) SendError(err error) bool {
+	return c.Send(LiveEvent{Type: LiveErrorEvent, Message: err.Error()})
+}
+
+// Subscribe forwards the events of the room channel to the client, the hook is called after each event
+func (c *LiveConnection) Subscribe(cache *redis.Client, pin string, hook func(event LiveEvent)) error {
+	c.pubsub = cache.Subscribe(cache.Context(), getLiveChannel(pin))
+	if _, err := c.pubsub.Receive(cache.Context()); err != nil {
+		_ = c.pubsub.Close()
+		return err
+	}
+
+	go func() {
+		for message := range c.pubsub.Channel() {
+			c.SendText(message.Payload)
+			if hook == nil {
+				continue
+			}
+
+			if event, err := utils.UnmarshalString[LiveEvent](message.Payload); err == nil {
+				hook(event)
+			}
+		}
+	}()
+	return nil
+}
+
+// Unsubscribe stops forwarding the room events
+func (c *LiveConnection) Unsubscribe() {
+	if c.pubsub != nil {
+		_ = c.pubsub.Close()
+	}
+}
+
+func (c *LiveConnection) ReadWorker() {
+	for {
+		if c.conn.IsClosed() {
+			break
+		}
+
+		form, err := utils.ReadForm[LiveAction](c.conn)
+		if err != nil {
+			break
+		}
+
+		c.Write(form)
+	}
+
+	c.Write(nil)
+}
+
+func (c *LiveConnection) Write(action *LiveAction) {
+	if len(c.stack) == cap(c.stack) {
+		<-c.stack
+	}
+	c.stack <- action
+}
+
+func (c *LiveConnection) Process(handler func(*LiveAction) error) {
+	for {
+		action := <-c.stack
+		if action == nil {
+			return
+		}
+
+		if err := handler(action); err != nil {
+			return
+		}
+	}
+}
+
+// Handle processes the actions until the client disconnects
+func (c *LiveConnection) Handle(handler func(*LiveAction) error) {
+	go c.Process(handler)
+	c.ReadWorker()
+}
+
+// LiveHostAPI opens a live room of the saved quiz and drives it by the host actions,
+// the room is ended when the host disconnects
+func LiveHostAPI(c *gin.Context) {
+	var conn *utils.WebSocket
+	if conn = utils.NewWebsocket(c, false); conn == nil {
+		return
+	}
+	defer conn.DeferClose()
+
+	form, err := utils.ReadForm[LiveHostForm](conn)
+	if err != nil {
+		return
+	}
+
+	live := NewLiveConnection(conn)
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+	user := auth.ParseToken(c, form.Token)
+	if user == nil {
+		live.SendError(fmt.Errorf("user not found"))
+		return
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), form.QuizId)
+	if quiz == nil {
+		live.SendError(fmt.Errorf("quiz not found"))
+		return
+	}
+
+	room, err := CreateLiveRoom(cache, user.GetID(db), quiz, form.Duration)
+	if err != nil {
+		live.SendError(err)
+		return
+	}
+
+	host := NewLiveHost(cache, room)
+	defer host.End()
+
+	if err := live.Subscribe(cache, room.Pin, func(event LiveEvent) {
+		// reveal the question as soon as all the players answered
+		if event.Type == LiveAnsweredEvent && event.Players > 0 && event.Answered >= event.Players {
+			host.Reveal(event.Index)
+		}
+	}); err != nil {
+		live.SendError(err)
+		return
+	}
+	defer live.Unsubscribe()
+
+	live.Send(LiveEvent{
+		Type:     LiveRoomEvent,
+		Pin:      room.Pin,
+		Name:     room.Name,
+		Stage:    room.Stage,
+		Index:    room.Current,
+		Total:    len(room.Quizzes),
+		Duration: room.Duration,
+	})
+
+	live.Handle(func(action *LiveAction) error {
+		switch action.Type {
+		case LiveNextAction:
+			if err := host.Next(); err != nil {
+				live.SendError(err)
+			}
+		case LiveEndAction:
+			host.End()
+		default:
+			live.SendError(fmt.Errorf("unknown action: %s", action.Type))
+		}
+		return nil
+	})
+}
+
+// LiveJoinAPI joins the player to the live room of the pin and submits the answers of the player
+func LiveJoinAPI(c *gin.Context) {
+	var conn *utils.WebSocket
+	if conn = utils.NewWebsocket(c, false); conn == nil {
+		return
+	}
+	defer conn.DeferClose()
+
+	form, err := utils.ReadForm[LivePlayerForm](conn)
+	if err != nil {
+		return
+	}
+
+	live := NewLiveConnection(conn)
+	cache := utils.GetCacheFromContext(c)
+	if user := auth.ParseToken(c, form.Token); user != nil {
+		form.Name = user.Username
+	}
+
+	// subscribe before joining so that no event after the welcome state is missed
+	pin := strings.TrimSpace(form.Pin)
+	if err := live.Subscribe(cache, pin, nil); err != nil {
+		live.SendError(err)
+		return
+	}
+	defer live.Unsubscribe()
+
+	room, name, err := JoinLiveRoom(cache, pin, form.Name)
+	if err != nil {
+		live.SendError(err)
+		return
+	}
+	defer LeaveLiveRoom(cache, room.Pin, name)
+
+	welcome := LiveEvent{
+		Type:     LiveWelcomeEvent,
+		Pin:      room.Pin,
+		Name:     name,
+		Stage:    room.Stage,
+		Players:  room.CountPlayers(cache),
+		Index:    room.Current,
+		Total:    len(room.Quizzes),
+		Duration: room.Duration,
+	}
+	if room.Stage == LiveQuestion {
+		question := room.GetCurrentQuestion(cache)
+		welcome.Deadline, welcome.Question, welcome.Answered = question.Deadline, question.Question, question.Answered
+	}
+	live.Send(welcome)
+
+	live.Handle(func(action *LiveAction) error {
+		if action.Type != LiveAnswerAction {
+			live.SendError(fmt.Errorf("unknown action: %s", action.Type))
+			return nil
+		}
+
+		result, err := SubmitLiveAnswer(cache, room.Pin, name, action.Index, action.Answer)
+		if err != nil {
+			live.SendError(err)
+			return nil
+		}
+
+		live.Send(*result)
+		return nil
+	})
+}
@@ -0,0 +1,393 @@
+package quiz
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// this is a minimal pdf text extractor, it follows the page tree of uncompressed
+// object dictionaries and decodes the FlateDecode content streams of each page.
+// pdfs that store pages in object streams fall back to scanning all content streams.
+
+var (
+	pdfObjectRegex   = regexp.MustCompile(`(?s)(\d+)\s+\d+\s+obj\b(.*?)\bendobj`)
+	pdfRefRegex      = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfPagesRegex    = regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`)
+	pdfKidsRegex     = regexp.MustCompile(`(?s)/Kids\s*\[(.*?)\]`)
+	pdfContentsRegex = regexp.MustCompile(`(?s)/Contents\s*(\[.*?\]|\d+\s+\d+\s+R)`)
+	pdfTypeRegex     = regexp.MustCompile(`/Type\s*/(\w+)`)
+)
+
+type pdfObject struct {
+	Dict   string
+	Stream []byte
+}
+
+func parsePdfObjects(data []byte) map[int]pdfObject {
+	objects := map[int]pdfObject{}
+	for _, match := range pdfObjectRegex.FindAllSubmatch(data, -1) {
+		id, err := strconv.Atoi(string(match[1]))
+		if err != nil {
+			continue
+		}
+
+		body := match[2]
+		object := pdfObject{Dict: string(body)}
+		if idx := bytes.Index(body, []byte("stream")); idx >= 0 {
+			object.Dict = string(body[:idx])
+			stream := body[idx+len("stream"):]
+			stream = bytes.TrimPrefix(stream, []byte("\r"))
+			stream = bytes.TrimPrefix(stream, []byte("\n"))
+			if end := bytes.LastIndex(stream, []byte("endstream")); end >= 0 {
+				stream = stream[:end]
+			}
+			object.Stream = stream
+		}
+
+		objects[id] = object
+	}
+
+	return objects
+}
+
+func (o pdfObject) GetType() string {
+	if match := pdfTypeRegex.FindStringSubmatch(o.Dict); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func (o pdfObject) Decode(budget *extractBudget) ([]byte, error) {
+	if !strings.Contains(o.Dict, "/FlateDecode") {
+		return o.Stream, nil
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(o.Stream))
+	if err != nil {
+		return nil, nil
+	}
+	defer reader.Close()
+
+	// truncated streams are still partially readable
+	data, err := io.ReadAll(budget.Reader(reader))
+	if err == errExtractLimit {
+		return nil, err
+	}
+	return data, nil
+}
+
+func parseRefs(value string) []int {
+	refs := make([]int, 0)
+	for _, match := range pdfRefRegex.FindAllStringSubmatch(value, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			refs = append(refs, id)
+		}
+	}
+	return refs
+}
+
+// collectPages walks the page tree and returns the page object ids in order
+func collectPages(objects map[int]pdfObject, id int, pages []int, depth int) []int {
+	object, ok := objects[id]
+	if !ok || depth > 32 {
+		return pages
+	}
+
+	switch object.GetType() {
+	case "Page":
+		return append(pages, id)
+	case "Pages":
+		if match := pdfKidsRegex.FindStringSubmatch(object.Dict); match != nil {
+			for _, kid := range parseRefs(match[1]) {
+				pages = collectPages(objects, kid, pages, depth+1)
+			}
+		}
+	}
+
+	return pages
+}
+
+func findPageContents(objects map[int]pdfObject) [][]int {
+	root := -1
+	for _, object := range objects {
+		if object.GetType() == "Catalog" {
+			if match := pdfPagesRegex.FindStringSubmatch(object.Dict); match != nil {
+				root, _ = strconv.Atoi(match[1])
+			}
+			break
+		}
+	}
+
+	contents := make([][]int, 0)
+	if root < 0 {
+		return contents
+	}
+
+	for _, page := range collectPages(objects, root, nil, 0) {
+		if match := pdfContentsRegex.FindStringSubmatch(objects[page].Dict); match != nil {
+			contents = append(contents, parseRefs(match[1]))
+		} else {
+			contents = append(contents, nil)
+		}
+	}
+
+	return contents
+}
+
+func extractPdf(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("%PDF")) {
+		return "", fmt.Errorf("invalid pdf file")
+	}
+
+	objects := parsePdfObjects(data)
+	pages := findPageContents(objects)
+
+	// the streams are decoded once within the extract budget of the file
+	budget := newExtractBudget()
+	decoded := map[int][]byte{}
+	decode := func(id int) ([]byte, error) {
+		if stream, ok := decoded[id]; ok {
+			return stream, nil
+		}
+		stream, err := objects[id].Decode(budget)
+		if err != nil {
+			return nil, err
+		}
+		decoded[id] = stream
+		return stream, nil
+	}
+
+	if len(pages) == 0 {
+		// fallback: treat every text content stream as a page
+		ids := make([]int, 0, len(objects))
+		for id := range objects {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			object := objects[id]
+			if object.Stream == nil || object.GetType() != "" {
+				continue
+			}
+
+			stream, err := decode(id)
+			if err != nil {
+				return "", err
+			}
+			if bytes.Contains(stream, []byte("BT")) {
+				pages = append(pages, []int{id})
+			}
+		}
+	}
+
+	if len(pages) > MaxFilePages {
+		return "", fmt.Errorf("file exceeds the page limit of %d", MaxFilePages)
+	}
+
+	var builder strings.Builder
+	for i, refs := range pages {
+		builder.WriteString(pageMarker("Page", i+1))
+		for _, ref := range refs {
+			if _, ok := objects[ref]; !ok {
+				continue
+			}
+
+			stream, err := decode(ref)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(extractPdfText(stream))
+		}
+	}
+
+	return cleanText(builder.String()), nil
+}
+
+// extractPdfText reads the strings shown by the Tj, TJ, ' and " operators of a content stream
+func extractPdfText(stream []byte) string {
+	var (
+		builder  strings.Builder
+		operands []string
+		numbers  []float64
+	)
+
+	for i := 0; i < len(stream); {
+		c := stream[i]
+		switch {
+		case c == '(':
+			text, next := readPdfString(stream, i)
+			operands = append(operands, text)
+			i = next
+		case c == '<' && i+1 < len(stream) && stream[i+1] != '<':
+			end := bytes.IndexByte(stream[i:], '>')
+			if end < 0 {
+				return builder.String()
+			}
+			operands = append(operands, decodePdfHex(string(stream[i+1:i+end])))
+			i += end + 1
+		case c == '[':
+			operands = append(operands, "[")
+			i++
+		case c == ']':
+			// join the array of a TJ operator, large negative kerning means a space
+			idx := len(operands) - 1
+			for idx >= 0 && operands[idx] != "[" {
+				idx--
+			}
+			if idx >= 0 {
+				operands = append(operands[:idx], strings.Join(operands[idx+1:], ""))
+			}
+			i++
+		case c == '%':
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case isPdfRegular(c):
+			start := i
+			for i < len(stream) && isPdfRegular(stream[i]) {
+				i++
+			}
+			token := string(stream[start:i])
+
+			if number, err := strconv.ParseFloat(token, 64); err == nil {
+				if number < -200 {
+					operands = append(operands, " ")
+				}
+				numbers = append(numbers, number)
+				continue
+			}
+
+			switch token {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					builder.WriteString(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				builder.WriteString("\n")
+				if len(operands) > 0 {
+					builder.WriteString(operands[len(operands)-1])
+				}
+			case "Td", "TD":
+				// a horizontal move is a space, a vertical move is a new line
+				if len(numbers) >= 2 && numbers[len(numbers)-1] != 0 {
+					builder.WriteString("\n")
+				} else {
+					builder.WriteString(" ")
+				}
+			case "T*", "ET":
+				builder.WriteString("\n")
+			}
+			operands = operands[:0]
+			numbers = numbers[:0]
+		default:
+			i++
+		}
+	}
+
+	return builder.String()
+}
+
+func isPdfRegular(c byte) bool {
+	return !bytes.ContainsRune([]byte(" \t\r\n\f\x00()<>[]{}/%"), rune(c))
+}
+
+func readPdfString(stream []byte, start int) (string, int) {
+	var builder strings.Builder
+	depth := 0
+
+	i := start
+	for ; i < len(stream); i++ {
+		c := stream[i]
+		switch c {
+		case '\\':
+			if i+1 >= len(stream) {
+				continue
+			}
+			i++
+			switch stream[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r', 't', 'b', 'f':
+				builder.WriteByte(' ')
+			case '\r', '\n':
+				// line continuation
+			default:
+				if stream[i] >= '0' && stream[i] <= '7' {
+					end := i
+					for end < len(stream) && end < i+3 && stream[end] >= '0' && stream[end] <= '7' {
+						end++
+					}
+					value, _ := strconv.ParseInt(string(stream[i:end]), 8, 32)
+					builder.WriteByte(byte(value))
+					i = end - 1
+				} else {
+					builder.WriteByte(stream[i])
+				}
+			}
+		case '(':
+			if depth > 0 {
+				builder.WriteByte(c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePdfString(builder.String()), i + 1
+			}
+			builder.WriteByte(c)
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return decodePdfString(builder.String()), i
+}
+
+func decodePdfHex(value string) string {
+	value = strings.Join(strings.Fields(value), "")
+	if len(value)%2 == 1 {
+		value += "0"
+	}
+
+	raw := make([]byte, 0, len(value)/2)
+	for i := 0; i+1 < len(value); i += 2 {
+		b, err := strconv.ParseUint(value[i:i+2], 16, 8)
+		if err != nil {
+			return ""
+		}
+		raw = append(raw, byte(b))
+	}
+
+	return decodePdfString(string(raw))
+}
+
+// decodePdfString decodes utf-16 strings with bom and drops the non-printable bytes
+// of the strings shown by embedded fonts with custom encodings
+func decodePdfString(value string) string {
+	if strings.HasPrefix(value, "\xfe\xff") {
+		runes := make([]rune, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			runes = append(runes, rune(value[i])<<8|rune(value[i+1]))
+		}
+		return string(runes)
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= 0x20 && c < 0x7f || c == '\n' || c == '\t' {
+			builder.WriteByte(c)
+		} else if c >= 0xa0 {
+			// latin-1 supplement
+			builder.WriteRune(rune(c))
+		}
+	}
+	return builder.String()
+}
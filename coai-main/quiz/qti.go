@@ -0,0 +1,159 @@
+package quiz
+
+import (
+	"bytes"
+	"chat/utils"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// the qti export is an ims content package of qti 2.1 items, each question is an
+// assessment item referenced by the imsmanifest.xml at the root of the zip
+
+const (
+	qtiNamespace      = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	manifestNamespace = "http://www.imsglobal.org/xsd/imscp_v1p1"
+)
+
+const (
+	qtiMatchCorrect = `<responseCondition><responseIf><match><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></match><setOutcomeValue identifier="SCORE"><baseValue baseType="float">1</baseValue></setOutcomeValue></responseIf><responseElse><setOutcomeValue identifier="SCORE"><baseValue baseType="float">0</baseValue></setOutcomeValue></responseElse></responseCondition>`
+	qtiMapResponse  = `<setOutcomeValue identifier="SCORE"><mapResponse identifier="RESPONSE"/></setOutcomeValue>`
+	qtiShowFeedback = `<setOutcomeValue identifier="FEEDBACK"><baseValue baseType="identifier">SOLUTION</baseValue></setOutcomeValue>`
+)
+
+func escapeXml(text string) string {
+	var buffer bytes.Buffer
+	_ = xml.EscapeText(&buffer, []byte(text))
+	return buffer.String()
+}
+
+func qtiChoiceId(key string) string {
+	return "CHOICE_" + strings.ToUpper(key)
+}
+
+func qtiValues(values []string) string {
+	var builder strings.Builder
+	for _, value := range values {
+		builder.WriteString(fmt.Sprintf("<value>%s</value>", escapeXml(value)))
+	}
+	return builder.String()
+}
+
+func qtiChoices(q Quiz) string {
+	var builder strings.Builder
+	for _, key := range q.Options.Keys() {
+		builder.WriteString(fmt.Sprintf(`<simpleChoice identifier="%s">%s</simpleChoice>`, qtiChoiceId(key), escapeXml(q.Options[key])))
+	}
+	return builder.String()
+}
+
+// ToQtiItem exports the question to a qti 2.1 assessment item
+func ToQtiItem(q Quiz, identifier string) string {
+	var (
+		declaration string
+		body        string
+		processing  = qtiMatchCorrect
+		prompt      = fmt.Sprintf("<prompt>%s</prompt>", escapeXml(q.Question))
+	)
+
+	switch q.GetType() {
+	case MultipleChoice:
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier"><correctResponse>%s</correctResponse></responseDeclaration>`,
+			qtiValues([]string{qtiChoiceId(q.Answer)}))
+		body = fmt.Sprintf(`<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="1">%s%s</choiceInteraction>`, prompt, qtiChoices(q))
+	case TrueFalse:
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier"><correctResponse>%s</correctResponse></responseDeclaration>`,
+			qtiValues([]string{qtiChoiceId(q.Answer)}))
+		body = fmt.Sprintf(`<choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">%s<simpleChoice identifier="%s">True</simpleChoice><simpleChoice identifier="%s">False</simpleChoice></choiceInteraction>`,
+			prompt, qtiChoiceId("true"), qtiChoiceId("false"))
+	case MultiSelect:
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier"><correctResponse>%s</correctResponse></responseDeclaration>`,
+			qtiValues(utils.Each(q.Answers, qtiChoiceId)))
+		body = fmt.Sprintf(`<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="0">%s%s</choiceInteraction>`, prompt, qtiChoices(q))
+	case Ordering:
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="ordered" baseType="identifier"><correctResponse>%s</correctResponse></responseDeclaration>`,
+			qtiValues(utils.Each(q.Answers, qtiChoiceId)))
+		body = fmt.Sprintf(`<orderInteraction responseIdentifier="RESPONSE" shuffle="true">%s%s</orderInteraction>`, prompt, qtiChoices(q))
+	case FillBlank:
+		var mapping strings.Builder
+		for _, answer := range q.Answers {
+			mapping.WriteString(fmt.Sprintf(`<mapEntry mapKey="%s" mappedValue="1"/>`, escapeXml(answer)))
+		}
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string"><correctResponse>%s</correctResponse><mapping defaultValue="0" upperBound="1">%s</mapping></responseDeclaration>`,
+			qtiValues(q.Answers[:1]), mapping.String())
+
+		interaction := `<textEntryInteraction responseIdentifier="RESPONSE" expectedLength="20"/>`
+		parts := strings.SplitN(q.Question, BlankMarker, 2)
+		if len(parts) == 2 {
+			body = fmt.Sprintf("<p>%s%s%s</p>", escapeXml(parts[0]), interaction, escapeXml(parts[1]))
+		} else {
+			body = fmt.Sprintf("<p>%s</p><p>%s</p>", escapeXml(q.Question), interaction)
+		}
+		processing = qtiMapResponse
+	case ShortAnswer:
+		// essays are graded manually, the rubric is kept as the model answer
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string"><correctResponse>%s</correctResponse></responseDeclaration>`,
+			qtiValues([]string{strings.Join(q.Rubric, "; ")}))
+		body = fmt.Sprintf(`<extendedTextInteraction responseIdentifier="RESPONSE" expectedLines="5">%s</extendedTextInteraction>`, prompt)
+		processing = ""
+	}
+
+	// the explanation is shown as the modal feedback once the item is answered
+	var feedback string
+	if len(strings.TrimSpace(q.Description)) > 0 {
+		feedback = fmt.Sprintf(`<modalFeedback outcomeIdentifier="FEEDBACK" identifier="SOLUTION" showHide="show">%s</modalFeedback>`, escapeXml(q.Description))
+		processing += qtiShowFeedback
+	}
+
+	if len(processing) > 0 {
+		processing = fmt.Sprintf("<responseProcessing>%s</responseProcessing>", processing)
+	}
+
+	return xml.Header + fmt.Sprintf(`<assessmentItem xmlns="%s" identifier="%s" title="%s" adaptive="false" timeDependent="false">%s<outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"/><outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier"/><itemBody>%s</itemBody>%s%s</assessmentItem>`,
+		qtiNamespace, identifier, identifier, declaration, body, processing, feedback)
+}
+
+// ToQtiManifest builds the ims content package manifest of the item identifiers
+func ToQtiManifest(identifiers []string) string {
+	var resources strings.Builder
+	for _, identifier := range identifiers {
+		href := fmt.Sprintf("items/%s.xml", identifier)
+		resources.WriteString(fmt.Sprintf(`<resource identifier="%s" type="imsqti_item_xmlv2p1" href="%s"><file href="%s"/></resource>`, identifier, href, href))
+	}
+
+	return xml.Header + fmt.Sprintf(`<manifest xmlns="%s" identifier="MANIFEST"><metadata><schema>IMS Content</schema><schemaversion>1.1</schemaversion></metadata><organizations/><resources>%s</resources></manifest>`,
+		manifestNamespace, resources.String())
+}
+
+// exportQti exports the quiz to a qti 2.1 content package zip
+func exportQti(quiz *SavedQuiz, file *ExportFile) (*ExportFile, error) {
+	source := fmt.Sprintf("%s/source", file.Dir)
+	files := make([]string, 0, len(quiz.Data)+1)
+	identifiers := make([]string, 0, len(quiz.Data))
+
+	for i, q := range quiz.Data {
+		identifier := fmt.Sprintf("Q%d", i+1)
+		path := fmt.Sprintf("%s/items/%s.xml", source, identifier)
+		if err := utils.WriteFile(path, ToQtiItem(q, identifier), true); err != nil {
+			return nil, err
+		}
+
+		identifiers = append(identifiers, identifier)
+		files = append(files, path)
+	}
+
+	manifest := fmt.Sprintf("%s/imsmanifest.xml", source)
+	if err := utils.WriteFile(manifest, ToQtiManifest(identifiers), true); err != nil {
+		return nil, err
+	}
+	files = append([]string{manifest}, files...)
+
+	file.Filename = exportFilename(quiz.Name, "qti.zip")
+	file.Path = fmt.Sprintf("%s/%s", file.Dir, file.Filename)
+	if err := utils.CreateZipObject(file.Path, files, source); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
@@ -0,0 +1,436 @@
+package quiz
+
+import (
+	"chat/utils"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	MultipleChoice = "multiple_choice"
+	TrueFalse      = "true_false"
+	MultiSelect    = "multi_select"
+	FillBlank      = "fill_blank"
+	Ordering       = "ordering"
+	ShortAnswer    = "short_answer"
+)
+
+// BlankMarker marks the blank in the question text of fill_blank questions
+const BlankMarker = "___"
+
+var QuestionTypes = []string{MultipleChoice, TrueFalse, MultiSelect, FillBlank, Ordering, ShortAnswer}
+
+const (
+	minChoiceOptions = 2  // min options of multiple_choice questions, e.g. imported two-option questions
+	maxChoiceOptions = 10 // max options of multiple_choice questions, the generation asks for four
+)
+
+// questionSchemas are the json examples of each question type used in the prompt
+var questionSchemas = map[string]string{
+	MultipleChoice: `{"id": "1", "type": "multiple_choice", "question": "Question text", "description": "Explanation", "options": {"a": "Option A", "b": "Option B", "c": "Option C", "d": "Option D"}, "answer": "a", "explanations": {"a": "Why A is correct", "b": "Why B is wrong", "c": "Why C is wrong", "d": "Why D is wrong"}}
+  // exactly four options a, b, c and d; "answer" is the single correct key; "explanations" explains every option`,
+	TrueFalse: `{"id": "2", "type": "true_false", "question": "Statement text", "description": "Explanation", "answer": "true", "explanations": {"true": "Why the statement is true", "false": "Why answering false is wrong"}}
+  // "answer" is either "true" or "false"; "explanations" explains both answers`,
+	MultiSelect: `{"id": "3", "type": "multi_select", "question": "Question text", "description": "Explanation", "options": {"a": "Option A", "b": "Option B", "c": "Option C", "d": "Option D"}, "answers": ["a", "c"], "explanations": {"a": "Why A is correct", "b": "Why B is wrong", "c": "Why C is correct", "d": "Why D is wrong"}}
+  // three to six options; "answers" contains every correct key; "explanations" explains every option`,
+	FillBlank: `{"id": "4", "type": "fill_blank", "question": "The capital of France is ___.", "description": "Explanation", "answers": ["Paris"]}
+  // the question contains exactly one "___" blank; "answers" lists every accepted variant`,
+	Ordering: `{"id": "5", "type": "ordering", "question": "Order the following items", "description": "Explanation", "options": {"a": "Item", "b": "Item", "c": "Item", "d": "Item"}, "answers": ["c", "a", "d", "b"]}
+  // three to six items labelled in a shuffled order; "answers" is the correct sequence of every key`,
+	ShortAnswer: `{"id": "6", "type": "short_answer", "question": "Question text", "description": "Explanation", "model_answer": "Model answer", "rubric": ["key point 1", "key point 2"]}
+  // "model_answer" is the reference answer; "rubric" lists the key points a correct answer must mention`,
+}
+
+func IsQuestionType(t string) bool {
+	return utils.Contains(t, QuestionTypes)
+}
+
+// GetQuestionTypes returns the valid requested question types, multiple_choice by default
+func (r QuizGenerationRequest) GetQuestionTypes() []string {
+	types := make([]string, 0)
+	for _, t := range r.QuestionTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if IsQuestionType(t) && !utils.Contains(t, types) {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 {
+		return []string{MultipleChoice}
+	}
+	return types
+}
+
+// GetType returns the question type, questions without a type are multiple choice
+func (q Quiz) GetType() string {
+	if len(q.Type) == 0 {
+		return MultipleChoice
+	}
+	return q.Type
+}
+
+func (o QuizOption) Keys() []string {
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Normalize lowercases the option keys
+func (o QuizOption) Normalize() QuizOption {
+	if o == nil {
+		return nil
+	}
+
+	options := QuizOption{}
+	for key, value := range o {
+		options[normalizeKey(key)] = value
+	}
+	return options
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// splitKeys splits the comma separated selection of multi_select and ordering questions
+func splitKeys(selected string) []string {
+	keys := make([]string, 0)
+	for _, key := range strings.Split(selected, ",") {
+		if key = normalizeKey(key); len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func validateOptions(options QuizOption, min int, max int) []string {
+	problems := make([]string, 0)
+	if len(options) < min || len(options) > max {
+		problems = append(problems, fmt.Sprintf("expected %d to %d options, got %d", min, max, len(options)))
+	}
+
+	for _, key := range options.Keys() {
+		if len(strings.TrimSpace(options[key])) == 0 {
+			problems = append(problems, fmt.Sprintf("option %s is empty", key))
+		}
+	}
+	return problems
+}
+
+// Validate returns the schema problems of the question, empty if the question is valid
+func (q Quiz) Validate() []string {
+	problems := make([]string, 0)
+
+	if len(strings.TrimSpace(q.Question)) == 0 {
+		problems = append(problems, "question text is empty")
+	}
+
+	switch q.GetType() {
+	case MultipleChoice:
+		problems = append(problems, validateOptions(q.Options, minChoiceOptions, maxChoiceOptions)...)
+		if _, ok := q.Options[normalizeKey(q.Answer)]; !ok {
+			problems = append(problems, fmt.Sprintf("answer %q is not one of the option keys %s", q.Answer, strings.Join(q.Options.Keys(), ", ")))
+		}
+	case TrueFalse:
+		if answer := normalizeKey(q.Answer); answer != "true" && answer != "false" {
+			problems = append(problems, fmt.Sprintf("answer %q is not true or false", q.Answer))
+		}
+	case MultiSelect:
+		problems = append(problems, validateOptions(q.Options, 3, 6)...)
+		if len(q.Answers) == 0 {
+			problems = append(problems, "answers is empty")
+		}
+		for _, key := range q.Answers {
+			if _, ok := q.Options[normalizeKey(key)]; !ok {
+				problems = append(problems, fmt.Sprintf("answer %q is not an option key", key))
+			}
+		}
+	case FillBlank:
+		if strings.Count(q.Question, BlankMarker) != 1 {
+			problems = append(problems, fmt.Sprintf("question must contain exactly one %s blank", BlankMarker))
+		}
+		if len(q.Answers) == 0 {
+			problems = append(problems, "accepted answers is empty")
+		}
+	case Ordering:
+		problems = append(problems, validateOptions(q.Options, 3, 6)...)
+		keys := q.Options.Keys()
+		sequence := make([]string, 0, len(q.Answers))
+		for _, key := range q.Answers {
+			sequence = append(sequence, normalizeKey(key))
+		}
+		sort.Strings(sequence)
+		if strings.Join(sequence, ",") != strings.Join(keys, ",") {
+			problems = append(problems, "answers must be a sequence of every option key exactly once")
+		}
+	case ShortAnswer:
+		if len(q.Rubric) == 0 {
+			problems = append(problems, "rubric is empty")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("type %q is not one of %s", q.Type, strings.Join(QuestionTypes, ", ")))
+	}
+
+	return problems
+}
+
+// explanationKeys returns the keys which must be explained, empty if the type has no per-option explanations
+func (q Quiz) explanationKeys() []string {
+	switch q.GetType() {
+	case MultipleChoice, MultiSelect:
+		return q.Options.Keys()
+	case TrueFalse:
+		return []string{"true", "false"}
+	}
+	return nil
+}
+
+// validateExplanations returns the problems of the per-option explanations of a generated question
+func (q Quiz) validateExplanations() []string {
+	problems := make([]string, 0)
+	for _, key := range q.explanationKeys() {
+		if len(strings.TrimSpace(q.Explanations[key])) == 0 {
+			problems = append(problems, fmt.Sprintf("explanation of %s is missing", key))
+		}
+	}
+	return problems
+}
+
+// Explain returns the explanations of the selected options, empty if they are not explained
+func (q Quiz) Explain(selected string) string {
+	switch q.GetType() {
+	case MultipleChoice, TrueFalse:
+		return q.Explanations[normalizeKey(selected)]
+	case MultiSelect:
+		lines := make([]string, 0)
+		for _, key := range splitKeys(selected) {
+			if explanation := q.Explanations[key]; len(explanation) > 0 {
+				lines = append(lines, fmt.Sprintf("%s. %s", strings.ToUpper(key), explanation))
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// FormatSelection formats the selection (or the answer key) of the question as readable text
+func (q Quiz) FormatSelection(selected string) string {
+	switch q.GetType() {
+	case MultipleChoice:
+		key := normalizeKey(selected)
+		if option, ok := q.Options[key]; ok {
+			return fmt.Sprintf("%s. %s", strings.ToUpper(key), option)
+		}
+	case MultiSelect:
+		return strings.Join(utils.Each(splitKeys(selected), func(key string) string {
+			return fmt.Sprintf("%s. %s", strings.ToUpper(key), q.Options[key])
+		}), "\n")
+	case Ordering:
+		return strings.Join(utils.Each(splitKeys(selected), func(key string) string {
+			return q.Options[key]
+		}), " -> ")
+	}
+
+	return selected
+}
+
+// Normalize lowercases the answer keys, it should be called after the question is validated
+func (q Quiz) Normalize() Quiz {
+	q.Type = q.GetType()
+
+	switch q.Type {
+	case MultipleChoice, TrueFalse:
+		q.Answer = normalizeKey(q.Answer)
+	case MultiSelect:
+		q.Answers = splitKeys(strings.Join(q.Answers, ","))
+	case Ordering:
+		q.Answers = splitKeys(strings.Join(q.Answers, ","))
+		q = q.shuffleOrdering()
+	}
+
+	return q
+}
+
+// shuffleOrdering relabels the items if the correct sequence is the alphabetical order,
+// otherwise the key order of the options would leak the answer
+func (q Quiz) shuffleOrdering() Quiz {
+	if strings.Join(q.Answers, ",") != strings.Join(q.Options.Keys(), ",") {
+		return q
+	}
+
+	keys := q.Options.Keys()
+	labels := make([]string, len(keys))
+	copy(labels, keys)
+	for i := 0; i < 3 && strings.Join(labels, ",") == strings.Join(keys, ","); i++ {
+		rand.Shuffle(len(labels), func(i, j int) {
+			labels[i], labels[j] = labels[j], labels[i]
+		})
+	}
+
+	options := QuizOption{}
+	answers := make([]string, 0, len(keys))
+	for i, key := range keys {
+		options[labels[i]] = q.Options[key]
+		answers = append(answers, labels[i])
+	}
+
+	q.Options = options
+	q.Answers = answers
+	return q
+}
+
+// AnswerKey returns the readable correct answer of the question
+func (q Quiz) AnswerKey() string {
+	switch q.GetType() {
+	case MultiSelect, Ordering:
+		return strings.Join(q.Answers, ",")
+	case FillBlank:
+		return strings.Join(q.Answers, " / ")
+	case ShortAnswer:
+		return strings.Join(q.Rubric, "; ")
+	}
+
+	return q.Answer
+}
+
+// NormalizeSelection normalizes the user selection before it is stored
+func (q Quiz) NormalizeSelection(selected string) string {
+	switch q.GetType() {
+	case MultipleChoice, TrueFalse:
+		return normalizeKey(selected)
+	case MultiSelect, Ordering:
+		return strings.Join(splitKeys(selected), ",")
+	}
+
+	return strings.TrimSpace(selected)
+}
+
+// IsCorrect grades the selection against the question,
+// multi_select and ordering selections are comma separated keys
+func (q Quiz) IsCorrect(selected string) bool {
+	if len(strings.TrimSpace(selected)) == 0 {
+		return false
+	}
+
+	switch q.GetType() {
+	case MultipleChoice, TrueFalse:
+		return normalizeKey(selected) == normalizeKey(q.Answer)
+	case MultiSelect:
+		keys := splitKeys(selected)
+		answers := splitKeys(strings.Join(q.Answers, ","))
+		sort.Strings(keys)
+		sort.Strings(answers)
+		return strings.Join(keys, ",") == strings.Join(answers, ",")
+	case Ordering:
+		return strings.Join(splitKeys(selected), ",") == strings.Join(splitKeys(strings.Join(q.Answers, ",")), ",")
+	case FillBlank:
+		for _, accepted := range q.Answers {
+			if normalizeText(selected) == normalizeText(accepted) {
+				return true
+			}
+		}
+		return false
+	case ShortAnswer:
+		return gradeRubric(selected, q.Rubric)
+	}
+
+	return false
+}
+
+// gradeRubric grades a short answer deterministically, a key point is hit if the answer contains
+// the point or at least half of its keywords, the answer is correct if at least half of the points are hit
+func gradeRubric(answer string, rubric []string) bool {
+	if len(rubric) == 0 {
+		return false
+	}
+
+	answer = normalizeText(answer)
+	hit := 0
+	for _, point := range rubric {
+		if hitRubricPoint(answer, normalizeText(point)) {
+			hit++
+		}
+	}
+
+	return hit*2 >= len(rubric)
+}
+
+func hitRubricPoint(answer string, point string) bool {
+	if len(point) == 0 || strings.Contains(answer, point) {
+		return true
+	}
+
+	keywords := 0
+	matched := 0
+	for _, word := range strings.Fields(point) {
+		stem := []rune(strings.Trim(word, ".,;:!?\"'()"))
+		if len(stem) < 4 {
+			// skip short words like articles and prepositions
+			continue
+		}
+
+		// compare the stem to tolerate simple suffixes like -s, -ed and -es
+		if len(stem) > 5 {
+			stem = stem[:len(stem)-2]
+		}
+
+		keywords++
+		if strings.Contains(answer, string(stem)) {
+			matched++
+		}
+	}
+
+	return keywords > 0 && matched*2 >= keywords
+}
+
+// buildTypesPrompt describes the json structure of the requested question types
+func buildTypesPrompt(types []string) string {
+	var builder strings.Builder
+
+	if len(types) == 1 {
+		builder.WriteString(fmt.Sprintf("Every question must be of type %s and follow this structure:\n", types[0]))
+	} else {
+		builder.WriteString(fmt.Sprintf("Use a mix of the following question types: %s. Each type follows its structure:\n", strings.Join(types, ", ")))
+	}
+
+	for _, t := range types {
+		builder.WriteString(questionSchemas[t])
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// QuizQuestion represents a quiz question without its answer and the answer explanation
+type QuizQuestion struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Question  string         `json:"question"`
+	Options   QuizOption     `json:"options,omitempty"`
+	Resources []QuizResource `json:"resources,omitempty"`
+}
+
+func (q Quiz) Hide() QuizQuestion {
+	return QuizQuestion{
+		ID:        q.ID,
+		Type:      q.GetType(),
+		Question:  q.Question,
+		Options:   q.Options,
+		Resources: q.Resources,
+	}
+}
+
+func HideQuizzes(quizzes []Quiz) []QuizQuestion {
+	return utils.Each(quizzes, func(q Quiz) QuizQuestion {
+		return q.Hide()
+	})
+}
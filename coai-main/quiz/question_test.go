@@ -0,0 +1,37 @@
+package quiz
+
+import (
+	"chat/utils"
+	"strings"
+	"testing"
+)
+
+func TestHideQuizzes(t *testing.T) {
+	quizzes := append(newRoundTripQuiz().Data, Quiz{
+		ID:          "5",
+		Type:        ShortAnswer,
+		Question:    "Why do cells divide?",
+		Description: "Cells divide to grow and to repair tissue.",
+		ModelAnswer: "Cells divide so the organism can grow and repair damaged tissue.",
+		Rubric:      []string{"growth", "repair"},
+	})
+
+	data := utils.Marshal(HideQuizzes(quizzes))
+	for _, q := range quizzes {
+		for _, secret := range []string{q.Description, q.ModelAnswer} {
+			if len(secret) > 0 && strings.Contains(data, secret) {
+				t.Errorf("question %s: hidden question leaks %q", q.ID, secret)
+			}
+		}
+	}
+	for _, field := range []string{`"answer"`, `"answers"`, `"rubric"`, `"explanations"`, `"description"`, `"model_answer"`} {
+		if strings.Contains(data, field) {
+			t.Errorf("hidden questions contain the %s field", field)
+		}
+	}
+
+	_, results := GradeAttempt(quizzes, map[string]string{"5": "they grow"})
+	if last := results[len(results)-1]; last.Description != quizzes[4].Description || last.ModelAnswer != quizzes[4].ModelAnswer {
+		t.Errorf("expected the graded result to carry the explanation and the model answer, got %+v", last)
+	}
+}
@@ -0,0 +1,128 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuizRelayForm is the request body of the quiz generation relay api,
+// the stream field switches the response to server-sent events
+type QuizRelayForm struct {
+	QuizGenerationRequest
+	Stream bool `json:"stream"`
+}
+
+func sendRelayError(c *gin.Context, code int, err error, t string) {
+	c.AbortWithStatusJSON(code, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    t,
+		},
+	})
+}
+
+// getRelayStatus maps the error type of the failed generation to the http status code
+func getRelayStatus(t string) int {
+	switch t {
+	case InvalidRequestError:
+		return http.StatusBadRequest
+	case PermissionError:
+		return http.StatusForbidden
+	case QuotaExceededError:
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+// GenerationRelayAPI handles quiz generation via REST, authenticated by the api key of the authorization header
+func GenerationRelayAPI(c *gin.Context) {
+	if globals.CloseRelay {
+		sendRelayError(c, http.StatusForbidden, fmt.Errorf("relay api is denied of access"), "access_denied_error")
+		return
+	}
+
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		sendRelayError(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid api key"), "authentication_error")
+		return
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		sendRelayError(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+		return
+	}
+
+	var form QuizRelayForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		sendRelayError(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	if form.Model == "" {
+		sendRelayError(c, http.StatusBadRequest, fmt.Errorf("model is required"), "invalid_request_error")
+		return
+	}
+
+	user := &auth.User{
+		Username: username,
+	}
+
+	if form.Stream {
+		sendStreamGenerationResponse(c, user, &form.QuizGenerationRequest)
+	} else {
+		sendGenerationResponse(c, user, &form.QuizGenerationRequest)
+	}
+}
+
+func sendGenerationResponse(c *gin.Context, user *auth.User, form *QuizGenerationRequest) {
+	var result QuizGenerationResponse
+	processQuizGeneration(c, user, form, func(response QuizGenerationResponse) {
+		if response.End {
+			result = response
+		}
+	})
+
+	if result.Error != "" {
+		sendRelayError(c, getRelayStatus(result.Type), errors.New(result.Message), utils.Multi(result.Type != "", result.Type, GenerationError))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func sendStreamGenerationResponse(c *gin.Context, user *auth.User, form *QuizGenerationRequest) {
+	partial := make(chan QuizGenerationResponse, 16)
+
+	// the generation may outlive the request if the client disconnects
+	ctx := c.Copy()
+	go func() {
+		processQuizGeneration(ctx, user, form, func(response QuizGenerationResponse) {
+			partial <- response
+		})
+		close(partial)
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		if resp, ok := <-partial; ok {
+			c.Render(-1, utils.NewEvent(resp))
+			return true
+		}
+
+		c.Render(-1, utils.NewEndEvent())
+		return false
+	})
+
+	// keep consuming the frames of a disconnected client, the generation is still billed
+	go func() {
+		for range partial {
+		}
+	}()
+}
@@ -0,0 +1,259 @@
+package quiz
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultEase  = 2.5
+	minEase      = 1.3
+	reviewLimit  = 20 // default question count of a review session
+	maxReviewCap = 100
+)
+
+// ReviewItem represents the spaced-repetition schedule of a saved quiz question
+type ReviewItem struct {
+	QuizId      int64     `json:"quiz_id"`
+	QuestionId  string    `json:"question_id"`
+	Ease        float64   `json:"ease"`
+	Interval    int       `json:"interval"` // days until the next review
+	Repetitions int       `json:"repetitions"`
+	DueAt       time.Time `json:"due_at"`
+}
+
+// ReviewQuestion represents a due question of the review session
+type ReviewQuestion struct {
+	QuizId   int64        `json:"quiz_id"`
+	QuizName string       `json:"quiz_name"`
+	Question QuizQuestion `json:"question"`
+	Schedule ReviewItem   `json:"schedule"`
+}
+
+// ReviewResult represents the graded review of a question with its next schedule
+type ReviewResult struct {
+	Correct  bool       `json:"correct"`
+	Answer   string     `json:"answer"`
+	Schedule ReviewItem `json:"schedule"`
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// Schedule updates the item with the SM-2 algorithm, quality is the recall quality from 0 to 5
+func (r *ReviewItem) Schedule(quality int, now time.Time) {
+	if quality < 0 {
+		quality = 0
+	} else if quality > 5 {
+		quality = 5
+	}
+
+	if quality < 3 {
+		r.Repetitions = 0
+		r.Interval = 1
+	} else {
+		r.Repetitions++
+		switch r.Repetitions {
+		case 1:
+			r.Interval = 1
+		case 2:
+			r.Interval = 6
+		default:
+			r.Interval = int(math.Round(float64(r.Interval) * r.Ease))
+		}
+	}
+
+	diff := float64(5 - quality)
+	r.Ease = math.Max(minEase, r.Ease+0.1-diff*(0.08+diff*0.02))
+	r.DueAt = now.AddDate(0, 0, r.Interval)
+}
+
+func LoadReviewItem(db *sql.DB, userId int64, quizId int64, questionId string) *ReviewItem {
+	item := ReviewItem{
+		QuizId:     quizId,
+		QuestionId: questionId,
+	}
+
+	var due []uint8
+	if err := globals.QueryRowDb(db, `
+		SELECT ease, review_interval, repetitions, due_at FROM quiz_review
+		WHERE user_id = ? AND quiz_id = ? AND question_id = ?
+	`, userId, quizId, questionId).Scan(&item.Ease, &item.Interval, &item.Repetitions, &due); err != nil {
+		return nil
+	}
+
+	if t := utils.ConvertTime(due); t != nil {
+		item.DueAt = *t
+	}
+
+	return &item
+}
+
+func saveReviewItem(db *sql.DB, userId int64, item *ReviewItem, exists bool) error {
+	if exists {
+		_, err := globals.ExecDb(db, `
+			UPDATE quiz_review SET ease = ?, review_interval = ?, repetitions = ?, due_at = ?
+			WHERE user_id = ? AND quiz_id = ? AND question_id = ?
+		`, item.Ease, item.Interval, item.Repetitions, formatTime(item.DueAt), userId, item.QuizId, item.QuestionId)
+		return err
+	}
+
+	_, err := globals.ExecDb(db, `
+		INSERT INTO quiz_review (user_id, quiz_id, question_id, ease, review_interval, repetitions, due_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userId, item.QuizId, item.QuestionId, item.Ease, item.Interval, item.Repetitions, formatTime(item.DueAt))
+	return err
+}
+
+// EnqueueReview puts the missed question into the review queue, the schedule
+// of a question already in the queue is reset to be reviewed now
+func EnqueueReview(db *sql.DB, userId int64, quizId int64, questionId string) error {
+	item := LoadReviewItem(db, userId, quizId, questionId)
+	exists := item != nil
+	if !exists {
+		item = &ReviewItem{
+			QuizId:     quizId,
+			QuestionId: questionId,
+			Ease:       defaultEase,
+		}
+	}
+
+	item.Repetitions = 0
+	item.Interval = 0
+	item.DueAt = time.Now()
+
+	if err := saveReviewItem(db, userId, item, exists); err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during enqueue review: %s", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueMissedQuestions puts the incorrect questions of the graded attempt into the review queue
+func EnqueueMissedQuestions(db *sql.DB, userId int64, quizId int64, results []QuestionResult) {
+	for _, result := range results {
+		if !result.Correct {
+			_ = EnqueueReview(db, userId, quizId, result.ID)
+		}
+	}
+}
+
+func deleteReviewItem(db *sql.DB, userId int64, quizId int64, questionId string) {
+	_, _ = globals.ExecDb(db, `
+		DELETE FROM quiz_review WHERE user_id = ? AND quiz_id = ? AND question_id = ?
+	`, userId, quizId, questionId)
+}
+
+func DeleteQuizReviews(db *sql.DB, userId int64, quizId int64) error {
+	_, err := globals.ExecDb(db, "DELETE FROM quiz_review WHERE user_id = ? AND quiz_id = ?", userId, quizId)
+	return err
+}
+
+// LoadDueReviews assembles a review session from the due questions across all quizzes of the user,
+// the schedules of deleted quizzes or questions are removed from the queue
+func LoadDueReviews(db *sql.DB, userId int64, limit int) []ReviewQuestion {
+	if limit <= 0 {
+		limit = reviewLimit
+	} else if limit > maxReviewCap {
+		limit = maxReviewCap
+	}
+
+	items := make([]ReviewItem, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT quiz_id, question_id, ease, review_interval, repetitions, due_at FROM quiz_review
+		WHERE user_id = ? AND due_at <= ?
+		ORDER BY due_at ASC LIMIT ?
+	`, userId, formatTime(time.Now()), limit)
+	if err != nil {
+		return []ReviewQuestion{}
+	}
+
+	for rows.Next() {
+		var (
+			item ReviewItem
+			due  []uint8
+		)
+		if err := rows.Scan(&item.QuizId, &item.QuestionId, &item.Ease, &item.Interval, &item.Repetitions, &due); err != nil {
+			continue
+		}
+
+		if t := utils.ConvertTime(due); t != nil {
+			item.DueAt = *t
+		}
+		items = append(items, item)
+	}
+	_ = rows.Close()
+
+	quizzes := map[int64]*SavedQuiz{}
+	result := make([]ReviewQuestion, 0, len(items))
+	for _, item := range items {
+		quiz, ok := quizzes[item.QuizId]
+		if !ok {
+			quiz = LoadQuiz(db, userId, item.QuizId)
+			quizzes[item.QuizId] = quiz
+		}
+
+		var question *Quiz
+		if quiz != nil {
+			question = findQuestion(quiz.Data, item.QuestionId)
+		}
+
+		if question == nil {
+			deleteReviewItem(db, userId, item.QuizId, item.QuestionId)
+			continue
+		}
+
+		result = append(result, ReviewQuestion{
+			QuizId:   quiz.Id,
+			QuizName: quiz.Name,
+			Question: question.Hide(),
+			Schedule: item,
+		})
+	}
+
+	return result
+}
+
+// GradeReview grades the answer of the reviewed question and updates its schedule,
+// quality is the optional self-rated recall quality (3 to 5) of a correct answer
+func GradeReview(db *sql.DB, userId int64, quizId int64, questionId string, answer string, quality int) (*ReviewResult, error) {
+	item := LoadReviewItem(db, userId, quizId, questionId)
+	if item == nil {
+		return nil, fmt.Errorf("review item not found")
+	}
+
+	quiz := LoadQuiz(db, userId, quizId)
+	if quiz == nil {
+		return nil, fmt.Errorf("quiz not found")
+	}
+
+	question := findQuestion(quiz.Data, questionId)
+	if question == nil {
+		return nil, fmt.Errorf("question not found")
+	}
+
+	correct := question.IsCorrect(answer)
+	switch {
+	case !correct:
+		quality = 1
+	case quality < 3 || quality > 5:
+		quality = 4
+	}
+
+	item.Schedule(quality, time.Now())
+	if err := saveReviewItem(db, userId, item, true); err != nil {
+		return nil, err
+	}
+
+	return &ReviewResult{
+		Correct:  correct,
+		Answer:   question.AnswerKey(),
+		Schedule: *item,
+	}, nil
+}
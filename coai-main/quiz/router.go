@@ -4,8 +4,74 @@ import "github.com/gin-gonic/gin"
 
 // Register registers quiz routes
 func Register(app *gin.RouterGroup) {
+	app.POST("/v1/quiz/generations", GenerationRelayAPI)
+
+	// prompt templates, the admin routes are guarded by the auth middleware
+	app.GET("/admin/quiz/template/list", ListAllTemplateAPI)
+	app.POST("/admin/quiz/template/update", UpdateTemplateAPI)
+
 	group := app.Group("/quiz")
 	{
 		group.GET("/generate", GenerateQuizAPI)
+		group.GET("/cost", CostAPI)
+		group.GET("/template/list", ListTemplateAPI)
+		group.POST("/template/preview", PreviewTemplateAPI)
+
+		// library
+		group.GET("/list", ListAPI)
+		group.GET("/load", LoadAPI)
+		group.POST("/rename", RenameAPI)
+		group.GET("/delete", DeleteAPI)
+		group.GET("/export", ExportAPI)
+		group.POST("/import", ImportAPI)
+		group.POST("/translate", TranslateAPI)
+		group.GET("/variants", VariantsAPI)
+
+		// attempt
+		group.POST("/attempt/start", StartAttemptAPI)
+		group.POST("/attempt/submit", SubmitAnswerAPI)
+		group.POST("/attempt/finish", FinishAttemptAPI)
+		group.GET("/attempt/view", ViewAttemptAPI)
+		group.GET("/attempt/history", AttemptHistoryAPI)
+		group.POST("/explain", ExplainAPI)
+
+		// adaptive
+		group.GET("/adaptive", AdaptiveAPI)
+		group.GET("/ability/list", AbilityListAPI)
+
+		// review
+		group.GET("/review/due", DueReviewAPI)
+		group.POST("/review/grade", GradeReviewAPI)
+
+		// share
+		group.POST("/share", ShareAPI)
+		group.GET("/view", ViewAPI)
+		group.GET("/share/list", ListSharingAPI)
+		group.GET("/share/delete", DeleteSharingAPI)
+		group.GET("/share/results", SharingResultsAPI)
+		group.POST("/share/start", StartSharedAttemptAPI)
+		group.POST("/share/submit", SubmitSharedAnswerAPI)
+		group.POST("/share/finish", FinishSharedAttemptAPI)
+
+		// live
+		group.GET("/live/host", LiveHostAPI)
+		group.GET("/live/join", LiveJoinAPI)
+
+		// classroom
+		group.POST("/classroom/create", CreateClassroomAPI)
+		group.GET("/classroom/list", ListClassroomAPI)
+		group.GET("/classroom/delete", DeleteClassroomAPI)
+		group.GET("/classroom/reset", ResetClassroomCodeAPI)
+		group.POST("/classroom/join", JoinClassroomAPI)
+		group.GET("/classroom/leave", LeaveClassroomAPI)
+		group.GET("/classroom/roster", RosterAPI)
+		group.POST("/classroom/remove", RemoveMemberAPI)
+		group.POST("/classroom/assign", AssignAPI)
+		group.GET("/classroom/assignments", ListAssignmentAPI)
+		group.GET("/classroom/unassign", UnassignAPI)
+		group.POST("/classroom/attempt/start", StartAssignmentAPI)
+		group.POST("/classroom/attempt/submit", SubmitAssignmentAnswerAPI)
+		group.POST("/classroom/attempt/finish", FinishAssignmentAttemptAPI)
+		group.GET("/classroom/gradebook", GradebookAPI)
 	}
 }
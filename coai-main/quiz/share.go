@@ -0,0 +1,450 @@
+package quiz
+
+import (
+	"chat/admin"
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const anonymousName = "anonymous"
+
+// SharedQuizPreview represents a share link in the owner's share list
+type SharedQuizPreview struct {
+	Hash     string    `json:"hash"`
+	QuizId   int64     `json:"quiz_id"`
+	Name     string    `json:"name"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// SharedQuiz represents the public take-only view of a shared quiz
+type SharedQuiz struct {
+	Hash      string         `json:"hash"`
+	Username  string         `json:"username"`
+	Name      string         `json:"name"`
+	Topic     string         `json:"topic"`
+	Questions []QuizQuestion `json:"questions"`
+	Time      time.Time      `json:"time"`
+
+	quiz *SavedQuiz
+}
+
+// SharedAttempt represents an anonymous or logged-in attempt against a share link,
+// the token authorizes the following submissions of the attempt
+type SharedAttempt struct {
+	Id         int64             `json:"id"`
+	Hash       string            `json:"hash"`
+	Token      string            `json:"token"`
+	Name       string            `json:"name"`
+	Answers    map[string]string `json:"answers"`
+	Score      int               `json:"score"`
+	Total      int               `json:"total"`
+	Finished   bool              `json:"finished"`
+	StartedAt  *time.Time        `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at"`
+}
+
+// SharedAttemptPreview represents an attempt in the aggregate results of a share link
+type SharedAttemptPreview struct {
+	Name     string     `json:"name"`
+	Score    int        `json:"score"`
+	Total    int        `json:"total"`
+	Finished bool       `json:"finished"`
+	Duration int64      `json:"duration"`
+	Time     *time.Time `json:"time"`
+}
+
+// QuestionStat represents the correct rate of a question across the finished attempts
+type QuestionStat struct {
+	ID       string  `json:"id"`
+	Question string  `json:"question"`
+	Correct  int     `json:"correct"`
+	Rate     float64 `json:"rate"`
+}
+
+// SharedQuizResults represents the aggregate results of a share link
+type SharedQuizResults struct {
+	Hash      string                 `json:"hash"`
+	Name      string                 `json:"name"`
+	Attempts  int                    `json:"attempts"`
+	Finished  int                    `json:"finished"`
+	Average   float64                `json:"average"` // average score rate of the finished attempts
+	Questions []QuestionStat         `json:"questions"`
+	Records   []SharedAttemptPreview `json:"records"`
+}
+
+// ShareQuiz creates a new share link of the saved quiz, a quiz can have several links
+func ShareQuiz(db *sql.DB, user *auth.User, id int64) (string, error) {
+	if user == nil {
+		return "", fmt.Errorf("user not found")
+	}
+
+	userId := user.GetID(db)
+	if LoadQuiz(db, userId, id) == nil {
+		return "", fmt.Errorf("quiz not found")
+	}
+
+	hash := utils.Md5Encrypt(fmt.Sprintf("%d:%d:%s", userId, id, utils.GenerateChar(16)))
+	if _, err := globals.ExecDb(db, `
+		INSERT INTO quiz_sharing (hash, user_id, quiz_id) VALUES (?, ?, ?)
+	`, hash, userId, id); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func ListSharedQuiz(db *sql.DB, user *auth.User) []SharedQuizPreview {
+	result := make([]SharedQuizPreview, 0)
+	if user == nil {
+		return result
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT quiz_sharing.hash, quiz_sharing.quiz_id, quiz.quiz_name, quiz_sharing.updated_at,
+		       (SELECT COUNT(*) FROM quiz_share_attempt WHERE quiz_share_attempt.hash = quiz_sharing.hash)
+		FROM quiz_sharing
+		INNER JOIN quiz ON quiz.id = quiz_sharing.quiz_id AND quiz.user_id = quiz_sharing.user_id
+		WHERE quiz_sharing.user_id = ?
+		ORDER BY quiz_sharing.updated_at DESC
+		LIMIT 100
+	`, user.GetID(db))
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			form    SharedQuizPreview
+			updated []uint8
+		)
+		if err := rows.Scan(&form.Hash, &form.QuizId, &form.Name, &updated, &form.Attempts); err != nil {
+			continue
+		}
+
+		if t := utils.ConvertTime(updated); t != nil {
+			form.Time = *t
+		}
+		result = append(result, form)
+	}
+
+	return result
+}
+
+// DeleteSharedQuiz revokes the share link and removes its attempts
+func DeleteSharedQuiz(db *sql.DB, user *auth.User, hash string) error {
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	res, err := globals.ExecDb(db, `
+		DELETE FROM quiz_sharing WHERE user_id = ? AND hash = ?
+	`, user.GetID(db), hash)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("share link not found")
+	}
+
+	_, err = globals.ExecDb(db, "DELETE FROM quiz_share_attempt WHERE hash = ?", hash)
+	return err
+}
+
+// DeleteQuizSharing revokes all share links of the quiz
+func DeleteQuizSharing(db *sql.DB, userId int64, quizId int64) error {
+	if _, err := globals.ExecDb(db, `
+		DELETE FROM quiz_share_attempt WHERE hash IN (
+			SELECT hash FROM quiz_sharing WHERE user_id = ? AND quiz_id = ?
+		)
+	`, userId, quizId); err != nil {
+		return err
+	}
+
+	_, err := globals.ExecDb(db, "DELETE FROM quiz_sharing WHERE user_id = ? AND quiz_id = ?", userId, quizId)
+	return err
+}
+
+// GetSharedQuiz returns the public view of the shared quiz, the answers are hidden
+func GetSharedQuiz(db *sql.DB, hash string) (*SharedQuiz, error) {
+	var (
+		shared  SharedQuiz
+		userId  int64
+		quizId  int64
+		updated []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT auth.username, quiz_sharing.user_id, quiz_sharing.quiz_id, quiz_sharing.updated_at
+		FROM quiz_sharing
+		INNER JOIN auth ON auth.id = quiz_sharing.user_id
+		WHERE quiz_sharing.hash = ?
+	`, hash).Scan(&shared.Username, &userId, &quizId, &updated); err != nil {
+		return nil, fmt.Errorf("share link not found")
+	}
+
+	quiz := LoadQuiz(db, userId, quizId)
+	if quiz == nil {
+		return nil, fmt.Errorf("quiz not found")
+	}
+
+	shared.Hash = hash
+	shared.Name = quiz.Name
+	shared.Topic = quiz.Topic
+	shared.Questions = HideQuizzes(quiz.Data)
+	shared.quiz = quiz
+	if t := utils.ConvertTime(updated); t != nil {
+		shared.Time = *t
+	}
+
+	return &shared, nil
+}
+
+// StartSharedAttempt starts an attempt against the share link, the user is nil for anonymous attempts
+func StartSharedAttempt(db *sql.DB, user *auth.User, hash string, name string) (*SharedAttempt, *SharedQuiz, error) {
+	shared, err := GetSharedQuiz(db, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var userId interface{}
+	if user != nil {
+		userId = user.GetID(db)
+		name = user.Username
+	} else if name = utils.Extract(strings.TrimSpace(name), 24, ""); len(name) == 0 {
+		name = anonymousName
+	}
+
+	token := utils.GenerateChar(32)
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz_share_attempt (hash, user_id, token, name, answers, total) VALUES (?, ?, ?, ?, ?, ?)
+	`, hash, userId, token, name, "{}", len(shared.quiz.Data))
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during start shared attempt: %s", err.Error()))
+		return nil, nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &SharedAttempt{
+		Id:      id,
+		Hash:    hash,
+		Token:   token,
+		Name:    name,
+		Answers: map[string]string{},
+		Total:   len(shared.quiz.Data),
+	}, shared, nil
+}
+
+func LoadSharedAttempt(db *sql.DB, id int64, token string) *SharedAttempt {
+	return scanSharedAttempt(globals.QueryRowDb(db, `
+		SELECT token, hash, name, answers, score, total, finished, created_at, finished_at FROM quiz_share_attempt
+		WHERE id = ? AND token = ?
+	`, id, token), id)
+}
+
+// LoadUserSharedAttempt loads the shared attempt of the logged-in user without the token
+func LoadUserSharedAttempt(db *sql.DB, userId int64, id int64) *SharedAttempt {
+	return scanSharedAttempt(globals.QueryRowDb(db, `
+		SELECT token, hash, name, answers, score, total, finished, created_at, finished_at FROM quiz_share_attempt
+		WHERE id = ? AND user_id = ?
+	`, id, userId), id)
+}
+
+func scanSharedAttempt(row *sql.Row, id int64) *SharedAttempt {
+	attempt := SharedAttempt{Id: id}
+
+	var (
+		answers           string
+		started, finished []uint8
+	)
+	if err := row.Scan(&attempt.Token, &attempt.Hash, &attempt.Name, &answers, &attempt.Score, &attempt.Total, &attempt.Finished, &started, &finished); err != nil {
+		return nil
+	}
+
+	attempt.Answers, _ = utils.UnmarshalString[map[string]string](answers)
+	if attempt.Answers == nil {
+		attempt.Answers = map[string]string{}
+	}
+
+	attempt.StartedAt = utils.ConvertTime(started)
+	attempt.FinishedAt = utils.ConvertTime(finished)
+
+	return &attempt
+}
+
+// SubmitAnswer records the selection of a question, each question can only be answered once
+func (a *SharedAttempt) SubmitAnswer(db *sql.DB, questionId string, selected string) (*QuestionResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	shared, err := GetSharedQuiz(db, a.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	question := findQuestion(shared.quiz.Data, questionId)
+	if question == nil {
+		return nil, fmt.Errorf("question not found")
+	}
+
+	if _, ok := a.Answers[questionId]; ok {
+		return nil, fmt.Errorf("question is already answered")
+	}
+
+	selected = question.NormalizeSelection(selected)
+	a.Answers[questionId] = selected
+
+	if _, err := globals.ExecDb(db, `
+		UPDATE quiz_share_attempt SET answers = ? WHERE id = ? AND token = ?
+	`, utils.Marshal(a.Answers), a.Id, a.Token); err != nil {
+		delete(a.Answers, questionId)
+		return nil, err
+	}
+
+	return &QuestionResult{
+		ID:          question.ID,
+		Selected:    selected,
+		Answer:      question.AnswerKey(),
+		Correct:     question.IsCorrect(selected),
+		Explanation: question.Explain(selected),
+		Description: question.Description,
+		ModelAnswer: question.ModelAnswer,
+	}, nil
+}
+
+// Finish grades the shared attempt and stores the score
+func (a *SharedAttempt) Finish(db *sql.DB) (*AttemptResult, error) {
+	if a.Finished {
+		return nil, fmt.Errorf("attempt is already finished")
+	}
+
+	shared, err := GetSharedQuiz(db, a.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	score, results := GradeAttempt(shared.quiz.Data, a.Answers)
+	if _, err := globals.ExecDb(db, `
+		UPDATE quiz_share_attempt SET score = ?, total = ?, finished = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND token = ?
+	`, score, len(shared.quiz.Data), true, a.Id, a.Token); err != nil {
+		return nil, err
+	}
+	admin.AnalyseQuizAttempt(score, len(shared.quiz.Data))
+
+	attempt := LoadSharedAttempt(db, a.Id, a.Token)
+	if attempt == nil {
+		return nil, fmt.Errorf("attempt not found")
+	}
+
+	result := AttemptResult{
+		Id:       attempt.Id,
+		QuizName: shared.Name,
+		Score:    score,
+		Total:    len(shared.quiz.Data),
+		Finished: true,
+		Results:  results,
+		Time:     attempt.StartedAt,
+	}
+	if attempt.StartedAt != nil && attempt.FinishedAt != nil {
+		result.Duration = int64(attempt.FinishedAt.Sub(*attempt.StartedAt).Seconds())
+	}
+
+	return &result, nil
+}
+
+// GetSharedQuizResults aggregates the attempts of the share link for the owner
+func GetSharedQuizResults(db *sql.DB, user *auth.User, hash string) (*SharedQuizResults, error) {
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var quizId int64
+	if err := globals.QueryRowDb(db, `
+		SELECT quiz_id FROM quiz_sharing WHERE user_id = ? AND hash = ?
+	`, user.GetID(db), hash).Scan(&quizId); err != nil {
+		return nil, fmt.Errorf("share link not found")
+	}
+
+	quiz := LoadQuiz(db, user.GetID(db), quizId)
+	if quiz == nil {
+		return nil, fmt.Errorf("quiz not found")
+	}
+
+	rows, err := globals.QueryDb(db, `
+		SELECT name, answers, score, total, finished, created_at, finished_at FROM quiz_share_attempt
+		WHERE hash = ?
+		ORDER BY id DESC
+	`, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := SharedQuizResults{
+		Hash:    hash,
+		Name:    quiz.Name,
+		Records: make([]SharedAttemptPreview, 0),
+	}
+
+	correct := make(map[string]int)
+	var rate float64
+	for rows.Next() {
+		var (
+			record            SharedAttemptPreview
+			answers           string
+			started, finished []uint8
+		)
+		if err := rows.Scan(&record.Name, &answers, &record.Score, &record.Total, &record.Finished, &started, &finished); err != nil {
+			continue
+		}
+
+		record.Time = utils.ConvertTime(started)
+		results.Attempts++
+
+		if record.Finished {
+			results.Finished++
+			if end := utils.ConvertTime(finished); record.Time != nil && end != nil {
+				record.Duration = int64(end.Sub(*record.Time).Seconds())
+			}
+			if record.Total > 0 {
+				rate += float64(record.Score) / float64(record.Total)
+			}
+
+			selections, _ := utils.UnmarshalString[map[string]string](answers)
+			_, graded := GradeAttempt(quiz.Data, selections)
+			for _, item := range graded {
+				if item.Correct {
+					correct[item.ID]++
+				}
+			}
+		}
+
+		results.Records = append(results.Records, record)
+	}
+
+	if results.Finished > 0 {
+		results.Average = rate / float64(results.Finished)
+	}
+
+	results.Questions = utils.Each(quiz.Data, func(q Quiz) QuestionStat {
+		stat := QuestionStat{ID: q.ID, Question: q.Question, Correct: correct[q.ID]}
+		if results.Finished > 0 {
+			stat.Rate = float64(stat.Correct) / float64(results.Finished)
+		}
+		return stat
+	})
+
+	return &results, nil
+}
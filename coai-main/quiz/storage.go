@@ -0,0 +1,201 @@
+package quiz
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultQuizName = "new quiz"
+
+// SavedQuiz represents a generated quiz stored in the quiz library
+type SavedQuiz struct {
+	Id         int64     `json:"id"`
+	UserId     int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Model      string    `json:"model"`
+	Topic      string    `json:"topic"`
+	Difficulty string    `json:"difficulty"`
+	Language   string    `json:"language"`
+	ParentId   int64     `json:"parent_id"` // id of the quiz it is translated from, 0 if it is the original
+	SourceHash string    `json:"source_hash"`
+	Data       []Quiz    `json:"data"`
+	Time       time.Time `json:"time"`
+}
+
+// SavedQuizPreview represents a quiz library entry without its questions
+type SavedQuizPreview struct {
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Model      string    `json:"model"`
+	Topic      string    `json:"topic"`
+	Difficulty string    `json:"difficulty"`
+	Language   string    `json:"language"`
+	ParentId   int64     `json:"parent_id"`
+	Count      int       `json:"count"`
+	Time       time.Time `json:"time"`
+}
+
+// GetSourceHash returns the hash of the source notes and files of the generation request
+func GetSourceHash(form QuizGenerationRequest) string {
+	return utils.Sha2Encrypt(form.Notes + strings.Join(form.Files, ""))
+}
+
+func getQuizName(form QuizGenerationRequest, quizzes []Quiz) string {
+	if topic := strings.TrimSpace(form.Topic); len(topic) > 0 {
+		return utils.Extract(topic, 50, "...")
+	}
+
+	if len(quizzes) > 0 && len(quizzes[0].Question) > 0 {
+		return utils.Extract(quizzes[0].Question, 50, "...")
+	}
+
+	return defaultQuizName
+}
+
+// SaveQuiz stores the generated quizzes into the library and returns the quiz id
+func SaveQuiz(db *sql.DB, userId int64, form QuizGenerationRequest, quizzes []Quiz) (int64, error) {
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz (user_id, quiz_name, model, topic, difficulty, language, source_hash, quiz_count, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userId, getQuizName(form, quizzes), form.Model, form.Topic, form.Difficulty, getLanguageName(form.Language),
+		GetSourceHash(form), len(quizzes), utils.Marshal(quizzes))
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during save quiz: %s", err.Error()))
+		return -1, err
+	}
+
+	return res.LastInsertId()
+}
+
+func LoadQuiz(db *sql.DB, userId int64, id int64) *SavedQuiz {
+	quiz := SavedQuiz{
+		Id:     id,
+		UserId: userId,
+	}
+
+	var (
+		data    string
+		updated []uint8
+	)
+	if err := globals.QueryRowDb(db, `
+		SELECT quiz_name, model, topic, difficulty, language, parent_id, source_hash, data, updated_at FROM quiz
+		WHERE user_id = ? AND id = ?
+	`, userId, id).Scan(&quiz.Name, &quiz.Model, &quiz.Topic, &quiz.Difficulty, &quiz.Language, &quiz.ParentId, &quiz.SourceHash, &data, &updated); err != nil {
+		return nil
+	}
+
+	quizzes, err := utils.Unmarshal[[]Quiz]([]byte(data))
+	if err != nil {
+		return nil
+	}
+
+	quiz.Data = quizzes
+	if t := utils.ConvertTime(updated); t != nil {
+		quiz.Time = *t
+	}
+
+	return &quiz
+}
+
+func LoadQuizList(db *sql.DB, userId int64) []SavedQuizPreview {
+	list := make([]SavedQuizPreview, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT id, quiz_name, model, topic, difficulty, language, parent_id, quiz_count, updated_at FROM quiz
+		WHERE user_id = ?
+		ORDER BY id DESC LIMIT 100
+	`, userId)
+	if err != nil {
+		return list
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			return
+		}
+	}(rows)
+
+	for rows.Next() {
+		var (
+			preview SavedQuizPreview
+			updated []uint8
+		)
+		if err := rows.Scan(&preview.Id, &preview.Name, &preview.Model, &preview.Topic, &preview.Difficulty, &preview.Language, &preview.ParentId, &preview.Count, &updated); err != nil {
+			continue
+		}
+
+		if t := utils.ConvertTime(updated); t != nil {
+			preview.Time = *t
+		}
+		list = append(list, preview)
+	}
+
+	return list
+}
+
+func (q *SavedQuiz) RenameQuiz(db *sql.DB, name string) bool {
+	name = utils.Extract(strings.TrimSpace(name), 50, "...")
+	if len(name) == 0 {
+		name = defaultQuizName
+	}
+
+	_, err := globals.ExecDb(db, `
+		UPDATE quiz SET quiz_name = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ? AND id = ?
+	`, name, q.UserId, q.Id)
+	if err != nil {
+		return false
+	}
+
+	q.Name = name
+	return true
+}
+
+func (q *SavedQuiz) DeleteQuiz(db *sql.DB) bool {
+	if err := DeleteQuizAttempts(db, q.UserId, q.Id); err != nil {
+		return false
+	}
+
+	if err := DeleteQuizReviews(db, q.UserId, q.Id); err != nil {
+		return false
+	}
+
+	if err := DeleteQuizSharing(db, q.UserId, q.Id); err != nil {
+		return false
+	}
+
+	if err := DeleteQuizAssignments(db, q.UserId, q.Id); err != nil {
+		return false
+	}
+
+	if err := q.unlinkVariants(db); err != nil {
+		return false
+	}
+
+	_, err := globals.ExecDb(db, "DELETE FROM quiz WHERE user_id = ? AND id = ?", q.UserId, q.Id)
+	return err == nil
+}
+
+// unlinkVariants promotes the first translation of the original quiz to the parent of the other translations
+func (q *SavedQuiz) unlinkVariants(db *sql.DB) error {
+	var next int64
+	if err := globals.QueryRowDb(db, `
+		SELECT id FROM quiz WHERE user_id = ? AND parent_id = ? ORDER BY id ASC LIMIT 1
+	`, q.UserId, q.Id).Scan(&next); err != nil {
+		// the quiz has no translations
+		return nil
+	}
+
+	if _, err := globals.ExecDb(db, `
+		UPDATE quiz SET parent_id = ? WHERE user_id = ? AND parent_id = ? AND id <> ?
+	`, next, q.UserId, q.Id, next); err != nil {
+		return err
+	}
+
+	_, err := globals.ExecDb(db, `
+		UPDATE quiz SET parent_id = 0 WHERE user_id = ? AND id = ?
+	`, q.UserId, next)
+	return err
+}
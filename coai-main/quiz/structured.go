@@ -0,0 +1,198 @@
+package quiz
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the generation declares the quiz schema as a function tool and forces the model to call it, so
+// that the tool-calling channels return the questions as the structured arguments. the channels
+// without tool calling receive the request without tools (see channel.getChannelProps) and the
+// prompt still asks for the json array, so both outputs pass the same validation.
+
+const quizToolName = "submit_quiz"
+
+type quizToolArguments struct {
+	Questions json.RawMessage `json:"questions"`
+}
+
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": description,
+	}
+}
+
+func stringArrayProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"description": description,
+		"items":       map[string]interface{}{"type": "string"},
+	}
+}
+
+func optionProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 "object",
+		"description":          description,
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}
+}
+
+// buildQuizTools returns the function tool whose parameters are the schema of the quiz questions
+func buildQuizTools(types []string) *globals.FunctionTools {
+	question := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":           stringProperty("unique id of the question"),
+			"type":         map[string]interface{}{"type": "string", "enum": types},
+			"question":     stringProperty("the question text"),
+			"description":  stringProperty("a short explanation of the answer"),
+			"options":      optionProperty("answer options keyed by a, b, c, ..."),
+			"answer":       stringProperty("the correct option key of multiple_choice questions, true or false of true_false questions"),
+			"answers":      stringArrayProperty("the correct option keys, the ordering sequence or the accepted fill_blank variants"),
+			"rubric":       stringArrayProperty("the key points of short_answer questions"),
+			"model_answer": stringProperty("the reference answer of short_answer questions"),
+			"explanations": optionProperty(
+				"why each option (or true and false) is correct or wrong, keyed by the option key",
+			),
+			"resources": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title": map[string]interface{}{"type": "string"},
+						"link":  map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"title", "link"},
+				},
+			},
+			"source": stringProperty("excerpt of the source the question is based on"),
+		},
+		"required": []string{"id", "type", "question", "description"},
+	}
+
+	return &globals.FunctionTools{
+		{
+			Type: "function",
+			Function: globals.ToolFunction{
+				Name:        quizToolName,
+				Description: "Submit the generated quiz questions.",
+				Parameters: globals.ToolParameters{
+					Type: "object",
+					Properties: globals.ToolProperties{
+						"questions": {
+							"type":  "array",
+							"items": question,
+						},
+					},
+					Required: &[]string{"questions"},
+				},
+			},
+		},
+	}
+}
+
+// getQuizToolChoice forces the model to call the quiz function
+func getQuizToolChoice() *interface{} {
+	return utils.ToPtr[interface{}](map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name": quizToolName,
+		},
+	})
+}
+
+// readQuizResponse returns the questions of the quiz function call as the json array,
+// the text of the buffer is returned if the model answered in prompt-only mode
+func readQuizResponse(buffer *utils.Buffer) string {
+	calls := buffer.GetToolCalls()
+	if calls == nil {
+		return buffer.Read()
+	}
+
+	for _, call := range *calls {
+		if call.Function.Name != quizToolName {
+			continue
+		}
+
+		var args quizToolArguments
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil || len(args.Questions) == 0 {
+			// truncated arguments are left to the salvage of the validation
+			return call.Function.Arguments
+		}
+		return string(args.Questions)
+	}
+
+	return buffer.Read()
+}
+
+// isToolUnsupportedError checks if the upstream rejected the request because of the tools
+func isToolUnsupportedError(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "tool") || strings.Contains(message, "function call")
+}
+
+// hasQuizToolCall checks if the buffer contains the arguments of the quiz function call
+func hasQuizToolCall(buffer *utils.Buffer) bool {
+	if buffer.ToolCalls == nil {
+		return false
+	}
+
+	for _, call := range *buffer.ToolCalls {
+		if call.Function.Name == quizToolName && len(strings.TrimSpace(call.Function.Arguments)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldFallbackQuiz checks if the structured request should be retried in prompt-only mode,
+// which is the case if the upstream rejected the tools or the model answered nothing
+func shouldFallbackQuiz(buffer *utils.Buffer, err error) bool {
+	if hasQuizToolCall(buffer) || len(strings.TrimSpace(buffer.Read())) > 0 {
+		return false
+	}
+	return err == nil || isToolUnsupportedError(err)
+}
+
+// requestStructuredQuiz requests the quiz questions with the quiz function tool, the request is
+// retried in prompt-only mode with the same buffer if the model or the upstream rejects the tools
+func requestStructuredQuiz(c *gin.Context, user *auth.User, form QuizGenerationRequest, messages []globals.Message, hook func(data *globals.Chunk, buffer *utils.Buffer)) (string, *utils.Buffer, error) {
+	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+	err := sendQuizRequest(c, user, form, &adaptercommon.ChatProps{
+		OriginalModel: form.Model,
+		Message:       messages,
+		Tools:         buildQuizTools(form.GetQuestionTypes()),
+		ToolChoice:    getQuizToolChoice(),
+		OptionalTools: true,
+	}, buffer, hook)
+
+	if shouldFallbackQuiz(buffer, err) {
+		reason := "empty tool call"
+		if err != nil {
+			reason = err.Error()
+		}
+		globals.Debug(fmt.Sprintf("[quiz] structured output failed (model: %s): %s, fallback to prompt-only mode", form.Model, reason))
+
+		// the input of the buffer is already metered, the retry only writes the output into it
+		buffer.SetToolCalls(nil)
+		err = sendQuizRequest(c, user, form, &adaptercommon.ChatProps{
+			OriginalModel: form.Model,
+			Message:       messages,
+		}, buffer, hook)
+	}
+
+	if err != nil {
+		return "", buffer, err
+	}
+	return readQuizResponse(buffer), buffer, nil
+}
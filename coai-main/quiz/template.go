@@ -0,0 +1,158 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the prompt templates replace the built-in tutor persona of the generation prompt. the template
+// prompt is rendered with the request variables, the notes, the questions to avoid and the json
+// format instructions are appended to it, so that the output can still be validated.
+
+const maxTemplateLength = 8000
+
+var TemplateVariables = []string{"topic", "difficulty", "count", "language", "notes"}
+
+// PromptTemplate represents a named quiz prompt template managed by the admins
+type PromptTemplate struct {
+	Id          string   `json:"id" mapstructure:"id"`
+	Name        string   `json:"name" mapstructure:"name"`
+	Description string   `json:"description" mapstructure:"description"`
+	System      string   `json:"system" mapstructure:"system"` // optional system message, e.g. the persona of the tutor
+	Prompt      string   `json:"prompt" mapstructure:"prompt"` // instructions with the {{variable}} placeholders
+	Groups      []string `json:"groups" mapstructure:"groups"` // permission groups the template is visible to, all groups if empty
+}
+
+type PromptTemplateList []PromptTemplate
+
+type TemplateStore struct {
+	Templates PromptTemplateList `json:"templates" mapstructure:"templates"`
+}
+
+var TemplateInstance *TemplateStore
+
+func InitTemplates() {
+	TemplateInstance = NewTemplateStore()
+}
+
+func NewTemplateStore() *TemplateStore {
+	var templates PromptTemplateList
+	if err := viper.UnmarshalKey("quiz_template", &templates); err != nil {
+		globals.Warn(fmt.Sprintf("[quiz] read template config error: %s, use default config", err.Error()))
+		templates = PromptTemplateList{}
+	}
+
+	return &TemplateStore{
+		Templates: templates,
+	}
+}
+
+func (s *TemplateStore) GetTemplates() PromptTemplateList {
+	if s == nil || s.Templates == nil {
+		return PromptTemplateList{}
+	}
+	return s.Templates
+}
+
+func (s *TemplateStore) GetTemplate(id string) *PromptTemplate {
+	for _, template := range s.GetTemplates() {
+		if template.Id == id {
+			return &template
+		}
+	}
+	return nil
+}
+
+// GetVisibleTemplates returns the templates visible to the permission group of the user
+func (s *TemplateStore) GetVisibleTemplates(db *sql.DB, user *auth.User) PromptTemplateList {
+	list := make(PromptTemplateList, 0)
+	for _, template := range s.GetTemplates() {
+		if template.IsVisible(db, user) {
+			list = append(list, template)
+		}
+	}
+	return list
+}
+
+func (s *TemplateStore) SaveConfig() error {
+	viper.Set("quiz_template", s.Templates)
+	return viper.WriteConfig()
+}
+
+func (s *TemplateStore) SetTemplates(templates PromptTemplateList) error {
+	ids := make(map[string]bool)
+	for i := range templates {
+		if err := templates[i].Validate(); err != nil {
+			return err
+		}
+		if ids[templates[i].Id] {
+			return fmt.Errorf("template id %q is used more than once", templates[i].Id)
+		}
+		ids[templates[i].Id] = true
+	}
+
+	s.Templates = templates
+	return s.SaveConfig()
+}
+
+// Validate trims the template fields and checks the template
+func (t *PromptTemplate) Validate() error {
+	t.Id = strings.TrimSpace(t.Id)
+	t.Name = strings.TrimSpace(t.Name)
+	t.System = strings.TrimSpace(t.System)
+	t.Prompt = strings.TrimSpace(t.Prompt)
+
+	if len(t.Id) == 0 {
+		return fmt.Errorf("template id is required")
+	}
+	if len(t.Name) == 0 {
+		t.Name = t.Id
+	}
+	if len(t.Prompt) == 0 {
+		return fmt.Errorf("prompt of template %q is required", t.Id)
+	}
+	if len(t.System)+len(t.Prompt) > maxTemplateLength {
+		return fmt.Errorf("template %q is longer than %d characters", t.Id, maxTemplateLength)
+	}
+	return nil
+}
+
+func (t *PromptTemplate) IsVisible(db *sql.DB, user *auth.User) bool {
+	return len(t.Groups) == 0 || auth.HitGroups(db, user, t.Groups)
+}
+
+// UsesVariable checks if the template contains the {{variable}} placeholder
+func (t *PromptTemplate) UsesVariable(name string) bool {
+	placeholder := fmt.Sprintf("{{%s}}", name)
+	return strings.Contains(t.System, placeholder) || strings.Contains(t.Prompt, placeholder)
+}
+
+// Render replaces the {{variable}} placeholders of the text with the request variables of the task
+func (t *PromptTemplate) Render(text string, form QuizGenerationRequest, task quizTask) string {
+	return strings.NewReplacer(
+		"{{topic}}", form.Topic,
+		"{{difficulty}}", form.Difficulty,
+		"{{count}}", strconv.Itoa(task.Count),
+		"{{language}}", getLanguageName(form.Language),
+		"{{notes}}", task.Source,
+	).Replace(text)
+}
+
+// loadQuizTemplate returns the template selected by the request, nil if no template is selected
+func loadQuizTemplate(db *sql.DB, user *auth.User, id string) (*PromptTemplate, error) {
+	if len(strings.TrimSpace(id)) == 0 {
+		return nil, nil
+	}
+
+	template := TemplateInstance.GetTemplate(strings.TrimSpace(id))
+	if template == nil || !template.IsVisible(db, user) {
+		return nil, fmt.Errorf("prompt template %q not found", id)
+	}
+	return template, nil
+}
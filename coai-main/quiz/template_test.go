@@ -0,0 +1,45 @@
+package quiz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildQuizPromptLanguage(t *testing.T) {
+	form := QuizGenerationRequest{Topic: "Photosynthesis", Difficulty: "Easy", Language: "French"}
+	task := quizTask{Count: 3}
+	instruction := "Write all the text of the questions in French"
+
+	cases := []struct {
+		name     string
+		template *PromptTemplate
+		expected int // occurrences of the language instruction
+	}{
+		{"persona", nil, 1},
+		{"template without language", &PromptTemplate{Id: "exam", Prompt: "Write {{count}} exam questions about {{topic}}."}, 1},
+		{"template with language", &PromptTemplate{Id: "exam", Prompt: "Write {{count}} questions about {{topic}} in {{language}}."}, 0},
+	}
+
+	for _, c := range cases {
+		prompt := buildQuizPrompt(form, task, c.template)
+		if count := strings.Count(prompt, instruction); count != c.expected {
+			t.Errorf("%s: expected %d language instructions, got %d:\n%s", c.name, c.expected, count, prompt)
+		}
+		if !strings.Contains(prompt, "French") {
+			t.Errorf("%s: the prompt does not mention the language:\n%s", c.name, prompt)
+		}
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	template := &PromptTemplate{Id: "exam", Prompt: "{{count}} {{difficulty}} questions on {{topic}} from {{notes}}"}
+	form := QuizGenerationRequest{Topic: "cells", Difficulty: "Hard"}
+
+	rendered := template.Render(template.Prompt, form, quizTask{Count: 4, Source: "the notes"})
+	if rendered != "4 Hard questions on cells from the notes" {
+		t.Errorf("unexpected rendered prompt: %q", rendered)
+	}
+	if !template.UsesVariable("notes") || template.UsesVariable("language") {
+		t.Errorf("unexpected variables of the template")
+	}
+}
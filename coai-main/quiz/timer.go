@@ -0,0 +1,244 @@
+package quiz
+
+import (
+	"chat/connection"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// every attempt records the elapsed time of each question, which is the time between the answer and
+// the previous answer (or the start of the attempt) as the questions are answered one by one. a timed
+// attempt has a deadline fixed at the start from the whole-quiz limit, the per-question limit and the
+// close time of the assignment. the answers after the deadline are rejected, the answers over the
+// per-question limit are recorded as unanswered and the sweeper submits the expired attempts.
+
+const (
+	maxAttemptTimeLimit  = 24 * 60 * 60 // seconds
+	maxQuestionTimeLimit = 60 * 60
+	sweepBatchSize       = 100
+)
+
+var sweepTick = time.Minute
+
+// AttemptTimer represents the time limits and the elapsed time of the questions of an attempt
+type AttemptTimer struct {
+	TimeLimit     int              `json:"time_limit"`     // seconds of the whole attempt, 0 for no limit
+	QuestionLimit int              `json:"question_limit"` // seconds per question, 0 for no limit
+	StartedAt     int64            `json:"started_at"`     // unix milliseconds
+	Deadline      int64            `json:"deadline"`       // unix milliseconds, 0 for untimed attempts
+	Elapsed       map[string]int64 `json:"elapsed"`        // question id -> elapsed milliseconds
+	Expired       bool             `json:"expired"`        // submitted after the deadline
+}
+
+// NewAttemptTimer starts the timer of an attempt with count questions, the deadline is capped by closeAt
+func NewAttemptTimer(timeLimit int, questionLimit int, count int, closeAt *time.Time) (*AttemptTimer, error) {
+	if timeLimit < 0 || timeLimit > maxAttemptTimeLimit {
+		return nil, fmt.Errorf("time limit must be between 0 and %d seconds", maxAttemptTimeLimit)
+	}
+	if questionLimit < 0 || questionLimit > maxQuestionTimeLimit {
+		return nil, fmt.Errorf("question time limit must be between 0 and %d seconds", maxQuestionTimeLimit)
+	}
+
+	now := time.Now().UnixMilli()
+	timer := &AttemptTimer{
+		TimeLimit:     timeLimit,
+		QuestionLimit: questionLimit,
+		StartedAt:     now,
+		Elapsed:       map[string]int64{},
+	}
+
+	deadlines := make([]int64, 0)
+	if timeLimit > 0 {
+		deadlines = append(deadlines, now+int64(timeLimit)*1000)
+	}
+	if questionLimit > 0 {
+		deadlines = append(deadlines, now+int64(questionLimit*count)*1000)
+	}
+	if closeAt != nil {
+		deadlines = append(deadlines, closeAt.UnixMilli())
+	}
+
+	for _, deadline := range deadlines {
+		if timer.Deadline == 0 || deadline < timer.Deadline {
+			timer.Deadline = deadline
+		}
+	}
+	return timer, nil
+}
+
+// loadAttemptTimer decodes the timer column, the attempts before the timers have an empty timer
+func loadAttemptTimer(raw sql.NullString) *AttemptTimer {
+	timer := &AttemptTimer{Elapsed: map[string]int64{}}
+	if raw.Valid && len(raw.String) > 0 {
+		if decoded, err := utils.UnmarshalString[AttemptTimer](raw.String); err == nil {
+			timer = &decoded
+		}
+	}
+
+	if timer.Elapsed == nil {
+		timer.Elapsed = map[string]int64{}
+	}
+	return timer
+}
+
+func (t *AttemptTimer) IsTimed() bool {
+	return t.Deadline > 0 || t.QuestionLimit > 0
+}
+
+func (t *AttemptTimer) IsExpired(now int64) bool {
+	return t.Deadline > 0 && now > t.Deadline
+}
+
+// Remaining returns the remaining seconds of the attempt, -1 for untimed attempts
+func (t *AttemptTimer) Remaining() int64 {
+	if t.Deadline == 0 {
+		return -1
+	}
+	return int64(math.Max(0, math.Ceil(float64(t.Deadline-time.Now().UnixMilli())/1000)))
+}
+
+func (t *AttemptTimer) lastAnsweredAt() int64 {
+	last := t.StartedAt
+	for _, elapsed := range t.Elapsed {
+		last += elapsed
+	}
+	return last
+}
+
+// Track records the elapsed time of the question answered now, an error is returned after the
+// deadline and timeout is true if the answer is over the per-question limit
+func (t *AttemptTimer) Track(questionId string) (timeout bool, err error) {
+	now := time.Now().UnixMilli()
+	if t.IsExpired(now) {
+		return false, fmt.Errorf("time is up, the attempt is over its deadline")
+	}
+
+	// the attempts before the timers have no start time
+	if t.StartedAt == 0 {
+		return false, nil
+	}
+
+	elapsed := now - t.lastAnsweredAt()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	t.Elapsed[questionId] = elapsed
+	return t.QuestionLimit > 0 && elapsed > int64(t.QuestionLimit)*1000, nil
+}
+
+// Apply sets the elapsed time of the graded questions
+func (t *AttemptTimer) Apply(results []QuestionResult) {
+	for i := range results {
+		results[i].Elapsed = t.Elapsed[results[i].ID]
+	}
+}
+
+// FormatElapsed formats the elapsed seconds of the questions as "id:seconds" separated by spaces
+func (t *AttemptTimer) FormatElapsed() string {
+	ids := make([]string, 0, len(t.Elapsed))
+	for id := range t.Elapsed {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s:%.1f", id, float64(t.Elapsed[id])/1000))
+	}
+	return strings.Join(parts, " ")
+}
+
+// SweepExpiredAttempts submits the unfinished attempts over their deadlines with the recorded answers
+func SweepExpiredAttempts(db *sql.DB) {
+	now := time.Now().UnixMilli()
+
+	rows, err := globals.QueryDb(db, `
+		SELECT id, user_id FROM quiz_attempt
+		WHERE finished = ? AND deadline > 0 AND deadline < ?
+		LIMIT ?
+	`, false, now, sweepBatchSize)
+	if err == nil {
+		expired := scanAttemptOwners(rows)
+		for _, item := range expired {
+			attempt := LoadAttempt(db, item[1], item[0])
+			if attempt == nil {
+				continue
+			}
+
+			quiz := LoadQuiz(db, attempt.UserId, attempt.QuizId)
+			if quiz == nil {
+				// the quiz is deleted, the attempt is closed without grading
+				_, _ = globals.ExecDb(db, "UPDATE quiz_attempt SET finished = ? WHERE id = ?", true, attempt.Id)
+				continue
+			}
+
+			if _, err := attempt.Finish(db, quiz); err != nil {
+				globals.Warn(fmt.Sprintf("[quiz] failed to submit expired attempt %d: %s", attempt.Id, err.Error()))
+			}
+		}
+	}
+
+	rows, err = globals.QueryDb(db, `
+		SELECT id, user_id FROM quiz_assignment_attempt
+		WHERE finished = ? AND deadline > 0 AND deadline < ?
+		LIMIT ?
+	`, false, now, sweepBatchSize)
+	if err == nil {
+		expired := scanAttemptOwners(rows)
+		for _, item := range expired {
+			attempt := LoadAssignmentAttempt(db, item[1], item[0])
+			if attempt == nil {
+				continue
+			}
+
+			if _, err := attempt.Finish(db); err != nil {
+				globals.Warn(fmt.Sprintf("[quiz] failed to submit expired assignment attempt %d: %s", attempt.Id, err.Error()))
+				_, _ = globals.ExecDb(db, "UPDATE quiz_assignment_attempt SET finished = ? WHERE id = ?", true, attempt.Id)
+			}
+		}
+	}
+}
+
+// scanAttemptOwners reads the (id, user id) pairs and closes the rows before the attempts are submitted
+func scanAttemptOwners(rows *sql.Rows) [][2]int64 {
+	defer rows.Close()
+
+	result := make([][2]int64, 0)
+	for rows.Next() {
+		var item [2]int64
+		if err := rows.Scan(&item[0], &item[1]); err != nil {
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// SweepWorker submits the expired attempts periodically
+func SweepWorker() {
+	go func() {
+		for {
+			if connection.DB != nil {
+				SweepExpiredAttempts(connection.DB)
+			}
+
+			time.Sleep(sweepTick)
+		}
+	}()
+}
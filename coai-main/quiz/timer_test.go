@@ -0,0 +1,83 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAttemptTimer(t *testing.T) {
+	if _, err := NewAttemptTimer(-1, 0, 5, nil); err == nil {
+		t.Errorf("expected the negative time limit to be refused")
+	}
+	if _, err := NewAttemptTimer(0, maxQuestionTimeLimit+1, 5, nil); err == nil {
+		t.Errorf("expected the question time limit over the maximum to be refused")
+	}
+
+	untimed, err := NewAttemptTimer(0, 0, 5, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if untimed.IsTimed() || untimed.Remaining() != -1 {
+		t.Errorf("expected an untimed attempt, got %+v", untimed)
+	}
+
+	// the deadline is the earliest of the time limit, the question limits and the close time
+	timer, err := NewAttemptTimer(600, 30, 5, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if deadline := timer.StartedAt + 150*1000; timer.Deadline != deadline {
+		t.Errorf("expected the deadline of the question limits %d, got %d", deadline, timer.Deadline)
+	}
+
+	closeAt := time.Now().Add(time.Minute)
+	timer, err = NewAttemptTimer(600, 0, 5, &closeAt)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if timer.Deadline != closeAt.UnixMilli() {
+		t.Errorf("expected the deadline to be capped by the close time, got %d", timer.Deadline)
+	}
+	if remaining := timer.Remaining(); remaining <= 0 || remaining > 60 {
+		t.Errorf("unexpected remaining seconds: %d", remaining)
+	}
+}
+
+func TestAttemptTimerTrack(t *testing.T) {
+	now := time.Now().UnixMilli()
+	timer := &AttemptTimer{
+		QuestionLimit: 10,
+		StartedAt:     now - 25*1000,
+		Deadline:      now + 60*1000,
+		Elapsed:       map[string]int64{"1": 5 * 1000},
+	}
+
+	// the second question is answered 20 seconds after the first one
+	timeout, err := timer.Track("2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !timeout {
+		t.Errorf("expected the answer over the question limit to time out")
+	}
+	if elapsed := timer.Elapsed["2"]; elapsed < 19*1000 || elapsed > 21*1000 {
+		t.Errorf("unexpected elapsed time: %d", elapsed)
+	}
+
+	timeout, err = timer.Track("3")
+	if err != nil || timeout {
+		t.Errorf("expected the immediate answer to be in time, got %v %v", timeout, err)
+	}
+
+	timer.Deadline = now - 1000
+	if _, err := timer.Track("4"); err == nil {
+		t.Errorf("expected the answer after the deadline to be refused")
+	}
+}
+
+func TestAttemptTimerFormatElapsed(t *testing.T) {
+	timer := &AttemptTimer{Elapsed: map[string]int64{"10": 3000, "2": 1500, "1": 0}}
+	if formatted := timer.FormatElapsed(); formatted != "1:0.0 2:1.5 10:3.0" {
+		t.Errorf("unexpected formatted elapsed time: %q", formatted)
+	}
+}
@@ -0,0 +1,317 @@
+package quiz
+
+import (
+	"chat/auth"
+	"chat/globals"
+	"chat/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// a translated quiz is saved as a variant of the original quiz (parent_id), the question ids, the types,
+// the option keys and the answer keys are taken from the original so that the attempts of the variants
+// can be compared question by question. only the text fields are taken from the model output.
+
+const maxLanguageLength = 32
+
+// languageNames maps the locale codes of the frontend to the language names of the prompts
+var languageNames = map[string]string{
+	"en":    "English",
+	"cn":    "Simplified Chinese",
+	"zh":    "Simplified Chinese",
+	"zh-cn": "Simplified Chinese",
+	"tw":    "Traditional Chinese",
+	"zh-tw": "Traditional Chinese",
+	"ja":    "Japanese",
+	"ru":    "Russian",
+	"de":    "German",
+	"fr":    "French",
+	"es":    "Spanish",
+	"ko":    "Korean",
+}
+
+// getLanguageName returns the language name of the locale code, other values are kept as language names
+func getLanguageName(language string) string {
+	language = strings.TrimSpace(language)
+	if name, ok := languageNames[strings.ToLower(language)]; ok {
+		return name
+	}
+	return utils.Extract(language, maxLanguageLength, "")
+}
+
+// translatedQuiz is the text of a question returned by the model
+type translatedQuiz struct {
+	ID           string         `json:"id"`
+	Question     string         `json:"question"`
+	Description  string         `json:"description"`
+	Options      QuizOption     `json:"options,omitempty"`
+	Answers      []string       `json:"answers,omitempty"` // accepted fill_blank answers
+	Rubric       []string       `json:"rubric,omitempty"`
+	ModelAnswer  string         `json:"model_answer,omitempty"`
+	Explanations QuizOption     `json:"explanations,omitempty"`
+	Resources    []QuizResource `json:"resources,omitempty"`
+}
+
+func toTranslatedQuiz(q Quiz) translatedQuiz {
+	translated := translatedQuiz{
+		ID:           q.ID,
+		Question:     q.Question,
+		Description:  q.Description,
+		Options:      q.Options,
+		Rubric:       q.Rubric,
+		ModelAnswer:  q.ModelAnswer,
+		Explanations: q.Explanations,
+		Resources:    q.Resources,
+	}
+	if q.GetType() == FillBlank {
+		translated.Answers = q.Answers
+	}
+	return translated
+}
+
+func buildTranslatePrompt(quizzes []Quiz, language string) string {
+	return fmt.Sprintf(
+		"Translate the following quiz questions into %s.\n%s\n\n"+
+			"Translate the values of \"question\", \"description\", \"options\", \"answers\", \"rubric\", \"model_answer\", \"explanations\" "+
+			"and the titles of \"resources\". Keep the \"id\" of each question, the keys of \"options\" and \"explanations\", "+
+			"the links of \"resources\" and the %s marker unchanged. \"answers\" are the accepted words of the blank.\n"+
+			"Respond only with the translated JSON array without any additional text or markdown formatting.",
+		language, utils.Marshal(utils.Each(quizzes, toTranslatedQuiz)), BlankMarker,
+	)
+}
+
+// applyTranslation returns the original question with the translated text, the structure is
+// taken from the original question and the problems of the translation are returned
+func applyTranslation(origin Quiz, translated translatedQuiz) (Quiz, []string) {
+	problems := make([]string, 0)
+	q := origin
+
+	if text := strings.TrimSpace(translated.Question); len(text) > 0 {
+		q.Question = text
+	} else {
+		problems = append(problems, fmt.Sprintf("question %s: question is empty", origin.ID))
+	}
+	if origin.GetType() == FillBlank && !strings.Contains(q.Question, BlankMarker) {
+		problems = append(problems, fmt.Sprintf("question %s: the %s marker is missing", origin.ID, BlankMarker))
+	}
+	if len(strings.TrimSpace(translated.Description)) > 0 {
+		q.Description = translated.Description
+	}
+	if len(strings.TrimSpace(translated.ModelAnswer)) > 0 {
+		q.ModelAnswer = translated.ModelAnswer
+	}
+
+	if len(origin.Options) > 0 {
+		q.Options = QuizOption{}
+		for key, option := range origin.Options {
+			if text := strings.TrimSpace(translated.Options[key]); len(text) > 0 {
+				q.Options[key] = text
+			} else {
+				q.Options[key] = option
+				problems = append(problems, fmt.Sprintf("question %s: option %s is missing", origin.ID, key))
+			}
+		}
+	}
+
+	if len(origin.Explanations) > 0 {
+		q.Explanations = QuizOption{}
+		for key, explanation := range origin.Explanations {
+			if text := strings.TrimSpace(translated.Explanations[key]); len(text) > 0 {
+				q.Explanations[key] = text
+			} else {
+				q.Explanations[key] = explanation
+			}
+		}
+	}
+
+	switch origin.GetType() {
+	case FillBlank:
+		if len(translated.Answers) > 0 {
+			q.Answers = translated.Answers
+		} else {
+			problems = append(problems, fmt.Sprintf("question %s: answers are missing", origin.ID))
+		}
+	case ShortAnswer:
+		if len(translated.Rubric) == len(origin.Rubric) {
+			q.Rubric = translated.Rubric
+		} else {
+			problems = append(problems, fmt.Sprintf("question %s: rubric has %d points instead of %d", origin.ID, len(translated.Rubric), len(origin.Rubric)))
+		}
+	}
+
+	if len(translated.Resources) == len(origin.Resources) {
+		q.Resources = make([]QuizResource, len(origin.Resources))
+		for i, resource := range origin.Resources {
+			q.Resources[i] = QuizResource{Title: translated.Resources[i].Title, Link: resource.Link}
+			if len(strings.TrimSpace(q.Resources[i].Title)) == 0 {
+				q.Resources[i].Title = resource.Title
+			}
+		}
+	}
+
+	return q, problems
+}
+
+// mergeTranslation applies the translated questions to the original questions by id, the
+// questions with problems are kept untranslated and their problems are returned
+func mergeTranslation(origin []Quiz, response string) ([]Quiz, []string) {
+	translated, err := utils.UnmarshalString[[]translatedQuiz](trimResponse(response))
+	if err != nil {
+		return origin, []string{fmt.Sprintf("response is not a valid JSON array: %s", err.Error())}
+	}
+
+	texts := map[string]translatedQuiz{}
+	for _, item := range translated {
+		texts[strings.TrimSpace(item.ID)] = item
+	}
+
+	result := make([]Quiz, len(origin))
+	problems := make([]string, 0)
+	for i, q := range origin {
+		text, ok := texts[q.ID]
+		if !ok {
+			result[i] = q
+			problems = append(problems, fmt.Sprintf("question %s is missing", q.ID))
+			continue
+		}
+
+		merged, issues := applyTranslation(q, text)
+		if len(issues) > 0 {
+			result[i] = q
+			problems = append(problems, issues...)
+			continue
+		}
+		result[i] = merged
+	}
+
+	return result, problems
+}
+
+// TranslateQuiz translates the questions of the saved quiz, the questions are re-prompted with
+// the problems for at most maxRepairRounds rounds and an error is returned if any question is left untranslated
+func TranslateQuiz(c *gin.Context, user *auth.User, quiz *SavedQuiz, language string, model string) ([]Quiz, float32, error) {
+	form := QuizGenerationRequest{Model: model}
+	messages := []globals.Message{
+		{Role: globals.User, Content: buildTranslatePrompt(quiz.Data, language)},
+	}
+
+	var quota float32
+	for round := 0; round <= maxRepairRounds; round++ {
+		buffer, err := requestQuiz(c, user, form, messages, nil)
+		quota += buffer.GetQuota()
+		if err != nil {
+			return nil, quota, err
+		}
+
+		response := buffer.Read()
+		quizzes, problems := mergeTranslation(quiz.Data, response)
+		if len(problems) == 0 {
+			return quizzes, quota, nil
+		}
+
+		globals.Debug(fmt.Sprintf("[quiz] translation repair round %d: %s", round+1, strings.Join(problems, "; ")))
+		messages = append(messages,
+			globals.Message{Role: globals.Assistant, Content: response},
+			globals.Message{Role: globals.User, Content: fmt.Sprintf(
+				"Your previous translation has the following problems:\n- %s\n\n"+
+					"Respond with the complete translated JSON array of all the questions that fixes these problems.",
+				strings.Join(problems, "\n- "),
+			)},
+		)
+	}
+
+	return nil, quota, fmt.Errorf("failed to translate all the questions")
+}
+
+// SaveTranslation saves the translated questions as a variant of the quiz, the variants of
+// a variant are linked to the original quiz
+func SaveTranslation(db *sql.DB, quiz *SavedQuiz, language string, model string, quizzes []Quiz) (int64, error) {
+	parent := quiz.Id
+	if quiz.ParentId > 0 {
+		parent = quiz.ParentId
+	}
+
+	name := utils.Extract(fmt.Sprintf("%s (%s)", quiz.Name, language), 50, "...")
+	res, err := globals.ExecDb(db, `
+		INSERT INTO quiz (user_id, quiz_name, model, topic, difficulty, language, parent_id, source_hash, quiz_count, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, quiz.UserId, name, model, quiz.Topic, quiz.Difficulty, language, parent,
+		quiz.SourceHash, len(quizzes), utils.Marshal(quizzes))
+	if err != nil {
+		globals.Info(fmt.Sprintf("[quiz] execute error during save translation: %s", err.Error()))
+		return -1, err
+	}
+
+	return res.LastInsertId()
+}
+
+// LoadQuizVariants loads the original quiz and all its translations
+func LoadQuizVariants(db *sql.DB, quiz *SavedQuiz) []SavedQuizPreview {
+	root := quiz.Id
+	if quiz.ParentId > 0 {
+		root = quiz.ParentId
+	}
+
+	list := make([]SavedQuizPreview, 0)
+	rows, err := globals.QueryDb(db, `
+		SELECT id, quiz_name, model, topic, difficulty, language, parent_id, quiz_count, updated_at FROM quiz
+		WHERE user_id = ? AND (id = ? OR parent_id = ?)
+		ORDER BY id ASC
+	`, quiz.UserId, root, root)
+	if err != nil {
+		return list
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			preview SavedQuizPreview
+			updated []uint8
+		)
+		if err := rows.Scan(&preview.Id, &preview.Name, &preview.Model, &preview.Topic, &preview.Difficulty, &preview.Language, &preview.ParentId, &preview.Count, &updated); err != nil {
+			continue
+		}
+
+		if t := utils.ConvertTime(updated); t != nil {
+			preview.Time = *t
+		}
+		list = append(list, preview)
+	}
+
+	return list
+}
+
+// processQuizTranslation checks the permission and the quota of the user, translates the quiz
+// and bills it like the quiz generation, the id of the saved variant is returned
+func processQuizTranslation(c *gin.Context, user *auth.User, quiz *SavedQuiz, language string, model string) (int64, float32, error) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	if !auth.HitGroups(db, user, QuizPermissionGroup) {
+		return 0, 0, fmt.Errorf("permission denied: quiz feature not available")
+	}
+
+	check, plan := auth.CanEnableModelWithSubscription(db, cache, user, model, []globals.Message{})
+	if check != nil {
+		return 0, 0, check
+	}
+
+	quizzes, quota, err := TranslateQuiz(c, user, quiz, language, model)
+	if !plan && quota > 0 {
+		user.UseQuota(db, quota)
+	}
+
+	// the plan usage is only counted (and reverted) if the subscription is used
+	if err != nil {
+		if plan {
+			auth.RevertSubscriptionUsage(db, cache, user, model)
+		}
+		return 0, quota, err
+	}
+
+	id, err := SaveTranslation(db, quiz, language, model, quizzes)
+	return id, quota, err
+}
@@ -1,12 +1,7 @@
 package quiz
 
-// QuizOption represents quiz answer options
-type QuizOption struct {
-	A string `json:"a"`
-	B string `json:"b"`
-	C string `json:"c"`
-	D string `json:"d"`
-}
+// QuizOption represents quiz answer options keyed by a, b, c, ...
+type QuizOption map[string]string
 
 // QuizResource represents additional learning resources
 type QuizResource struct {
@@ -17,30 +12,65 @@ type QuizResource struct {
 // Quiz represents a single quiz question
 type Quiz struct {
 	ID          string         `json:"id"`
+	Type        string         `json:"type,omitempty"` // question type, multiple_choice by default
 	Question    string         `json:"question"`
 	Description string         `json:"description"`
-	Options     QuizOption     `json:"options"`
-	Answer      string         `json:"answer"`
+	Options     QuizOption     `json:"options,omitempty"`
+	Answer      string         `json:"answer,omitempty"`       // multiple_choice and true_false
+	Answers     []string       `json:"answers,omitempty"`      // multi_select keys, ordering sequence or fill_blank variants
+	Rubric      []string       `json:"rubric,omitempty"`       // short_answer key points
+	ModelAnswer string         `json:"model_answer,omitempty"` // short_answer reference answer, only shown after grading
 	Resources   []QuizResource `json:"resources,omitempty"`
+
+	Explanations QuizOption `json:"explanations,omitempty"` // why each option (or true and false) is correct or wrong
+	Source       string     `json:"source,omitempty"`       // excerpt of the source the question is based on
+
+	Confidence  *float64 `json:"confidence,omitempty"`   // confidence of the answer key verified by the judge model
+	Flagged     bool     `json:"flagged,omitempty"`      // the judge model disagrees with the answer key
+	JudgeAnswer string   `json:"judge_answer,omitempty"` // answer of the judge model to the flagged question
 }
 
 // QuizGenerationRequest represents the request body for quiz generation
 type QuizGenerationRequest struct {
-	Token      string   `json:"token"`
-	Notes      string   `json:"notes,omitempty"`
-	Files      []string `json:"files,omitempty"` // base64 encoded files
-	FileMimes  []string `json:"file_mimes,omitempty"`
-	QuizCount  int      `json:"quiz_count"`
-	Difficulty string   `json:"difficulty"`
-	Topic      string   `json:"topic,omitempty"`
-	Model      string   `json:"model"`
+	Token         string   `json:"token"`
+	Notes         string   `json:"notes,omitempty"`
+	Files         []string `json:"files,omitempty"` // base64 encoded files
+	FileMimes     []string `json:"file_mimes,omitempty"`
+	QuizCount     int      `json:"quiz_count"`
+	Difficulty    string   `json:"difficulty"`
+	Topic         string   `json:"topic,omitempty"`
+	Language      string   `json:"language,omitempty"` // output language (name or locale code), the notes language by default
+	Model         string   `json:"model"`
+	QuestionTypes []string `json:"question_types,omitempty"`
+	Grounding     bool     `json:"grounding,omitempty"`   // replace the resource links with verified web search results
+	TemplateId    string   `json:"template_id,omitempty"` // prompt template of the generation, the built-in prompt by default
 }
 
 // QuizGenerationResponse represents the streaming response
 type QuizGenerationResponse struct {
-	Message string  `json:"message"`
-	Quota   float32 `json:"quota"`
-	End     bool    `json:"end"`
-	Error   string  `json:"error,omitempty"`
-	Data    string  `json:"data,omitempty"` // JSON string of quiz array
+	Id       int64         `json:"id,omitempty"` // id of the saved quiz in the library
+	Message  string        `json:"message"`
+	Quota    float32       `json:"quota"`
+	End      bool          `json:"end"`
+	Error    string        `json:"error,omitempty"`
+	Type     string        `json:"type,omitempty"`     // error type of the failed generation
+	Cached   bool          `json:"cached,omitempty"`   // served from the quiz cache without billing
+	Progress *QuizProgress `json:"progress,omitempty"` // chunk progress of long sources
+	Cost     *QuizCost     `json:"cost,omitempty"`     // cost of the quiz feature rule, estimated in the first frame
+	Data     []Quiz        `json:"data,omitempty"`     // validated questions, only set in the final frame
+}
+
+// error types of the failed generation, mapped to the http status codes by the relay api
+const (
+	PermissionError     = "permission_error"
+	InvalidRequestError = "invalid_request_error"
+	QuotaExceededError  = "quota_exceeded_error"
+	GenerationError     = "quiz_generation_error"
+)
+
+// QuizProgress represents the progress of the chunked generation
+type QuizProgress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+	Skipped int `json:"skipped,omitempty"` // chunks of the source left out, the source has more chunks than the questions
 }
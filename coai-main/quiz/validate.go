@@ -0,0 +1,167 @@
+package quiz
+
+import (
+	"chat/utils"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxRepairRounds is the maximum number of re-prompts when the model output is invalid
+const maxRepairRounds = 2
+
+func trimResponse(response string) string {
+	// remove potential markdown code blocks and leading text
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	response = strings.TrimSpace(response)
+
+	if idx := strings.Index(response, "["); idx > 0 {
+		response = response[idx:]
+	}
+
+	return response
+}
+
+// decodeQuizzes decodes the json array element by element, so that questions
+// before a truncated or malformed element are still kept
+func decodeQuizzes(response string) ([]Quiz, error) {
+	decoder := json.NewDecoder(strings.NewReader(trimResponse(response)))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("response is not a valid JSON array: %s", err.Error())
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("response is not a JSON array")
+	}
+
+	quizzes := make([]Quiz, 0)
+	for decoder.More() {
+		var quiz Quiz
+		if err := decoder.Decode(&quiz); err != nil {
+			return quizzes, fmt.Errorf("response is truncated or malformed after %d questions: %s", len(quizzes), err.Error())
+		}
+		quizzes = append(quizzes, quiz)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return quizzes, fmt.Errorf("response is truncated after %d questions", len(quizzes))
+	}
+
+	return quizzes, nil
+}
+
+// isMalformedQuizResponse checks if the model output can not be decoded as a complete JSON array
+func isMalformedQuizResponse(response string) bool {
+	_, err := decodeQuizzes(response)
+	return err != nil
+}
+
+// ValidateQuizResponse parses the model output and returns the schema-valid questions of the
+// given types with the problems of the output, problems is empty if the whole output is valid
+func ValidateQuizResponse(response string, types []string) ([]Quiz, []string) {
+	problems := make([]string, 0)
+
+	quizzes, err := decodeQuizzes(response)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	valid := make([]Quiz, 0, len(quizzes))
+	for i, quiz := range quizzes {
+		quiz.Type = normalizeKey(quiz.Type)
+		quiz.Options = quiz.Options.Normalize()
+		quiz.Explanations = quiz.Explanations.Normalize()
+
+		errs := append(quiz.Validate(), quiz.validateExplanations()...)
+		if len(errs) == 0 && !utils.Contains(quiz.GetType(), types) {
+			errs = append(errs, fmt.Sprintf("type %q is not one of %s", quiz.GetType(), strings.Join(types, ", ")))
+		}
+
+		if len(errs) > 0 {
+			problems = append(problems, fmt.Sprintf("question %d: %s", i+1, strings.Join(errs, "; ")))
+			continue
+		}
+
+		valid = append(valid, quiz.Normalize())
+	}
+
+	return valid, problems
+}
+
+// isDuplicateQuiz checks if the questions are the same or share most of their words
+func isDuplicateQuiz(a Quiz, b Quiz) bool {
+	x, y := normalizeText(a.Question), normalizeText(b.Question)
+	if x == y {
+		return true
+	}
+
+	words := map[string]bool{}
+	for _, word := range strings.Fields(x) {
+		words[word] = true
+	}
+
+	union := len(words)
+	shared := 0
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(y) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+
+		if words[word] {
+			shared++
+		} else {
+			union++
+		}
+	}
+
+	return union > 0 && float64(shared)/float64(union) >= 0.8
+}
+
+func hasDuplicateQuiz(source []Quiz, quiz Quiz) bool {
+	for _, item := range source {
+		if isDuplicateQuiz(item, quiz) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeQuizzes appends the questions which are not duplicated and returns at most count questions
+func mergeQuizzes(source []Quiz, target []Quiz, count int) []Quiz {
+	for _, quiz := range target {
+		if len(source) >= count {
+			break
+		}
+
+		if !hasDuplicateQuiz(source, quiz) {
+			source = append(source, quiz)
+		}
+	}
+
+	return source
+}
+
+// renumberQuizzes resets the question ids to keep them unique across repair rounds
+func renumberQuizzes(quizzes []Quiz) []Quiz {
+	for i := range quizzes {
+		quizzes[i].ID = fmt.Sprintf("%d", i+1)
+	}
+	return quizzes
+}
+
+// buildRepairPrompt asks the model to fix the problems of the previous output
+func buildRepairPrompt(problems []string, missing int) string {
+	return fmt.Sprintf(
+		"Your previous response has the following problems:\n- %s\n\n"+
+			"Generate %d more different questions that fix these problems and do not repeat the valid ones. "+
+			"Every question must follow the structure of its type described before. "+
+			"Return only the JSON array without any additional text or markdown formatting.",
+		strings.Join(problems, "\n- "), missing,
+	)
+}
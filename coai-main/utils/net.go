@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"runtime/debug"
 	"strings"
+	"syscall"
 
 	"github.com/goccy/go-json"
 	"golang.org/x/net/proxy"
@@ -165,6 +166,126 @@ func HttpRaw(uri string, method string, headers map[string]string, body io.Reade
 	return data, nil
 }
 
+// allowPrivateLinks disables the private address guard of CheckLink, only the tests against the local servers enable it
+var allowPrivateLinks = false
+
+// isPublicIP checks if the ip is a public unicast address, the loopback, private (rfc1918, fc00::/7),
+// link-local (including the 169.254.169.254 metadata address), multicast and unspecified addresses are rejected
+func isPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// checkLinkTarget checks the scheme of the link and that all the addresses of its host are public
+func checkLinkTarget(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if len(host) == 0 {
+		return fmt.Errorf("empty host")
+	}
+	if allowPrivateLinks {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), globals.LinkCheckTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("host %s resolves to the non-public address %s", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// newLinkClient returns the client of CheckLink, every redirect is checked again and the direct
+// connections are checked against the dialed address, so that dns rebinding cannot reach private hosts
+func newLinkClient(config []globals.ProxyConfig) *http.Client {
+	client := newClient(config)
+	client.Timeout = globals.LinkCheckTimeout
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return checkLinkTarget(req.URL)
+	}
+
+	if transport, ok := client.Transport.(*http.Transport); ok && transport.Proxy == nil && transport.DialContext == nil {
+		dialer := &net.Dialer{
+			Timeout: globals.LinkCheckTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); !allowPrivateLinks && !isPublicIP(ip) {
+					return fmt.Errorf("connection to the non-public address %s is denied", host)
+				}
+				return nil
+			},
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	return client
+}
+
+// CheckLink checks if the link is reachable, a HEAD request is sent first and a GET request
+// is sent if the server does not accept HEAD requests, status codes below 400 are reachable.
+// only http and https links of public hosts are checked, the others are unreachable
+func CheckLink(uri string, config ...globals.ProxyConfig) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	if err := checkLinkTarget(u); err != nil {
+		if globals.DebugMode {
+			globals.Debug(fmt.Sprintf("[http] refused to check link %s: %s", uri, err))
+		}
+		return false
+	}
+
+	client := newLinkClient(config)
+
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, uri, nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; LinkChecker/1.0)")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if globals.DebugMode {
+				globals.Debug(fmt.Sprintf("[http] failed to check link %s: %s", uri, err))
+			}
+			return false
+		}
+		resp.Body.Close()
+
+		status = resp.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+			break
+		}
+	}
+
+	if globals.DebugMode {
+		globals.Debug(fmt.Sprintf("[http] checked link %s: %d", uri, status))
+	}
+	return status > 0 && status < 400
+}
+
 func Get(uri string, headers map[string]string, config ...globals.ProxyConfig) (data interface{}, err error) {
 	err = Http(uri, http.MethodGet, &data, headers, nil, config)
 	return data, err
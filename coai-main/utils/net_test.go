@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+
+	for ip, expected := range cases {
+		if public := isPublicIP(net.ParseIP(ip)); public != expected {
+			t.Errorf("isPublicIP(%s) = %v, expected %v", ip, public, expected)
+		}
+	}
+}
+
+func TestCheckLinkGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for _, link := range []string{
+		server.URL,
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://[::1]/",
+		"file:///etc/passwd",
+		"ftp://example.com/file",
+		"gopher://127.0.0.1:25/",
+	} {
+		if CheckLink(link) {
+			t.Errorf("expected %s to be refused", link)
+		}
+	}
+}
+
+func TestCheckLinkRedirect(t *testing.T) {
+	client := newLinkClient(nil)
+	req := &http.Request{URL: &url.URL{Scheme: "http", Host: "169.254.169.254", Path: "/"}}
+	if err := client.CheckRedirect(req, nil); err == nil {
+		t.Errorf("expected the redirect to the metadata address to be refused")
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	allowPrivateLinks = true
+	defer func() { allowPrivateLinks = false }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cases := map[string]bool{
+		"/ok":       true,
+		"/missing":  false,
+		"/get-only": true,
+		"/moved":    true,
+	}
+
+	for path, expected := range cases {
+		if alive := CheckLink(server.URL + path); alive != expected {
+			t.Errorf("CheckLink(%s) = %v, expected %v", path, alive, expected)
+		}
+	}
+}